@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/history"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+)
+
+// Exit codes of the command-line interface.
+const (
+	exitOK          = 0   // The operation succeeded
+	exitFailed      = 1   // The operation ran and failed
+	exitUsage       = 2   // The command line was invalid
+	exitInterrupted = 130 // Cancelled with Ctrl+C
+)
+
+const cliUsage = `Usage: ari-s <command> [flags] <args>
+
+Commands:
+  pack <input-dir> <output-dir>      Pack a mod folder into .pak/.utoc/.ucas
+  unpack <paks> <output-dir>         Extract the assets of IoStore containers
+  list <paks>                        List the chunks of IoStore containers
+  uasset export <folder>             Convert .uasset/.uexp files to JSON
+  uasset import <folder>             Convert JSON files back to .uasset/.uexp
+
+Without a command, ARI-S opens its window. Flags go before the arguments;
+run "ari-s <command> -h" for a command's flags. Results are printed to
+stdout, as JSON with --json; progress and logs go to stderr.
+
+The AES keys of a --profile are unlocked with the passphrase in the file
+given by --passphrase-file, or else in the ` + passphraseEnv + ` environment
+variable. Packing needs no keys.
+`
+
+// passphraseEnv names the environment variable holding the passphrase of
+// the --profile's key vault when --passphrase-file is not given.
+const passphraseEnv = "ARIS_KEY_PASSPHRASE"
+
+// cliCommands maps the first argument to the command it runs. Any other
+// first argument starts the GUI.
+var cliCommands = map[string]func(c *cli, args []string) int{
+	"pack":   (*cli).pack,
+	"unpack": (*cli).unpack,
+	"list":   (*cli).list,
+	"uasset": (*cli).uasset,
+	"help":   (*cli).help,
+	"-h":     (*cli).help,
+	"--help": (*cli).help,
+}
+
+// isCLICommand reports whether arg selects headless mode.
+func isCLICommand(arg string) bool {
+	_, ok := cliCommands[arg]
+	return ok
+}
+
+// cli runs one command against the same services the GUI binds, built
+// without a Wails application. Events the services emit reach the cli
+// through the App's in-process subscribers.
+type cli struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+	setup  func() (depsDir string, err error)
+
+	app           *app.App
+	retocService  *retoc.RetocService
+	uassetService *uasset.UAssetService
+}
+
+// runCLI runs the command in args, where args[0] is the command name, and
+// returns the process exit code. setup extracts the dependencies once the
+// command line has been parsed.
+func runCLI(args []string, setup func() (string, error), stdout, stderr io.Writer) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := &cli{ctx: ctx, stdout: stdout, stderr: stderr, setup: setup}
+	code := cliCommands[args[0]](c, args[1:])
+	if code == exitFailed && ctx.Err() != nil {
+		return exitInterrupted
+	}
+	return code
+}
+
+// commonFlags are accepted by every command.
+type commonFlags struct {
+	json           bool
+	verbose        bool
+	profile        string
+	passphraseFile string
+}
+
+func (c *cli) flagSet(name, usage string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: ari-s %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&common.json, "json", false, "print the result as JSON")
+	fs.BoolVar(&common.verbose, "v", false, "log service and tool output to stderr")
+	fs.StringVar(&common.profile, "profile", "", "game profile ID for paths, engine version, keys and naming")
+	fs.StringVar(&common.passphraseFile, "passphrase-file", "", "file holding the passphrase that unlocks the profile's AES keys (default: $"+passphraseEnv+")")
+	return fs
+}
+
+// parse parses args into fs and checks the number of positional arguments.
+// It returns false with the exit code to use if the command should not run.
+func (c *cli) parse(fs *flag.FlagSet, args []string, nargs int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if fs.NArg() != nargs {
+		fmt.Fprintf(c.stderr, "expected %d argument(s), got %d\n", nargs, fs.NArg())
+		fs.Usage()
+		return exitUsage, false
+	}
+	return 0, true
+}
+
+// start extracts the dependencies, loads the configuration, unlocks the
+// profile's keys when a passphrase is given and builds the services.
+// Logging goes to stderr with -v and is discarded otherwise. It returns
+// false if the dependencies could not be set up or the keys not unlocked.
+func (c *cli) start(common commonFlags) bool {
+	if common.verbose {
+		log.SetOutput(c.stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+	depsDir, err := c.setup()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "error: dependency setup failed: %v\n", err)
+		return false
+	}
+
+	c.app = app.NewApp()
+	c.app.SetDepsDir(depsDir)
+	if err := c.app.LoadConfiguration(); err != nil {
+		fmt.Fprintf(c.stderr, "warning: failed to load configuration: %v\n", err)
+	}
+	if err := c.unlockKeys(common); err != nil {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		return false
+	}
+	c.retocService = retoc.NewRetocService(c.app, depsDir)
+	c.uassetService = uasset.NewUAssetService(c.app, depsDir)
+	// Record headless operations in the same history as the GUI's
+	history.NewHistoryService(c.app, c.retocService, c.uassetService)
+	return true
+}
+
+// unlockKeys unlocks the AES keys of the --profile with the passphrase
+// from --passphrase-file or the environment. Without a passphrase the keys
+// stay locked, and only commands that read encrypted containers fail.
+func (c *cli) unlockKeys(common commonFlags) error {
+	passphrase := os.Getenv(passphraseEnv)
+	if common.passphraseFile != "" {
+		data, err := os.ReadFile(common.passphraseFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the passphrase: %v", err)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	if common.profile == "" || passphrase == "" {
+		return nil
+	}
+	if err := c.app.UnlockGameKeys(common.profile, passphrase); err != nil {
+		return fmt.Errorf("failed to unlock the AES keys of profile '%s': %v", common.profile, err)
+	}
+	return nil
+}
+
+func (c *cli) pack(args []string) int {
+	var common commonFlags
+	var opts retoc.PackOptions
+	var noPatch bool
+	fs := c.flagSet("pack", "pack [flags] <input-dir> <output-dir>", &common)
+	fs.StringVar(&opts.ModName, "mod-name", "", "output base name (default: the input folder name)")
+	priority := fs.Int("priority", 1, "load-order serial, 0-9999")
+	fs.BoolVar(&noPatch, "no-patch-suffix", false, `leave out the "_P" patch suffix`)
+	fs.StringVar(&opts.MountPoint, "mount-point", "", `mount point, e.g. "../../../MyGame/"`)
+	fs.StringVar(&opts.Compression, "compression", "", "Oodle, Zlib or None")
+	fs.StringVar(&opts.EngineVersion, "version", "", "engine version: "+strings.Join(retoc.EngineVersions, ", "))
+	fs.StringVar(&opts.NamingPreset, "naming-preset", "", "file naming preset (default: the profile's)")
+	fs.StringVar(&opts.NameTemplate, "name-template", "", "file naming template, overriding the preset's")
+	if code, ok := c.parse(fs, args, 2); !ok {
+		return code
+	}
+	if noPatch {
+		patch := false
+		opts.PatchSuffix = &patch
+	}
+	opts.Priority = priority
+
+	if !c.start(common) {
+		return exitFailed
+	}
+	if errs := c.retocService.ValidatePackOptions(c.ctx, opts); len(errs) > 0 {
+		for _, fe := range errs {
+			fmt.Fprintf(c.stderr, "--%s: %s\n", strings.ReplaceAll(fe.Field, "_", "-"), fe.Message)
+		}
+		return exitUsage
+	}
+	return c.runRetoc(common, retoc.RetocOperation{
+		Command:    "to-zen",
+		InputPath:  fs.Arg(0),
+		OutputPath: fs.Arg(1),
+		Pack:       &opts,
+		ProfileID:  common.profile,
+	})
+}
+
+func (c *cli) unpack(args []string) int {
+	var common commonFlags
+	var include, exclude stringList
+	var legacy bool
+	fs := c.flagSet("unpack", "unpack [flags] <paks> <output-dir>", &common)
+	fs.Var(&include, "include", `package path glob to extract, e.g. "/Game/Data/**"; repeatable`)
+	fs.Var(&exclude, "exclude", "package path glob to skip; repeatable")
+	fs.BoolVar(&legacy, "legacy", false, "convert to legacy .pak assets (to-legacy) instead of extracting chunks")
+	if code, ok := c.parse(fs, args, 2); !ok {
+		return code
+	}
+
+	command := "unpack"
+	if legacy {
+		command = "to-legacy"
+	}
+	if !c.start(common) {
+		return exitFailed
+	}
+	return c.runRetoc(common, retoc.RetocOperation{
+		Command:    command,
+		InputPath:  fs.Arg(0),
+		OutputPath: fs.Arg(1),
+		Include:    include,
+		Exclude:    exclude,
+		ProfileID:  common.profile,
+	})
+}
+
+// runRetoc runs operation, reporting progress on stderr, and prints the
+// result.
+func (c *cli) runRetoc(common commonFlags, operation retoc.RetocOperation) int {
+	// Progress arrives from the goroutines reading stdout and stderr. A
+	// line is printed when the phase or the whole percentage changes.
+	var mu sync.Mutex
+	lastKey := ""
+	defer c.retocService.SubscribeProgress(func(p retoc.RetocProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := fmt.Sprintf("%s %.0f", p.Phase, p.Percent)
+		if key == lastKey {
+			return
+		}
+		lastKey = key
+		line := p.Phase
+		if p.ItemsTotal > 0 {
+			line += fmt.Sprintf(" %d/%d %s", p.ItemsDone, p.ItemsTotal, p.ItemUnit)
+		}
+		if p.Percent > 0 {
+			line += fmt.Sprintf(" (%.0f%%)", p.Percent)
+		}
+		fmt.Fprintln(c.stderr, line)
+	})()
+	if common.verbose {
+		defer c.retocService.SubscribeOutput(func(l retoc.RetocOutputLine) {
+			fmt.Fprintln(c.stderr, l.Line)
+		})()
+	}
+
+	result := c.retocService.RunRetoc(c.ctx, operation)
+	return c.result(common, result, result.Success, result.Message, result.Error)
+}
+
+func (c *cli) list(args []string) int {
+	var common commonFlags
+	fs := c.flagSet("list", "list [flags] <paks>", &common)
+	if code, ok := c.parse(fs, args, 1); !ok {
+		return code
+	}
+
+	if !c.start(common) {
+		return exitFailed
+	}
+	entries, err := c.retocService.ListContainer(c.ctx, fs.Arg(0), common.profile)
+	if err != nil {
+		return c.fail(common, err)
+	}
+	if common.json {
+		return c.printJSON(entries)
+	}
+	for _, e := range entries {
+		name := e.PackagePath
+		if name == "" {
+			name = e.ChunkID
+		}
+		fmt.Fprintf(c.stdout, "%s\t%s\t%d\n", name, e.ChunkType, e.Size)
+	}
+	return exitOK
+}
+
+func (c *cli) uasset(args []string) int {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(c.stderr, "Usage: ari-s uasset export|import [flags] <folder>")
+		return exitUsage
+	}
+	command := args[0]
+
+	var common commonFlags
+	var mappings string
+	fs := c.flagSet("uasset "+command, "uasset "+command+" [flags] <folder>", &common)
+	fs.StringVar(&mappings, "mappings", "", ".usmap mappings file (default: the profile's)")
+	if code, ok := c.parse(fs, args[1:], 1); !ok {
+		return code
+	}
+
+	if !c.start(common) {
+		return exitFailed
+	}
+	fmt.Fprintf(c.stderr, "Running UAsset %s on %s\n", command, fs.Arg(0))
+	result := c.uassetService.RunUAsset(c.ctx, uasset.UAssetRequest{
+		Command:      command,
+		FolderPath:   fs.Arg(0),
+		MappingsPath: mappings,
+		ProfileID:    common.profile,
+	})
+	if common.verbose && result.Output != "" {
+		fmt.Fprint(c.stderr, result.Output)
+	}
+	return c.result(common, result, result.Success, result.Message, result.Error)
+}
+
+func (c *cli) help(args []string) int {
+	fmt.Fprint(c.stdout, cliUsage)
+	return exitOK
+}
+
+// result prints the result struct of an operation and returns its exit
+// code. Without --json, only the message or error is printed.
+func (c *cli) result(common commonFlags, result any, success bool, message, errMessage string) int {
+	code := exitOK
+	if !success {
+		code = exitFailed
+	}
+	if common.json {
+		if printCode := c.printJSON(result); printCode != exitOK {
+			return printCode
+		}
+		return code
+	}
+	if success {
+		fmt.Fprintln(c.stdout, message)
+	} else {
+		fmt.Fprintf(c.stderr, "error: %s\n", strings.TrimSpace(errMessage))
+	}
+	return code
+}
+
+// fail reports err, as {"error": ...} with --json, and returns exitFailed.
+func (c *cli) fail(common commonFlags, err error) int {
+	if common.json {
+		c.printJSON(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+	}
+	return exitFailed
+}
+
+func (c *cli) printJSON(v any) int {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+// attachConsole is a no-op outside Windows, where processes inherit the
+// terminal they are started from.
+func attachConsole() {}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachParentProcess is ATTACH_PARENT_PROCESS, (DWORD)-1.
+const attachParentProcess = uintptr(^uint32(0))
+
+// attachConsole connects the process to the console of the shell that
+// started it. Release builds use the GUI subsystem, which starts without
+// a console, so CLI output would otherwise be lost. Streams that are
+// already redirected to a file or pipe are left alone.
+func attachConsole() {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	if r, _, _ := kernel32.NewProc("AttachConsole").Call(attachParentProcess); r == 0 {
+		return
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stdout = f
+		}
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stderr = f
+		}
+	}
+}
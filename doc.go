@@ -21,10 +21,31 @@
 // The backend follows a service-oriented pattern:
 //   - [App]: Core application lifecycle and configuration
 //   - [RetocService]: Wraps retoc.exe for pak operations
+//   - [iostore]: Native IoStore .utoc reader used for inspection
 //   - [UAssetService]: IPC-based UAsset serialization (default)
 //   - [UAssetNativeService]: CGO-based UAsset serialization (experimental)
 //   - [InjectorService]: Windows DLL injection via CreateRemoteThread
 //
+// # Command Line
+//
+// When the first argument is a command (pack, unpack, list, uasset export,
+// uasset import), ARI-S runs headless against the same services instead of
+// opening its window; see cli.go. Results go to stdout, as JSON with
+// --json, and progress to stderr.
+//
+// # Automation API
+//
+// With the rpc_enabled preference set, a running instance serves its
+// service methods as JSON-RPC 2.0 on 127.0.0.1, over HTTP and WebSocket,
+// for Python and editor scripts. Clients authenticate with the token in the
+// data directory; see package rpc.
+//
+// # Watch Mode
+//
+// A watch session polls a mod project folder and, once edits settle,
+// imports changed JSON, packs the folder and installs the result into
+// ~mods, reporting each step as a watch:event; see package watch.
+//
 // # Configuration
 //
 // Application settings are persisted to a JSON file in the user's local
@@ -34,4 +55,8 @@
 //
 // ARI-S is Windows-only, requiring Windows 10/11 (64-bit) due to WebView2
 // and Windows-specific API dependencies.
+//
+// The backend packages also build and test on Linux. There the bundled
+// Windows builds of retoc and UAssetBridge can be run under Wine by setting
+// the tool_runner preference to "wine"; see package toolrunner.
 package main
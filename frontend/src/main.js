@@ -339,24 +339,18 @@ async function runRetocToZen() {
 
     showOutput('retoc-output', 'Starting Retoc to-zen operation...', 'info');
 
-    // Build operation
+    // Build operation; the backend validates the pack options field by field
     const operation = new RetocOperation({
         command: 'to-zen',
         input_path: inputPath,
         output_path: outputPath,
         ue_version: ueVersion,
-        options: []
+        pack: {
+            mod_name: outputBaseName,
+            priority: orderSuffix ? parseInt(orderSuffix, 10) : null
+        }
     });
 
-    // Add mod name and serialization
-    if (outputBaseName) {
-        operation.options.push('--mod-name', outputBaseName);
-    }
-
-    if (orderSuffix) {
-        operation.options.push('--serialization', orderSuffix);
-    }
-    
     // Execute operation
     RetocService.RunRetoc(operation)
         .then(result => {
@@ -6,8 +6,7 @@ import (
     "log"
     "os"
     "path/filepath"
-    "syscall"
-    "unsafe"
+    "sync"
 
     "github.com/JaceTheGrayOne/ARI-S/internal/config"
     "github.com/microcosm-cc/bluemonday"
@@ -25,19 +24,35 @@ import (
 //   - Configuration loading and persistence
 //   - Windows folder/file selection dialogs
 //   - Path validation and directory utilities
+//   - Event delivery to the frontend and in-process subscribers
+//   - The registry of running and recent operations across all services
+//   - Game profiles with per-game paths, engine version and naming rules
+//   - Per-profile AES key vaults, unlocked for the session with a passphrase
 //
 // The zero value is not usable; instances must be created with [NewApp].
+// Its methods are safe for concurrent use: the UI, job runners and RPC
+// clients all read and change the configuration at once.
 type App struct {
-	config  *config.Config
-	ctx     context.Context
-	depsDir string // Path to extracted dependencies
+	config      *config.Config
+	configMutex sync.RWMutex // Guards config, its contents and config.json
+	ctx         context.Context
+	depsDir     string // Path to extracted dependencies
+
+	events     *eventBus // In-process subscribers for Emit
+	eventsOnce sync.Once
+
+	operations     *operationRegistry // Work registered by all services
+	operationsOnce sync.Once
+
+	unlockedKeys map[string][]config.AESKey // profile ID -> decrypted AES keys
+	keysMutex    sync.Mutex
 }
 
 // NewApp creates a new App instance ready for use with Wails.
 // The returned App has no configuration loaded; call LoadConfiguration
 // after creation to restore saved settings.
 func NewApp() *App {
-	return &App{}
+	return &App{events: newEventBus(), operations: newOperationRegistry()}
 }
 
 // SetDepsDir sets the dependencies directory path for this App instance.
@@ -59,7 +74,9 @@ func (a *App) LoadConfiguration() error {
 		log.Printf("Failed to load config: %v", err)
 		cfg = config.NewDefaultConfig()
 	}
+	a.configMutex.Lock()
 	a.config = cfg
+	a.configMutex.Unlock()
 
 	// Log successfully loaded paths for debugging
 	log.Printf("Configuration loaded from: %s", configPath)
@@ -85,14 +102,21 @@ func (a *App) LoadConfiguration() error {
 // are logged but do not prevent shutdown.
 func (a *App) WailsShutdown(ctx context.Context) {
 	// Save configuration
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
 	if a.config != nil {
-		configPath := filepath.Join(getAppDataDir(), "config.json")
-		if err := config.SaveConfig(configPath, a.config); err != nil {
+		if err := a.saveConfig(); err != nil {
 			log.Printf("Failed to save config: %v", err)
 		}
 	}
 }
 
+// saveConfig writes the configuration to config.json. The caller must hold
+// configMutex for writing, so concurrent saves do not interleave.
+func (a *App) saveConfig() error {
+	return config.SaveConfig(filepath.Join(getAppDataDir(), "config.json"), a.config)
+}
+
 // getAppDataDir returns the application data directory using cross-platform standard
 func getAppDataDir() string {
 	// Use os.UserConfigDir() for cross-platform compatibility
@@ -116,10 +140,18 @@ func getAppDataDir() string {
 	return appConfigDir
 }
 
+// DataDir returns the application data directory that holds config.json,
+// extracted dependencies and other state kept by the services.
+func (a *App) DataDir() string {
+    return getAppDataDir()
+}
+
 // GetLastUsedPath retrieves the last used path for the given key from the
 // configuration. Returns an empty string if the key does not exist or if
 // the configuration is not loaded.
 func (a *App) GetLastUsedPath(key string) string {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
 	if a.config == nil {
 		return ""
 	}
@@ -127,18 +159,19 @@ func (a *App) GetLastUsedPath(key string) string {
 }
 
 // SetLastUsedPath stores the given path for the given key in the configuration
-// and immediately saves it to disk. If the configuration is not loaded, this
-// method logs an error and returns without saving.
+// and immediately saves it to disk. If the configuration is not loaded, a
+// default configuration is created and saved with the path.
 func (a *App) SetLastUsedPath(key, path string) {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
 	if a.config == nil {
-		log.Printf("ERROR: Config is nil in SetLastUsedPath - this should never happen!")
-		return
+		log.Printf("Config is not loaded in SetLastUsedPath, using defaults")
+		a.config = config.NewDefaultConfig()
 	}
 	a.config.SetLastUsedPath(key, path)
 
 	// Save config immediately after setting path
-	configPath := filepath.Join(getAppDataDir(), "config.json")
-	if err := config.SaveConfig(configPath, a.config); err != nil {
+	if err := a.saveConfig(); err != nil {
 		log.Printf("Failed to save config after setting path: %v", err)
 	} else {
 		log.Printf("Saved path for key '%s': %s", key, path)
@@ -149,6 +182,8 @@ func (a *App) SetLastUsedPath(key, path string) {
 // configuration. Returns an empty string if the key does not exist or if
 // the configuration is not loaded.
 func (a *App) GetPreference(key string) string {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
 	if a.config == nil {
 		return ""
 	}
@@ -159,6 +194,8 @@ func (a *App) GetPreference(key string) string {
 // configuration and immediately saves it to disk. If the configuration is not
 // loaded, this method logs an error and returns without saving.
 func (a *App) SetPreference(key, value string) {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
 	if a.config == nil {
 		log.Printf("ERROR: Config is nil in SetPreference - this should never happen!")
 		return
@@ -166,8 +203,7 @@ func (a *App) SetPreference(key, value string) {
 	a.config.SetPreference(key, value)
 
 	// Save config immediately after setting preference
-	configPath := filepath.Join(getAppDataDir(), "config.json")
-	if err := config.SaveConfig(configPath, a.config); err != nil {
+	if err := a.saveConfig(); err != nil {
 		log.Printf("Failed to save config after setting preference: %v", err)
 	} else {
 		log.Printf("Saved preference '%s': %s", key, value)
@@ -194,6 +230,8 @@ func (a *App) ValidateDirectory(path string) bool {
 // on startup to populate form fields. If the configuration is not loaded,
 // it returns an empty map.
 func (a *App) GetInitialConfig() map[string]string {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
 	if a.config == nil {
 		return make(map[string]string)
 	}
@@ -224,19 +262,19 @@ func (a *App) BrowseFolder(title string, key string) string {
 
 	// Get the last used path for this specific field
 	var lastPath string
-	if a.config != nil && key != "" {
-		lastPath = a.config.GetLastUsedPath(key)
+	if key != "" {
+		lastPath = a.GetLastUsedPath(key)
 	}
 
 	// If no last path, try some common defaults
-	if lastPath == "" && a.config != nil {
+	if lastPath == "" {
 		// Try to get a sensible default path from other fields
-		lastPath = a.config.GetLastUsedPath("input_mod_folder")
+		lastPath = a.GetLastUsedPath("input_mod_folder")
 		if lastPath == "" {
-			lastPath = a.config.GetLastUsedPath("pak_output_dir")
+			lastPath = a.GetLastUsedPath("pak_output_dir")
 		}
 		if lastPath == "" {
-			lastPath = a.config.GetLastUsedPath("extract_output_dir")
+			lastPath = a.GetLastUsedPath("extract_output_dir")
 		}
 	}
 
@@ -273,13 +311,13 @@ func (a *App) BrowseFile(title, filter, key string) string {
 
 	// Get last used path if config is available and key is provided
 	var lastPath string
-	if a.config != nil && key != "" {
-		lastPath = a.config.GetLastUsedPath(key)
+	if key != "" {
+		lastPath = a.GetLastUsedPath(key)
 		log.Printf("Last used path for key '%s': %s", key, lastPath)
 	}
 
-	// Try to open Windows file dialog
-	result := a.openWindowsFileDialog(title, filter, lastPath)
+	// Try to open the platform's file dialog
+	result := a.openFileDialog(title, filter, lastPath)
 	return result
 }
 
@@ -308,106 +346,6 @@ func (a *App) RenderMarkdown(md string) string {
     return safe
 }
 
-// openWindowsFileDialog opens a Windows file selection dialog
-func (a *App) openWindowsFileDialog(title, filter, initialPath string) string {
-	// Load comdlg32.dll
-	comdlg32 := syscall.NewLazyDLL("comdlg32.dll")
-	getOpenFileName := comdlg32.NewProc("GetOpenFileNameW")
-
-	// Create buffer for selected file path
-	fileBuffer := make([]uint16, 260)
-
-	// Convert filter string to Windows format (e.g., "USMAP Files\x00*.usmap\x00All Files\x00*.*\x00\x00")
-	var filterUTF16 *uint16
-	if filter == "" {
-		// Default filter for .usmap files
-		filterStr := "USMAP Files\x00*.usmap\x00All Files\x00*.*\x00\x00"
-		filterUTF16, _ = syscall.UTF16PtrFromString(filterStr)
-	} else {
-		filterUTF16, _ = syscall.UTF16PtrFromString(filter)
-	}
-
-	titleUTF16, _ := syscall.UTF16PtrFromString(title)
-
-	// Set initial directory if provided
-	var initialDirUTF16 *uint16
-	if initialPath != "" {
-		// Extract directory from file path
-		initialDir := filepath.Dir(initialPath)
-		initialDirUTF16, _ = syscall.UTF16PtrFromString(initialDir)
-		log.Printf("Setting initial directory: %s", initialDir)
-	}
-
-	// Set up OPENFILENAME structure
-	ofn := struct {
-		lStructSize       uint32
-		hwndOwner         uintptr
-		hInstance         uintptr
-		lpstrFilter       *uint16
-		lpstrCustomFilter *uint16
-		nMaxCustFilter    uint32
-		nFilterIndex      uint32
-		lpstrFile         *uint16
-		nMaxFile          uint32
-		lpstrFileTitle    *uint16
-		nMaxFileTitle     uint32
-		lpstrInitialDir   *uint16
-		lpstrTitle        *uint16
-		flags             uint32
-		nFileOffset       uint16
-		nFileExtension    uint16
-		lpstrDefExt       *uint16
-		lCustData         uintptr
-		lpfnHook          uintptr
-		lpTemplateName    *uint16
-		pvReserved        uintptr
-		dwReserved        uint32
-		FlagsEx           uint32
-	}{
-		lStructSize: uint32(unsafe.Sizeof(struct {
-			lStructSize       uint32
-			hwndOwner         uintptr
-			hInstance         uintptr
-			lpstrFilter       *uint16
-			lpstrCustomFilter *uint16
-			nMaxCustFilter    uint32
-			nFilterIndex      uint32
-			lpstrFile         *uint16
-			nMaxFile          uint32
-			lpstrFileTitle    *uint16
-			nMaxFileTitle     uint32
-			lpstrInitialDir   *uint16
-			lpstrTitle        *uint16
-			flags             uint32
-			nFileOffset       uint16
-			nFileExtension    uint16
-			lpstrDefExt       *uint16
-			lCustData         uintptr
-			lpfnHook          uintptr
-			lpTemplateName    *uint16
-			pvReserved        uintptr
-			dwReserved        uint32
-			FlagsEx           uint32
-		}{})),
-		hwndOwner:       0,
-		lpstrFilter:     filterUTF16,
-		lpstrFile:       &fileBuffer[0],
-		nMaxFile:        260,
-		lpstrInitialDir: initialDirUTF16,
-		lpstrTitle:      titleUTF16,
-		flags:           0x00000800 | 0x00001000, // OFN_FILEMUSTEXIST | OFN_PATHMUSTEXIST
-	}
-
-	// Call GetOpenFileName
-	ret, _, _ := getOpenFileName.Call(uintptr(unsafe.Pointer(&ofn)))
-	if ret == 0 {
-		return "" // User cancelled or error
-	}
-
-	// Convert back to Go string
-	return syscall.UTF16ToString(fileBuffer)
-}
-
 // GetDumper7Path returns the full path to the Dumper7.dll file in the
 // dependencies directory. Returns an empty string if the dependencies
 // directory has not been set.
@@ -2,7 +2,9 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/JaceTheGrayOne/ARI-S/internal/config"
@@ -87,3 +89,41 @@ func TestApplicationLifecycle_ConfigLoadError_UsesDefaults(t *testing.T) {
 		t.Error("Expected default preferences in new config")
 	}
 }
+
+func TestApplicationLifecycle_ConcurrentConfigAccess_IsSafe(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	app := NewApp()
+	app.LoadConfiguration()
+
+	// Run with -race; unguarded map writes also crash the process outright
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				key := fmt.Sprintf("pref_%d", i)
+				app.SetPreference(key, fmt.Sprint(j))
+				app.GetPreference(key)
+				app.SetLastUsedPath(key, fmt.Sprint(j))
+				app.GetInitialConfig()
+				if _, err := app.SaveProfile(config.GameProfile{ID: key, Name: key}); err != nil {
+					t.Errorf("SaveProfile failed: %v", err)
+					return
+				}
+				app.GetProfile(key)
+				app.ListProfiles()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := app.GetPreference("pref_3"); got != "19" {
+		t.Errorf("Expected the last write to win, got %q", got)
+	}
+	if n := len(app.ListProfiles()); n != 8 {
+		t.Errorf("Expected 8 profiles, got %d", n)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package app
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// openFileDialog opens the Wails file selection dialog. The filter is in
+// the Windows format BrowseFile documents and is converted to Wails
+// filters. The initial directory is that of initialPath.
+func (a *App) openFileDialog(title, filter, initialPath string) string {
+	if filter == "" {
+		filter = "USMAP Files\x00*.usmap\x00All Files\x00*.*\x00\x00"
+	}
+
+	dialog := application.OpenFileDialog().
+		SetTitle(title).
+		CanChooseDirectories(false).
+		CanChooseFiles(true)
+	if initialPath != "" {
+		dialog.SetDirectory(filepath.Dir(initialPath))
+	}
+
+	// The filter is pairs of display name and pattern, each ending in a NUL
+	parts := strings.Split(strings.TrimRight(filter, "\x00"), "\x00")
+	for i := 0; i+1 < len(parts); i += 2 {
+		dialog.AddFilter(parts[i], parts[i+1])
+	}
+
+	result, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		log.Printf("File selection cancelled or failed: %v", err)
+		return ""
+	}
+	return result
+}
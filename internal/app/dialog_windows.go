@@ -0,0 +1,109 @@
+package app
+
+import (
+	"log"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// openFileDialog opens a Windows file selection dialog through
+// GetOpenFileNameW. The initial directory is that of initialPath.
+func (a *App) openFileDialog(title, filter, initialPath string) string {
+	// Load comdlg32.dll
+	comdlg32 := syscall.NewLazyDLL("comdlg32.dll")
+	getOpenFileName := comdlg32.NewProc("GetOpenFileNameW")
+
+	// Create buffer for selected file path
+	fileBuffer := make([]uint16, 260)
+
+	// Convert filter string to Windows format (e.g., "USMAP Files\x00*.usmap\x00All Files\x00*.*\x00\x00")
+	var filterUTF16 *uint16
+	if filter == "" {
+		// Default filter for .usmap files
+		filterStr := "USMAP Files\x00*.usmap\x00All Files\x00*.*\x00\x00"
+		filterUTF16, _ = syscall.UTF16PtrFromString(filterStr)
+	} else {
+		filterUTF16, _ = syscall.UTF16PtrFromString(filter)
+	}
+
+	titleUTF16, _ := syscall.UTF16PtrFromString(title)
+
+	// Set initial directory if provided
+	var initialDirUTF16 *uint16
+	if initialPath != "" {
+		// Extract directory from file path
+		initialDir := filepath.Dir(initialPath)
+		initialDirUTF16, _ = syscall.UTF16PtrFromString(initialDir)
+		log.Printf("Setting initial directory: %s", initialDir)
+	}
+
+	// Set up OPENFILENAME structure
+	ofn := struct {
+		lStructSize       uint32
+		hwndOwner         uintptr
+		hInstance         uintptr
+		lpstrFilter       *uint16
+		lpstrCustomFilter *uint16
+		nMaxCustFilter    uint32
+		nFilterIndex      uint32
+		lpstrFile         *uint16
+		nMaxFile          uint32
+		lpstrFileTitle    *uint16
+		nMaxFileTitle     uint32
+		lpstrInitialDir   *uint16
+		lpstrTitle        *uint16
+		flags             uint32
+		nFileOffset       uint16
+		nFileExtension    uint16
+		lpstrDefExt       *uint16
+		lCustData         uintptr
+		lpfnHook          uintptr
+		lpTemplateName    *uint16
+		pvReserved        uintptr
+		dwReserved        uint32
+		FlagsEx           uint32
+	}{
+		lStructSize: uint32(unsafe.Sizeof(struct {
+			lStructSize       uint32
+			hwndOwner         uintptr
+			hInstance         uintptr
+			lpstrFilter       *uint16
+			lpstrCustomFilter *uint16
+			nMaxCustFilter    uint32
+			nFilterIndex      uint32
+			lpstrFile         *uint16
+			nMaxFile          uint32
+			lpstrFileTitle    *uint16
+			nMaxFileTitle     uint32
+			lpstrInitialDir   *uint16
+			lpstrTitle        *uint16
+			flags             uint32
+			nFileOffset       uint16
+			nFileExtension    uint16
+			lpstrDefExt       *uint16
+			lCustData         uintptr
+			lpfnHook          uintptr
+			lpTemplateName    *uint16
+			pvReserved        uintptr
+			dwReserved        uint32
+			FlagsEx           uint32
+		}{})),
+		hwndOwner:       0,
+		lpstrFilter:     filterUTF16,
+		lpstrFile:       &fileBuffer[0],
+		nMaxFile:        260,
+		lpstrInitialDir: initialDirUTF16,
+		lpstrTitle:      titleUTF16,
+		flags:           0x00000800 | 0x00001000, // OFN_FILEMUSTEXIST | OFN_PATHMUSTEXIST
+	}
+
+	// Call GetOpenFileName
+	ret, _, _ := getOpenFileName.Call(uintptr(unsafe.Pointer(&ofn)))
+	if ret == 0 {
+		return "" // User cancelled or error
+	}
+
+	// Convert back to Go string
+	return syscall.UTF16ToString(fileBuffer)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// EventHandler receives the payload of an event emitted through [App.Emit].
+type EventHandler func(data any)
+
+// eventBus fans events out to in-process subscribers. It lets headless
+// callers (tests, scripts, the CLI) observe the same events the frontend
+// receives through Wails.
+type eventBus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[string]map[int]EventHandler
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{handlers: make(map[string]map[int]EventHandler)}
+}
+
+func (b *eventBus) subscribe(name string, handler EventHandler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	if b.handlers[name] == nil {
+		b.handlers[name] = make(map[int]EventHandler)
+	}
+	b.handlers[name][id] = handler
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.handlers[name], id)
+			if len(b.handlers[name]) == 0 {
+				delete(b.handlers, name)
+			}
+		})
+	}
+}
+
+func (b *eventBus) publish(name string, data any) {
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.handlers[name]))
+	for _, h := range b.handlers[name] {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(data)
+	}
+}
+
+// Emit publishes an event to in-process subscribers and, when a Wails
+// application is running, to the frontend. Handlers are invoked
+// synchronously on the calling goroutine.
+func (a *App) Emit(name string, data any) {
+	a.eventBus().publish(name, data)
+
+	if wailsApp := application.Get(); wailsApp != nil && wailsApp.Event != nil {
+		wailsApp.Event.Emit(name, data)
+	}
+}
+
+// Subscribe registers handler for events with the given name and returns a
+// function that removes the subscription. It is safe to call the returned
+// function more than once.
+func (a *App) Subscribe(name string, handler EventHandler) func() {
+	return a.eventBus().subscribe(name, handler)
+}
+
+// eventBus returns the App's bus, creating it on first use so that an App
+// built without NewApp can still emit events.
+func (a *App) eventBus() *eventBus {
+	a.eventsOnce.Do(func() {
+		if a.events == nil {
+			a.events = newEventBus()
+		}
+	})
+	return a.events
+}
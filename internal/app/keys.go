@@ -0,0 +1,153 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+// SaveGameKeys encrypts keys with passphrase, stores them as the key vault
+// of the game profile with the given ID and immediately saves the
+// configuration to disk. The keys stay unlocked for the rest of the session.
+func (a *App) SaveGameKeys(profileID string, keys []config.AESKey, passphrase string) error {
+	vault, err := a.storeKeyVault(profileID, keys, passphrase)
+	if err != nil {
+		return err
+	}
+	log.Printf("Saved %d AES key(s) for profile '%s'", len(keys), profileID)
+
+	// Cache the normalized keys, as Open would return them
+	unlocked, err := vault.Open(passphrase)
+	if err != nil {
+		return err
+	}
+	a.setUnlockedKeys(profileID, unlocked)
+	return nil
+}
+
+// storeKeyVault seals keys with passphrase as the key vault of the given
+// profile and saves the configuration. Sealing is slow by design, so it is
+// done before the configuration is locked.
+func (a *App) storeKeyVault(profileID string, keys []config.AESKey, passphrase string) (*config.KeyVault, error) {
+	vault, err := config.SealKeyVault(keys, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return nil, errors.New("configuration not loaded")
+	}
+	if a.config.GetProfile(profileID) == nil {
+		return nil, fmt.Errorf("unknown game profile '%s'", profileID)
+	}
+	a.config.SetKeyVault(profileID, vault)
+	if err := a.saveConfig(); err != nil {
+		return nil, fmt.Errorf("failed to save config: %v", err)
+	}
+	return vault, nil
+}
+
+// UnlockGameKeys decrypts the key vault of the given profile with
+// passphrase and keeps the keys in memory until [App.LockGameKeys] is
+// called or the app exits.
+func (a *App) UnlockGameKeys(profileID, passphrase string) error {
+	vault := a.keyVault(profileID)
+	if vault == nil {
+		return fmt.Errorf("no AES keys stored for profile '%s'", profileID)
+	}
+	keys, err := vault.Open(passphrase)
+	if err != nil {
+		return err
+	}
+	a.setUnlockedKeys(profileID, keys)
+	return nil
+}
+
+// LockGameKeys forgets the decrypted keys of the given profile.
+func (a *App) LockGameKeys(profileID string) {
+	a.keysMutex.Lock()
+	defer a.keysMutex.Unlock()
+	delete(a.unlockedKeys, profileID)
+}
+
+// IsGameUnlocked reports whether the keys of the given profile are
+// unlocked.
+func (a *App) IsGameUnlocked(profileID string) bool {
+	a.keysMutex.Lock()
+	defer a.keysMutex.Unlock()
+	_, ok := a.unlockedKeys[profileID]
+	return ok
+}
+
+// DeleteGameKeys removes the key vault of the given profile and immediately
+// saves the configuration to disk.
+func (a *App) DeleteGameKeys(profileID string) error {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return errors.New("configuration not loaded")
+	}
+	a.LockGameKeys(profileID)
+	a.config.DeleteKeyVault(profileID)
+
+	if err := a.saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
+
+// ListKeyVaults returns the IDs of profiles with stored AES keys, sorted.
+func (a *App) ListKeyVaults() []string {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.config == nil {
+		return []string{}
+	}
+	ids := []string{}
+	for id, p := range a.config.Profiles {
+		if p.KeyVault != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GameKeys returns the unlocked AES keys of the given profile. It returns
+// no keys and no error if the profile has no key vault, and fails if the
+// vault has not been unlocked this session.
+func (a *App) GameKeys(profileID string) ([]config.AESKey, error) {
+	a.keysMutex.Lock()
+	keys, ok := a.unlockedKeys[profileID]
+	a.keysMutex.Unlock()
+	if ok {
+		return keys, nil
+	}
+	if a.keyVault(profileID) == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("AES keys for profile '%s' are locked; unlock them with the vault passphrase", profileID)
+}
+
+func (a *App) keyVault(profileID string) *config.KeyVault {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.config == nil {
+		return nil
+	}
+	return a.config.GetKeyVault(profileID)
+}
+
+func (a *App) setUnlockedKeys(profileID string, keys []config.AESKey) {
+	a.keysMutex.Lock()
+	defer a.keysMutex.Unlock()
+	if a.unlockedKeys == nil {
+		a.unlockedKeys = make(map[string][]config.AESKey)
+	}
+	a.unlockedKeys[profileID] = keys
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+// GetNamingScheme returns the packed file naming scheme of the given game
+// profile. The zero scheme, which selects the default preset, is returned
+// if none is stored or the configuration is not loaded.
+func (a *App) GetNamingScheme(profileID string) config.NamingScheme {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.config == nil {
+		return config.NamingScheme{}
+	}
+	return a.config.GetNamingScheme(profileID)
+}
+
+// SetNamingScheme stores the naming scheme of the given game profile and
+// immediately saves the configuration to disk. The scheme is not validated
+// here; callers should use RetocService.SetProfileNaming, which is.
+func (a *App) SetNamingScheme(profileID string, scheme config.NamingScheme) error {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return errors.New("configuration not loaded")
+	}
+	if a.config.GetProfile(profileID) == nil {
+		return fmt.Errorf("unknown game profile '%s'", profileID)
+	}
+	a.config.SetNamingScheme(profileID, scheme)
+
+	if err := a.saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
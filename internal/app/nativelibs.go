@@ -10,8 +10,6 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"syscall"
-	"unsafe"
 )
 
 // EnsureNativeLibraries extracts embedded native libraries on first run and returns the extraction path.
@@ -81,38 +79,3 @@ func EnsureNativeLibraries(nativeLibsFS embed.FS) (string, error) {
 
 	return nativeLibsDir, nil
 }
-
-// addDLLSearchPath adds a directory to the DLL search path on Windows.
-// This allows the runtime linker to find DLLs in the specified directory.
-func addDLLSearchPath(dir string) error {
-	if runtime.GOOS != "windows" {
-		return nil // No-op on non-Windows platforms
-	}
-
-	// Convert Go string to UTF-16 for Windows API
-	dirPtr, err := syscall.UTF16PtrFromString(dir)
-	if err != nil {
-		return fmt.Errorf("failed to convert path to UTF-16: %w", err)
-	}
-
-	// Load kernel32.dll
-	kernel32, err := syscall.LoadDLL("kernel32.dll")
-	if err != nil {
-		return fmt.Errorf("failed to load kernel32.dll: %w", err)
-	}
-	defer kernel32.Release()
-
-	// Get SetDllDirectory function
-	setDllDirectory, err := kernel32.FindProc("SetDllDirectoryW")
-	if err != nil {
-		return fmt.Errorf("failed to find SetDllDirectoryW: %w", err)
-	}
-
-	// Call SetDllDirectoryW
-	ret, _, callErr := setDllDirectory.Call(uintptr(unsafe.Pointer(dirPtr)))
-	if ret == 0 {
-		return fmt.Errorf("SetDllDirectoryW failed: %w", callErr)
-	}
-
-	return nil
-}
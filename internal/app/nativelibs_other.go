@@ -0,0 +1,10 @@
+//go:build cgo && !windows
+// +build cgo,!windows
+
+package app
+
+// addDLLSearchPath is a no-op on non-Windows platforms, where the runtime
+// linker does not search a DLL directory.
+func addDLLSearchPath(dir string) error {
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build cgo
+// +build cgo
+
+package app
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// addDLLSearchPath adds a directory to the DLL search path on Windows.
+// This allows the runtime linker to find DLLs in the specified directory.
+func addDLLSearchPath(dir string) error {
+	// Convert Go string to UTF-16 for Windows API
+	dirPtr, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return fmt.Errorf("failed to convert path to UTF-16: %w", err)
+	}
+
+	// Load kernel32.dll
+	kernel32, err := syscall.LoadDLL("kernel32.dll")
+	if err != nil {
+		return fmt.Errorf("failed to load kernel32.dll: %w", err)
+	}
+	defer kernel32.Release()
+
+	// Get SetDllDirectory function
+	setDllDirectory, err := kernel32.FindProc("SetDllDirectoryW")
+	if err != nil {
+		return fmt.Errorf("failed to find SetDllDirectoryW: %w", err)
+	}
+
+	// Call SetDllDirectoryW
+	ret, _, callErr := setDllDirectory.Call(uintptr(unsafe.Pointer(dirPtr)))
+	if ret == 0 {
+		return fmt.Errorf("SetDllDirectoryW failed: %w", callErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OperationState is the lifecycle state of an operation. Operations move
+// from queued to running to one of the final states; a queued operation
+// may also be cancelled before it runs.
+type OperationState string
+
+const (
+	OperationQueued    OperationState = "queued"
+	OperationRunning   OperationState = "running"
+	OperationSucceeded OperationState = "succeeded"
+	OperationFailed    OperationState = "failed"
+	OperationCancelled OperationState = "cancelled"
+)
+
+// Finished reports whether s is a final state.
+func (s OperationState) Finished() bool {
+	return s == OperationSucceeded || s == OperationFailed || s == OperationCancelled
+}
+
+// EventOperation is emitted with an [Operation] whenever an operation is
+// registered or changes state.
+const EventOperation = "operation:changed"
+
+// maxFinishedOperations is how many finished operations are kept for
+// [App.ListOperations] after they end.
+const maxFinishedOperations = 100
+
+// Operation describes a unit of work registered by a service, such as a
+// retoc pack or a UAsset export.
+type Operation struct {
+	ID          string         `json:"id"`
+	Owner       string         `json:"owner"` // Service that runs it, e.g. "retoc"
+	Kind        string         `json:"kind"`  // What it does, e.g. "to-zen" or "export"
+	Description string         `json:"description"`
+	State       OperationState `json:"state"`
+	Error       string         `json:"error,omitempty"`
+	QueuedAt    time.Time      `json:"queued_at"`
+	StartedAt   time.Time      `json:"started_at,omitzero"`
+	EndedAt     time.Time      `json:"ended_at,omitzero"`
+}
+
+// operationRegistry tracks the operations of all services, active ones
+// with the function that cancels them.
+type operationRegistry struct {
+	mu       sync.Mutex
+	ops      map[string]*Operation
+	cancels  map[string]context.CancelFunc // Active operations only
+	finished []string                      // Finished operation IDs, oldest first
+}
+
+func newOperationRegistry() *operationRegistry {
+	return &operationRegistry{
+		ops:     make(map[string]*Operation),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// OperationHandle is held by the service running an operation to report
+// its progress through the lifecycle.
+type OperationHandle struct {
+	app *App
+	id  string
+	ctx context.Context
+}
+
+// ID returns the operation ID.
+func (h *OperationHandle) ID() string {
+	return h.id
+}
+
+// Context returns the operation's context, which is cancelled when the
+// operation is cancelled or the parent context passed at registration is.
+func (h *OperationHandle) Context() context.Context {
+	return h.ctx
+}
+
+// Start moves a queued operation to running. It returns false if the
+// operation was cancelled while queued, in which case it must not run.
+func (h *OperationHandle) Start() bool {
+	return h.app.updateOperation(h.id, func(op *Operation) bool {
+		if op.State != OperationQueued {
+			return false
+		}
+		op.State = OperationRunning
+		op.StartedAt = time.Now()
+		return true
+	})
+}
+
+// Finish ends the operation: succeeded if err is nil, otherwise cancelled
+// if its context was cancelled and failed if not. Calls after the first
+// have no effect.
+func (h *OperationHandle) Finish(err error) {
+	h.app.updateOperation(h.id, func(op *Operation) bool {
+		if op.State.Finished() {
+			return false
+		}
+		switch {
+		case err == nil:
+			op.State = OperationSucceeded
+		case h.ctx.Err() != nil:
+			op.State = OperationCancelled
+		default:
+			op.State = OperationFailed
+			op.Error = err.Error()
+		}
+		op.EndedAt = time.Now()
+		return true
+	})
+}
+
+// FinishResult ends the operation from a service result's success flag
+// and error message, as Finish does.
+func (h *OperationHandle) FinishResult(success bool, errMessage string) {
+	if success {
+		h.Finish(nil)
+		return
+	}
+	if errMessage == "" {
+		errMessage = "operation failed"
+	}
+	h.Finish(errors.New(errMessage))
+}
+
+// RegisterOperation registers queued work of the given owner and kind and
+// returns its handle. The caller must call Start before doing the work and
+// Finish when it ends; services that run work right away can use
+// [App.StartOperation] instead.
+func (a *App) RegisterOperation(ctx context.Context, owner, kind, description string) *OperationHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	op := &Operation{
+		ID:          uuid.New().String(),
+		Owner:       owner,
+		Kind:        kind,
+		Description: description,
+		State:       OperationQueued,
+		QueuedAt:    time.Now(),
+	}
+
+	r := a.operationRegistry()
+	r.mu.Lock()
+	r.ops[op.ID] = op
+	r.cancels[op.ID] = cancel
+	snapshot := *op
+	r.mu.Unlock()
+
+	a.Emit(EventOperation, snapshot)
+	return &OperationHandle{app: a, id: op.ID, ctx: ctx}
+}
+
+// StartOperation registers work that starts immediately and returns its
+// handle, already running. The caller must call Finish when it ends.
+func (a *App) StartOperation(ctx context.Context, owner, kind, description string) *OperationHandle {
+	h := a.RegisterOperation(ctx, owner, kind, description)
+	h.Start()
+	return h
+}
+
+// ListOperations returns the active operations and the most recently
+// finished ones, oldest first.
+func (a *App) ListOperations() []Operation {
+	r := a.operationRegistry()
+	r.mu.Lock()
+	ops := make([]Operation, 0, len(r.ops))
+	for _, op := range r.ops {
+		ops = append(ops, *op)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(ops, func(x, y Operation) int {
+		return x.QueuedAt.Compare(y.QueuedAt)
+	})
+	return ops
+}
+
+// GetOperation returns the operation with the given ID.
+func (a *App) GetOperation(id string) (Operation, error) {
+	r := a.operationRegistry()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op, ok := r.ops[id]
+	if !ok {
+		return Operation{}, fmt.Errorf("operation %s not found", id)
+	}
+	return *op, nil
+}
+
+// CancelOperation cancels the operation with the given ID. A queued
+// operation is marked cancelled at once; a running one when its service
+// sees the cancellation and finishes. It returns an error if the operation
+// is unknown or has already finished.
+func (a *App) CancelOperation(id string) error {
+	r := a.operationRegistry()
+	r.mu.Lock()
+	cancel := r.cancels[id]
+	r.mu.Unlock()
+	if cancel == nil {
+		return fmt.Errorf("operation %s not found or already completed", id)
+	}
+
+	cancel()
+	a.updateOperation(id, func(op *Operation) bool {
+		if op.State != OperationQueued {
+			return false
+		}
+		op.State = OperationCancelled
+		op.EndedAt = time.Now()
+		return true
+	})
+	return nil
+}
+
+// updateOperation applies change to the operation with the given ID and,
+// if change reports that it changed something, emits [EventOperation].
+// Operations that reach a final state are released and retained among the
+// finished ones.
+func (a *App) updateOperation(id string, change func(op *Operation) bool) bool {
+	r := a.operationRegistry()
+	r.mu.Lock()
+	op, ok := r.ops[id]
+	if !ok || !change(op) {
+		r.mu.Unlock()
+		return false
+	}
+	if op.State.Finished() {
+		if cancel := r.cancels[id]; cancel != nil {
+			cancel()
+			delete(r.cancels, id)
+		}
+		r.finished = append(r.finished, id)
+		if len(r.finished) > maxFinishedOperations {
+			delete(r.ops, r.finished[0])
+			r.finished = r.finished[1:]
+		}
+	}
+	snapshot := *op
+	r.mu.Unlock()
+
+	a.Emit(EventOperation, snapshot)
+	return true
+}
+
+// operationRegistry returns the App's registry, creating it on first use
+// so that an App built without NewApp can still register operations.
+func (a *App) operationRegistry() *operationRegistry {
+	a.operationsOnce.Do(func() {
+		if a.operations == nil {
+			a.operations = newOperationRegistry()
+		}
+	})
+	return a.operations
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOperations_Lifecycle_RecordsStatesAndTimes(t *testing.T) {
+	app := NewApp()
+	var states []OperationState
+	defer app.Subscribe(EventOperation, func(data any) {
+		states = append(states, data.(Operation).State)
+	})()
+
+	op := app.RegisterOperation(context.Background(), "retoc", "unpack", "input")
+	if !op.Start() {
+		t.Fatal("Expected Start to succeed for a queued operation")
+	}
+	op.Finish(nil)
+
+	got, err := app.GetOperation(op.ID())
+	if err != nil {
+		t.Fatalf("GetOperation failed: %v", err)
+	}
+	if got.State != OperationSucceeded || got.Owner != "retoc" || got.Kind != "unpack" {
+		t.Errorf("Unexpected operation %+v", got)
+	}
+	if got.StartedAt.IsZero() || got.EndedAt.Before(got.StartedAt) {
+		t.Errorf("Expected start and end times, got %v and %v", got.StartedAt, got.EndedAt)
+	}
+	want := []OperationState{OperationQueued, OperationRunning, OperationSucceeded}
+	if len(states) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, want[i], states[i])
+		}
+	}
+	if op.Context().Err() == nil {
+		t.Error("Expected the context to be released when the operation finished")
+	}
+}
+
+func TestOperations_SameKind_TrackedIndependently(t *testing.T) {
+	app := NewApp()
+	first := app.StartOperation(context.Background(), "retoc", "unpack", "a")
+	second := app.StartOperation(context.Background(), "retoc", "unpack", "b")
+
+	if err := app.CancelOperation(first.ID()); err != nil {
+		t.Fatalf("CancelOperation failed: %v", err)
+	}
+	if first.Context().Err() == nil {
+		t.Error("Expected the first operation to be cancelled")
+	}
+	if second.Context().Err() != nil {
+		t.Error("Expected the second operation to keep running")
+	}
+
+	first.Finish(context.Canceled)
+	second.Finish(errors.New("boom"))
+	ops := app.ListOperations()
+	if len(ops) != 2 || ops[0].ID != first.ID() {
+		t.Fatalf("Expected both operations oldest first, got %+v", ops)
+	}
+	if ops[0].State != OperationCancelled {
+		t.Errorf("Expected the first to be cancelled, got %s", ops[0].State)
+	}
+	if ops[1].State != OperationFailed || ops[1].Error != "boom" {
+		t.Errorf("Expected the second to fail with its error, got %+v", ops[1])
+	}
+}
+
+func TestOperations_CancelQueued_DoesNotStart(t *testing.T) {
+	app := NewApp()
+	op := app.RegisterOperation(context.Background(), "uasset", "export", "")
+
+	if err := app.CancelOperation(op.ID()); err != nil {
+		t.Fatalf("CancelOperation failed: %v", err)
+	}
+	if op.Start() {
+		t.Error("Expected Start to fail for a cancelled operation")
+	}
+	if got, _ := app.GetOperation(op.ID()); got.State != OperationCancelled {
+		t.Errorf("Expected cancelled, got %s", got.State)
+	}
+	if err := app.CancelOperation(op.ID()); err == nil {
+		t.Error("Expected an error cancelling a finished operation")
+	}
+}
+
+func TestOperations_FinishedHistory_IsBounded(t *testing.T) {
+	app := NewApp()
+	first := app.StartOperation(context.Background(), "retoc", "list", "")
+	first.Finish(nil)
+	for i := 0; i < maxFinishedOperations; i++ {
+		app.StartOperation(context.Background(), "retoc", "list", "").Finish(nil)
+	}
+
+	if n := len(app.ListOperations()); n != maxFinishedOperations {
+		t.Errorf("Expected %d operations, got %d", maxFinishedOperations, n)
+	}
+	if _, err := app.GetOperation(first.ID()); err == nil {
+		t.Error("Expected the oldest finished operation to be dropped")
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+// ListProfiles returns all game profiles sorted by name. Key vaults are
+// left out; use the key methods such as [App.IsGameUnlocked] instead.
+func (a *App) ListProfiles() []config.GameProfile {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.config == nil {
+		return []config.GameProfile{}
+	}
+	stored := a.config.ListProfiles()
+	profiles := make([]config.GameProfile, len(stored))
+	for i, p := range stored {
+		profiles[i] = *p
+		profiles[i].KeyVault = nil
+	}
+	return profiles
+}
+
+// GetProfile returns the game profile with the given ID, without its key
+// vault.
+func (a *App) GetProfile(id string) (config.GameProfile, error) {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.config == nil {
+		return config.GameProfile{}, errors.New("configuration not loaded")
+	}
+	p := a.config.GetProfile(id)
+	if p == nil {
+		return config.GameProfile{}, fmt.Errorf("unknown game profile '%s'", id)
+	}
+	profile := *p
+	profile.KeyVault = nil
+	return profile, nil
+}
+
+// SaveProfile creates or updates a game profile and immediately saves the
+// configuration to disk. A profile with an empty ID is created with an ID
+// derived from its name. The stored key vault is kept; the KeyVault field
+// of profile is ignored. Returns the saved profile.
+func (a *App) SaveProfile(profile config.GameProfile) (config.GameProfile, error) {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return config.GameProfile{}, errors.New("configuration not loaded")
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return config.GameProfile{}, errors.New("profile name must not be empty")
+	}
+
+	profile.KeyVault = nil
+	if profile.ID == "" {
+		profile.ID = a.config.NewProfileID(profile.Name)
+	} else if existing := a.config.GetProfile(profile.ID); existing != nil {
+		profile.KeyVault = existing.KeyVault
+	}
+	stored := profile
+	a.config.SetProfile(&stored)
+
+	if err := a.saveConfig(); err != nil {
+		return config.GameProfile{}, fmt.Errorf("failed to save config: %v", err)
+	}
+	profile.KeyVault = nil
+	return profile, nil
+}
+
+// DeleteProfile removes the game profile with the given ID, including its
+// key vault, and immediately saves the configuration to disk.
+func (a *App) DeleteProfile(id string) error {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return errors.New("configuration not loaded")
+	}
+	if a.config.GetProfile(id) == nil {
+		return fmt.Errorf("unknown game profile '%s'", id)
+	}
+	a.LockGameKeys(id)
+	a.config.DeleteProfile(id)
+
+	if err := a.saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
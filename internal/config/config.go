@@ -8,13 +8,24 @@ import (
 
 // Config stores application settings including last-used paths for file/folder
 // selection dialogs and user preferences such as the selected Unreal Engine
-// version. The configuration is persisted to disk as JSON in the user's
-// local AppData directory.
+// version. Per-game settings, including AES keys encrypted with a user
+// passphrase (see [KeyVault]), are stored in [GameProfile]s. The
+// configuration is persisted to disk as JSON in the user's local AppData
+// directory.
 //
 // Config is safe for concurrent use by multiple goroutines after initialization.
 type Config struct {
-	LastUsedPaths map[string]string `json:"last_used_paths"`
-	Preferences   map[string]string `json:"preferences"`
+	LastUsedPaths map[string]string       `json:"last_used_paths"`
+	Preferences   map[string]string       `json:"preferences"`
+	Profiles      map[string]*GameProfile `json:"profiles,omitempty"` // ID -> profile
+}
+
+// NamingScheme selects how packed mod files are named for one game. Preset
+// names one of the built-in schemes (see retoc.NamingPresets); Template, if
+// set, replaces the preset's template but keeps its prefix and patch suffix.
+type NamingScheme struct {
+	Preset   string `json:"preset"`
+	Template string `json:"template,omitempty"`
 }
 
 // NewDefaultConfig creates a Config with default values. The returned Config
@@ -63,6 +74,11 @@ func LoadConfig(filePath string) (*Config, error) {
 	if config.Preferences == nil {
 		config.Preferences = make(map[string]string)
 	}
+	for id, p := range config.Profiles {
+		if p.ID == "" {
+			p.ID = id
+		}
+	}
 
 	return config, nil
 }
@@ -125,3 +141,19 @@ func (c *Config) SetPreference(key, value string) {
 	}
 	c.Preferences[key] = value
 }
+
+// GetNamingScheme returns the naming scheme of the profile with the given
+// ID, or the zero NamingScheme (the default preset) if none.
+func (c *Config) GetNamingScheme(profileID string) NamingScheme {
+	if p := c.GetProfile(profileID); p != nil {
+		return p.Naming
+	}
+	return NamingScheme{}
+}
+
+// SetNamingScheme stores scheme in the profile with the given ID, creating
+// the profile if needed. This method does not persist the change to disk;
+// call SaveConfig to write changes.
+func (c *Config) SetNamingScheme(profileID string, scheme NamingScheme) {
+	c.profile(profileID).Naming = scheme
+}
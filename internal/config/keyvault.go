@@ -0,0 +1,219 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrWrongPassphrase is returned by [KeyVault.Open] when the passphrase does
+// not decrypt the vault.
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// keyVaultIterations is the PBKDF2-SHA256 work factor for new vaults.
+const keyVaultIterations = 600_000
+
+// AESKey is one AES-256 key for a game's IoStore containers. Games that use
+// several keys select them by the container's encryption key GUID; the key
+// with an empty GUID is the default, used for containers whose GUID is zero
+// or matches no other key.
+type AESKey struct {
+	GUID string `json:"guid"` // 32 hex digits, e.g. "A1B2C3D4..."; empty for the default key
+	Key  string `json:"key"`  // 64 hex digits with a "0x" prefix
+}
+
+// KeyVault holds a game's AES keys encrypted at rest with a key derived
+// from a user passphrase (PBKDF2-SHA256 and AES-256-GCM). Only the
+// ciphertext is ever written to the config file.
+type KeyVault struct {
+	Salt       string `json:"salt"`  // Base64
+	Nonce      string `json:"nonce"` // Base64
+	Data       string `json:"data"`  // Base64 ciphertext of the JSON-encoded keys
+	Iterations int    `json:"iterations"`
+}
+
+// SealKeyVault normalizes keys and encrypts them with passphrase. Each key
+// must be a valid AES-256 key and GUIDs must be unique.
+func SealKeyVault(keys []AESKey, passphrase string) (*KeyVault, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	normalized, err := NormalizeAESKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := vaultCipher(passphrase, salt, keyVaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &KeyVault{
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Data:       base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+		Iterations: keyVaultIterations,
+	}, nil
+}
+
+// Open decrypts the vault with passphrase and returns its keys.
+func (v *KeyVault) Open(passphrase string) ([]AESKey, error) {
+	salt, err := base64.StdEncoding.DecodeString(v.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt key vault salt: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(v.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt key vault nonce: %v", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(v.Data)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt key vault data: %v", err)
+	}
+
+	gcm, err := vaultCipher(passphrase, salt, v.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("corrupt key vault nonce: %d bytes", len(nonce))
+	}
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var keys []AESKey
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("corrupt key vault contents: %v", err)
+	}
+	return keys, nil
+}
+
+func vaultCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("invalid key vault iteration count: %d", iterations)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NormalizeAESKeys validates keys and returns them in canonical form:
+// "0x"-prefixed upper-case hex keys and upper-case GUIDs without dashes or
+// braces, with an all-zero GUID treated as the default key.
+func NormalizeAESKeys(keys []AESKey) ([]AESKey, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one AES key is required")
+	}
+	seen := make(map[string]bool)
+	out := make([]AESKey, 0, len(keys))
+	for _, k := range keys {
+		guid, err := NormalizeGUID(k.GUID)
+		if err != nil {
+			return nil, err
+		}
+		if seen[guid] {
+			if guid == "" {
+				return nil, errors.New("more than one default AES key")
+			}
+			return nil, fmt.Errorf("duplicate AES key GUID: %s", guid)
+		}
+		seen[guid] = true
+
+		raw, err := ParseAESKey(k.Key)
+		if err != nil {
+			if guid != "" {
+				return nil, fmt.Errorf("AES key for GUID %s: %v", guid, err)
+			}
+			return nil, err
+		}
+		out = append(out, AESKey{GUID: guid, Key: "0x" + strings.ToUpper(hex.EncodeToString(raw))})
+	}
+	return out, nil
+}
+
+// ParseAESKey decodes an AES-256 key written as 64 hex digits, with or
+// without a "0x" prefix, or as base64 (the form some key dumps use).
+func ParseAESKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	hexDigits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if raw, err := hex.DecodeString(hexDigits); err == nil {
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("AES key must be 32 bytes, got %d", len(raw))
+		}
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("AES key must be 64 hex digits or base64")
+}
+
+// NormalizeGUID returns guid as 32 upper-case hex digits with any dashes or
+// braces removed. An empty or all-zero GUID normalizes to "".
+func NormalizeGUID(guid string) (string, error) {
+	g := strings.ToUpper(strings.Trim(strings.TrimSpace(guid), "{}"))
+	g = strings.ReplaceAll(g, "-", "")
+	if g == "" {
+		return "", nil
+	}
+	if _, err := hex.DecodeString(g); err != nil || len(g) != 32 {
+		return "", fmt.Errorf("invalid key GUID: %q", guid)
+	}
+	if strings.Trim(g, "0") == "" {
+		return "", nil
+	}
+	return g, nil
+}
+
+// GetKeyVault returns the key vault of the profile with the given ID, or
+// nil if none.
+func (c *Config) GetKeyVault(profileID string) *KeyVault {
+	if p := c.GetProfile(profileID); p != nil {
+		return p.KeyVault
+	}
+	return nil
+}
+
+// SetKeyVault stores vault in the profile with the given ID, creating the
+// profile if needed. This method does not persist the change to disk; call
+// SaveConfig to write changes.
+func (c *Config) SetKeyVault(profileID string, vault *KeyVault) {
+	c.profile(profileID).KeyVault = vault
+}
+
+// DeleteKeyVault removes the key vault of the profile with the given ID.
+// This method does not persist the change to disk; call SaveConfig to write
+// changes.
+func (c *Config) DeleteKeyVault(profileID string) {
+	if p := c.GetProfile(profileID); p != nil {
+		p.KeyVault = nil
+	}
+}
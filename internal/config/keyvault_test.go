@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKey = "0x0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestKeyVault_SealAndOpen_RoundTrips(t *testing.T) {
+	keys := []AESKey{
+		{Key: testKey},
+		{GUID: "{a1b2c3d4-0000-0000-0000-000000000001}", Key: strings.TrimPrefix(testKey, "0x")},
+	}
+
+	vault, err := SealKeyVault(keys, "hunter2")
+	if err != nil {
+		t.Fatalf("SealKeyVault failed: %v", err)
+	}
+	if strings.Contains(vault.Data, "0123456789") {
+		t.Error("Expected vault data to be encrypted")
+	}
+
+	opened, err := vault.Open("hunter2")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(opened) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(opened))
+	}
+	if opened[0].GUID != "" || opened[0].Key != "0x"+strings.ToUpper(testKey[2:]) {
+		t.Errorf("Unexpected default key: %+v", opened[0])
+	}
+	if opened[1].GUID != "A1B2C3D4000000000000000000000001" {
+		t.Errorf("Expected normalized GUID, got %q", opened[1].GUID)
+	}
+}
+
+func TestKeyVault_Open_WrongPassphrase_ReturnsError(t *testing.T) {
+	vault, err := SealKeyVault([]AESKey{{Key: testKey}}, "hunter2")
+	if err != nil {
+		t.Fatalf("SealKeyVault failed: %v", err)
+	}
+
+	if _, err := vault.Open("hunter3"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestKeyVault_Seal_RejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []AESKey
+	}{
+		{"no keys", nil},
+		{"short key", []AESKey{{Key: "0x1234"}}},
+		{"bad GUID", []AESKey{{GUID: "not-a-guid", Key: testKey}}},
+		{"two defaults", []AESKey{{Key: testKey}, {GUID: "00000000000000000000000000000000", Key: testKey}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := SealKeyVault(tt.keys, "hunter2"); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestKeyVault_Persistence_SurvivesSaveAndLoad(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	vault, err := SealKeyVault([]AESKey{{Key: testKey}}, "hunter2")
+	if err != nil {
+		t.Fatalf("SealKeyVault failed: %v", err)
+	}
+	cfg := NewDefaultConfig()
+	cfg.SetKeyVault("MyGame", vault)
+	if err := SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	raw, _ := os.ReadFile(configPath)
+	if strings.Contains(strings.ToUpper(string(raw)), strings.ToUpper(testKey[2:])) {
+		t.Error("Expected AES key not to be stored in plaintext")
+	}
+
+	loaded, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	keys, err := loaded.GetKeyVault("MyGame").Open("hunter2")
+	if err != nil || len(keys) != 1 {
+		t.Errorf("Expected to open persisted vault, got %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GameProfile holds the settings for modding one game. Services take a
+// profile ID and read the paths, engine version, mappings, AES keys and
+// naming rules from the profile instead of separate parameters.
+type GameProfile struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	InstallDir   string       `json:"install_dir"`
+	PaksDir      string       `json:"paks_dir"`      // Content/Paks directory
+	ModsDir      string       `json:"mods_dir"`      // Defaults to PaksDir/~mods
+	UEVersion    string       `json:"ue_version"`    // e.g. "UE5_4"; empty detects it from InstallDir or PaksDir
+	MappingsPath string       `json:"mappings_path"` // .usmap file for UAsset operations
+	Naming       NamingScheme `json:"naming"`
+	KeyVault     *KeyVault    `json:"key_vault,omitempty"` // Encrypted AES keys
+}
+
+// ResolvedModsDir returns ModsDir, or the ~mods directory under PaksDir if
+// ModsDir is not set. It returns an empty string if neither is set.
+func (p *GameProfile) ResolvedModsDir() string {
+	if p.ModsDir != "" {
+		return p.ModsDir
+	}
+	if p.PaksDir != "" {
+		return filepath.Join(p.PaksDir, "~mods")
+	}
+	return ""
+}
+
+// GetProfile returns the profile with the given ID, or nil if none.
+func (c *Config) GetProfile(id string) *GameProfile {
+	return c.Profiles[id]
+}
+
+// ListProfiles returns all profiles sorted by name.
+func (c *Config) ListProfiles() []*GameProfile {
+	profiles := make([]*GameProfile, 0, len(c.Profiles))
+	for _, p := range c.Profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+	return profiles
+}
+
+// SetProfile stores p under p.ID, replacing any profile with that ID. If
+// the Profiles map is nil, it is initialized. This method does not persist
+// the change to disk; call SaveConfig to write changes.
+func (c *Config) SetProfile(p *GameProfile) {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*GameProfile)
+	}
+	c.Profiles[p.ID] = p
+}
+
+// DeleteProfile removes the profile with the given ID. This method does not
+// persist the change to disk; call SaveConfig to write changes.
+func (c *Config) DeleteProfile(id string) {
+	delete(c.Profiles, id)
+}
+
+// NewProfileID returns an unused profile ID derived from name, e.g.
+// "my-game" or "my-game-2".
+func (c *Config) NewProfileID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	base := strings.TrimSuffix(b.String(), "-")
+	if base == "" {
+		base = "game"
+	}
+
+	id := base
+	for n := 2; c.Profiles[id] != nil; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+// profile returns the profile with the given ID, creating one named after
+// the ID if it does not exist.
+func (c *Config) profile(id string) *GameProfile {
+	p := c.Profiles[id]
+	if p == nil {
+		p = &GameProfile{ID: id, Name: id}
+		c.SetProfile(p)
+	}
+	return p
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfile_NewProfileID_SlugsAndAvoidsCollisions(t *testing.T) {
+	config := NewDefaultConfig()
+
+	if id := config.NewProfileID("My Game: Remastered!"); id != "my-game-remastered" {
+		t.Errorf("Expected 'my-game-remastered', got %q", id)
+	}
+	if id := config.NewProfileID("!!!"); id != "game" {
+		t.Errorf("Expected 'game' for a name without letters, got %q", id)
+	}
+
+	config.SetProfile(&GameProfile{ID: "my-game", Name: "My Game"})
+	config.SetProfile(&GameProfile{ID: "my-game-2", Name: "My Game"})
+	if id := config.NewProfileID("My Game"); id != "my-game-3" {
+		t.Errorf("Expected 'my-game-3', got %q", id)
+	}
+}
+
+func TestProfile_ResolvedModsDir_DefaultsUnderPaks(t *testing.T) {
+	p := &GameProfile{PaksDir: filepath.Join("Game", "Content", "Paks")}
+	if got := p.ResolvedModsDir(); got != filepath.Join("Game", "Content", "Paks", "~mods") {
+		t.Errorf("Unexpected default mods dir %q", got)
+	}
+	p.ModsDir = "custom"
+	if got := p.ResolvedModsDir(); got != "custom" {
+		t.Errorf("Expected ModsDir to win, got %q", got)
+	}
+}
+
+func TestProfile_SaveAndLoadConfig_RoundTrips(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := NewDefaultConfig()
+	config.SetKeyVault("MyGame", &KeyVault{Salt: "c2FsdA==", Nonce: "bm9uY2U=", Data: "ZGF0YQ=="})
+	config.SetNamingScheme("MyGame", NamingScheme{Preset: "zzz"})
+	config.SetProfile(&GameProfile{ID: "Other", Name: "Other", PaksDir: "C:/Games/Other/Content/Paks"})
+
+	if err := SaveConfig(configPath, config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	reloaded, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	profiles := reloaded.ListProfiles()
+	if len(profiles) != 2 || profiles[0].ID != "MyGame" {
+		t.Fatalf("Unexpected profiles after reload: %+v", profiles)
+	}
+	if myGame := profiles[0]; myGame.KeyVault == nil || myGame.Naming.Preset != "zzz" {
+		t.Errorf("Expected MyGame profile with vault and naming, got %+v", myGame)
+	}
+	if other := profiles[1]; other.PaksDir != "C:/Games/Other/Content/Paks" {
+		t.Errorf("Unexpected Other profile: %+v", other)
+	}
+}
@@ -0,0 +1,214 @@
+// Package discovery finds games installed through Steam and the Epic Games
+// Launcher, tells which of them are Unreal Engine titles and creates game
+// profiles for them with their paths filled in.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+)
+
+// Store identifiers used in [InstalledGame.Store].
+const (
+	StoreSteam = "steam"
+	StoreEpic  = "epic"
+)
+
+// OperationOwner is the owner of the library scans DiscoveryService
+// registers with the App.
+const OperationOwner = "discovery"
+
+// Preference keys that override where the stores are looked for.
+const (
+	PrefSteamPath         = "steam_path"
+	PrefEpicManifestsPath = "epic_manifests_path"
+)
+
+// InstalledGame is a game found in a store library. PaksDir is set for
+// Unreal Engine games only. ProfileID names an existing game profile with
+// the same install directory, if any.
+type InstalledGame struct {
+	Store      string `json:"store"`  // StoreSteam or StoreEpic
+	AppID      string `json:"app_id"` // Steam app ID or Epic app name
+	Name       string `json:"name"`
+	InstallDir string `json:"install_dir"`
+	Unreal     bool   `json:"unreal"`
+	PaksDir    string `json:"paks_dir"`
+	ProfileID  string `json:"profile_id"`
+}
+
+// DiscoveryService scans the Steam and Epic Games Launcher libraries for
+// installed games. Only the stores' own manifest files are read; nothing
+// is launched and no registry access is needed.
+type DiscoveryService struct {
+	app *app.App
+
+	// steamRoots and epicManifestDir are where the stores are looked for
+	// unless overridden by a preference.
+	steamRoots      []string
+	epicManifestDir string
+}
+
+// NewDiscoveryService creates a DiscoveryService that looks for the stores
+// in their default install locations.
+func NewDiscoveryService(a *app.App) *DiscoveryService {
+	return &DiscoveryService{
+		app:             a,
+		steamRoots:      defaultSteamRoots(),
+		epicManifestDir: defaultEpicManifestDir(),
+	}
+}
+
+// ScanLibraries returns the games installed in the Steam and Epic
+// libraries, sorted by name. A store that is not installed is skipped;
+// a library that cannot be read is logged and skipped.
+func (d *DiscoveryService) ScanLibraries(ctx context.Context) ([]InstalledGame, error) {
+	op := d.app.StartOperation(ctx, OperationOwner, "scan", "Steam and Epic libraries")
+	games, err := d.scanLibraries(op.Context())
+	op.Finish(err)
+	return games, err
+}
+
+func (d *DiscoveryService) scanLibraries(ctx context.Context) ([]InstalledGame, error) {
+	steamRoots := d.steamRoots
+	if path := d.app.GetPreference(PrefSteamPath); path != "" {
+		steamRoots = []string{path}
+	}
+	epicDir := d.epicManifestDir
+	if path := d.app.GetPreference(PrefEpicManifestsPath); path != "" {
+		epicDir = path
+	}
+
+	var games []InstalledGame
+	seen := make(map[string]bool)
+	add := func(found []InstalledGame) {
+		for _, g := range found {
+			key := g.Store + "/" + g.AppID
+			if seen[key] {
+				continue // The same library reached through two roots
+			}
+			seen[key] = true
+			games = append(games, g)
+		}
+	}
+
+	for _, root := range steamRoots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !isDir(root) {
+			continue
+		}
+		found, err := scanSteam(root)
+		if err != nil {
+			log.Printf("Skipping Steam library at %s: %v", root, err)
+			continue
+		}
+		add(found)
+	}
+	if epicDir != "" && isDir(epicDir) {
+		found, err := scanEpic(epicDir)
+		if err != nil {
+			log.Printf("Skipping Epic manifests at %s: %v", epicDir, err)
+		}
+		add(found)
+	}
+
+	profiles := d.app.ListProfiles()
+	for i := range games {
+		games[i].PaksDir = findPaksDir(games[i].InstallDir)
+		games[i].Unreal = games[i].PaksDir != ""
+		for _, p := range profiles {
+			if samePath(p.InstallDir, games[i].InstallDir) {
+				games[i].ProfileID = p.ID
+				break
+			}
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return strings.ToLower(games[i].Name) < strings.ToLower(games[j].Name)
+	})
+	if games == nil {
+		games = []InstalledGame{}
+	}
+	return games, nil
+}
+
+// ProfileFor returns a game profile for game with its name, paths and,
+// if it can be detected with high confidence, engine version filled in.
+// The profile is not saved; see [DiscoveryService.CreateProfile].
+func (d *DiscoveryService) ProfileFor(ctx context.Context, game InstalledGame) (config.GameProfile, error) {
+	paksDir := findPaksDir(game.InstallDir)
+	if paksDir == "" {
+		return config.GameProfile{}, fmt.Errorf("%s is not an Unreal Engine game: no Content/Paks directory with .utoc or .pak files in %s", game.Name, game.InstallDir)
+	}
+	profile := config.GameProfile{
+		Name:       game.Name,
+		InstallDir: game.InstallDir,
+		PaksDir:    paksDir,
+	}
+	if detected, err := retoc.DetectEngineVersion(game.InstallDir); err == nil {
+		profile.UEVersion, _ = detected.Best()
+	}
+	return profile, nil
+}
+
+// CreateProfile creates and saves a game profile for game as filled in by
+// [DiscoveryService.ProfileFor]. It fails if a profile for the game's
+// install directory already exists.
+func (d *DiscoveryService) CreateProfile(ctx context.Context, game InstalledGame) (config.GameProfile, error) {
+	for _, p := range d.app.ListProfiles() {
+		if samePath(p.InstallDir, game.InstallDir) {
+			return config.GameProfile{}, fmt.Errorf("game profile '%s' already uses %s", p.ID, game.InstallDir)
+		}
+	}
+	profile, err := d.ProfileFor(ctx, game)
+	if err != nil {
+		return config.GameProfile{}, err
+	}
+	return d.app.SaveProfile(profile)
+}
+
+// findPaksDir returns the <Project>/Content/Paks directory under
+// installDir that holds .utoc or .pak files, or "" if there is none.
+func findPaksDir(installDir string) string {
+	if installDir == "" {
+		return ""
+	}
+	candidates, _ := filepath.Glob(filepath.Join(installDir, "*", "Content", "Paks"))
+	candidates = append(candidates, filepath.Join(installDir, "Content", "Paks"))
+	for _, dir := range candidates {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if !e.IsDir() && (ext == ".utoc" || ext == ".pak") {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
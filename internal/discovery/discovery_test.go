@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return data
+}
+
+// newConfiguredApp returns an App whose configuration lives in a temp
+// directory.
+func newConfiguredApp(t *testing.T) *app.App {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("AppData", configDir)
+	a := app.NewApp()
+	if err := a.LoadConfiguration(); err != nil {
+		t.Fatalf("LoadConfiguration failed: %v", err)
+	}
+	return a
+}
+
+// vdfString quotes s for a VDF file.
+func vdfString(s string) string {
+	return `"` + strings.ReplaceAll(s, `\`, `\\`) + `"`
+}
+
+func TestSteam_LibraryFolders_ParsesCurrentAndLegacyFormats(t *testing.T) {
+	paths, err := parseLibraryFolders(readFixture(t, "libraryfolders.vdf"))
+	if err != nil {
+		t.Fatalf("parseLibraryFolders failed: %v", err)
+	}
+	if want := []string{`C:\Program Files (x86)\Steam`, `D:\SteamLibrary`}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+
+	paths, err = parseLibraryFolders(readFixture(t, "libraryfolders_legacy.vdf"))
+	if err != nil {
+		t.Fatalf("parseLibraryFolders failed for legacy file: %v", err)
+	}
+	if want := []string{`D:\SteamLibrary`, `E:\Games\Steam`}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+}
+
+func TestSteam_AppManifest_ParsesNameAndInstallDir(t *testing.T) {
+	app, err := parseAppManifest(readFixture(t, "appmanifest_1234560.acf"))
+	if err != nil {
+		t.Fatalf("parseAppManifest failed: %v", err)
+	}
+	want := steamApp{AppID: "1234560", Name: `Awesome Game: "Remastered"`, InstallDir: "AwesomeGame"}
+	if app != want {
+		t.Errorf("Expected %+v, got %+v", want, app)
+	}
+}
+
+func TestVDF_Malformed_ReturnsError(t *testing.T) {
+	for _, doc := range []string{
+		`"AppState" { "appid" "1"`,
+		`"AppState" { "appid" }`,
+		`"name" "unterminated`,
+		`}`,
+	} {
+		if _, err := parseVDF([]byte(doc)); err == nil {
+			t.Errorf("Expected %q to be rejected", doc)
+		}
+	}
+}
+
+func TestEpic_Manifest_ParsesInstallLocation(t *testing.T) {
+	m, err := parseEpicManifest(readFixture(t, "AB12CD34EF56.item"))
+	if err != nil {
+		t.Fatalf("parseEpicManifest failed: %v", err)
+	}
+	if m.DisplayName != "Awesome Game" || m.AppName != "Falcon" || m.InstallLocation != `C:\Program Files\Epic Games\AwesomeGame` || m.IsIncompleteInstall {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+}
+
+func TestDiscovery_ScanLibraries_FindsUnrealGamesAndCreatesProfile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A Steam install with a second library holding an Unreal game, and a
+	// non-Unreal game in the default library.
+	steamRoot := filepath.Join(tempDir, "Steam")
+	library := filepath.Join(tempDir, "SteamLibrary")
+	os.MkdirAll(filepath.Join(steamRoot, "steamapps", "common", "Solitaire"), 0755)
+	os.MkdirAll(filepath.Join(library, "steamapps"), 0755)
+	folders := `"libraryfolders" { "0" { "path" ` + vdfString(steamRoot) + ` } "1" { "path" ` + vdfString(library) + ` } }`
+	os.WriteFile(filepath.Join(steamRoot, "steamapps", "libraryfolders.vdf"), []byte(folders), 0644)
+	os.WriteFile(filepath.Join(steamRoot, "steamapps", "appmanifest_42.acf"), []byte(`"AppState" { "appid" "42" "name" "Solitaire" "installdir" "Solitaire" }`), 0644)
+	os.WriteFile(filepath.Join(library, "steamapps", "appmanifest_1234560.acf"), readFixture(t, "appmanifest_1234560.acf"), 0644)
+
+	gameDir := filepath.Join(library, "steamapps", "common", "AwesomeGame")
+	paks := filepath.Join(gameDir, "AwesomeGame", "Content", "Paks")
+	files := []iostoretest.File{{Path: "AwesomeGame/Content/Data/DT_Items.uasset", Data: []byte("items")}}
+	if _, err := iostoretest.WriteContainer(paks, "pakchunk0-Windows", files, iostoretest.Options{Version: iostore.TocVersionPartitionSize}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	// An Epic manifest for a game that is not installed is skipped.
+	epicDir := filepath.Join(tempDir, "Manifests")
+	os.MkdirAll(epicDir, 0755)
+	item, _ := json.Marshal(epicManifest{DisplayName: "Gone", AppName: "Gone", InstallLocation: filepath.Join(tempDir, "Gone")})
+	os.WriteFile(filepath.Join(epicDir, "Gone.item"), item, 0644)
+
+	service := NewDiscoveryService(newConfiguredApp(t))
+	service.steamRoots = []string{steamRoot, steamRoot}
+	service.epicManifestDir = epicDir
+
+	games, err := service.ScanLibraries(context.Background())
+	if err != nil {
+		t.Fatalf("ScanLibraries failed: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games, got %+v", games)
+	}
+	game := games[0]
+	if game.Name != `Awesome Game: "Remastered"` || !game.Unreal || game.PaksDir != paks || game.Store != StoreSteam {
+		t.Errorf("Unexpected Unreal game: %+v", game)
+	}
+	if games[1].Unreal {
+		t.Errorf("Expected Solitaire not to be an Unreal game: %+v", games[1])
+	}
+
+	profile, err := service.CreateProfile(context.Background(), game)
+	if err != nil {
+		t.Fatalf("CreateProfile failed: %v", err)
+	}
+	if profile.ID == "" || profile.InstallDir != gameDir || profile.PaksDir != paks || profile.UEVersion != "UE4_27" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+	if _, err := service.CreateProfile(context.Background(), game); err == nil {
+		t.Error("Expected a second profile for the same game to be refused")
+	}
+	if games, _ := service.ScanLibraries(context.Background()); games[0].ProfileID != profile.ID {
+		t.Errorf("Expected the game to link to its profile, got %+v", games[0])
+	}
+	if _, err := service.CreateProfile(context.Background(), games[1]); err == nil {
+		t.Error("Expected a profile for a non-Unreal game to be refused")
+	}
+}
@@ -0,0 +1,76 @@
+package discovery
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// epicManifest is the part of an Epic Games Launcher .item manifest
+// discovery uses. The launcher writes one per installed app.
+type epicManifest struct {
+	DisplayName         string `json:"DisplayName"`
+	AppName             string `json:"AppName"`
+	MainGameAppName     string `json:"MainGameAppName"` // Differs from AppName for DLC
+	InstallLocation     string `json:"InstallLocation"`
+	IsIncompleteInstall bool   `json:"bIsIncompleteInstall"`
+}
+
+// defaultEpicManifestDir returns the directory the Epic Games Launcher
+// keeps its .item manifests in, or "" on systems it does not run on.
+func defaultEpicManifestDir() string {
+	if runtime.GOOS != "windows" {
+		return ""
+	}
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "Epic", "EpicGamesLauncher", "Data", "Manifests")
+}
+
+// parseEpicManifest parses an Epic Games Launcher .item manifest.
+func parseEpicManifest(data []byte) (epicManifest, error) {
+	var m epicManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return epicManifest{}, err
+	}
+	return m, nil
+}
+
+// scanEpic returns the games described by the .item manifests in dir.
+// DLC, which shares its game's install directory, and incomplete installs
+// are left out.
+func scanEpic(dir string) ([]InstalledGame, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.item"))
+	if err != nil {
+		return nil, err
+	}
+
+	var games []InstalledGame
+	for _, path := range manifests {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		m, err := parseEpicManifest(data)
+		if err != nil || m.IsIncompleteInstall || m.InstallLocation == "" {
+			continue
+		}
+		if m.MainGameAppName != "" && m.MainGameAppName != m.AppName {
+			continue
+		}
+		installDir := filepath.Clean(m.InstallLocation)
+		if !isDir(installDir) {
+			continue
+		}
+		games = append(games, InstalledGame{
+			Store:      StoreEpic,
+			AppID:      m.AppName,
+			Name:       m.DisplayName,
+			InstallDir: installDir,
+		})
+	}
+	return games, nil
+}
@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+)
+
+// steamApp is the part of an appmanifest_<appid>.acf file discovery uses.
+type steamApp struct {
+	AppID      string
+	Name       string
+	InstallDir string // Directory name under steamapps/common
+}
+
+// defaultSteamRoots returns the usual Steam install directories.
+func defaultSteamRoots() []string {
+	if runtime.GOOS == "windows" {
+		var roots []string
+		for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+			if dir := os.Getenv(env); dir != "" {
+				roots = append(roots, filepath.Join(dir, "Steam"))
+			}
+		}
+		return roots
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+	}
+}
+
+// parseLibraryFolders returns the library paths listed in a
+// libraryfolders.vdf file. Current files hold a block with a "path" per
+// library; older ones map the library number straight to its path.
+func parseLibraryFolders(data []byte) ([]string, error) {
+	root, err := parseVDF(data)
+	if err != nil {
+		return nil, err
+	}
+	folders := root.get("libraryfolders")
+	if folders == nil {
+		return nil, fmt.Errorf("no libraryfolders block")
+	}
+
+	var paths []string
+	for _, key := range folders.keys {
+		if _, err := strconv.Atoi(key); err != nil {
+			continue // e.g. "contentstatsid"
+		}
+		entry := folders.get(key)
+		path := entry.value
+		if entry.children != nil {
+			path = entry.str("path")
+		}
+		if path != "" {
+			paths = append(paths, filepath.Clean(path))
+		}
+	}
+	return paths, nil
+}
+
+// parseAppManifest parses an appmanifest_<appid>.acf file.
+func parseAppManifest(data []byte) (steamApp, error) {
+	root, err := parseVDF(data)
+	if err != nil {
+		return steamApp{}, err
+	}
+	state := root.get("AppState")
+	if state == nil {
+		return steamApp{}, fmt.Errorf("no AppState block")
+	}
+	app := steamApp{
+		AppID:      state.str("appid"),
+		Name:       state.str("name"),
+		InstallDir: state.str("installdir"),
+	}
+	if app.AppID == "" || app.InstallDir == "" {
+		return steamApp{}, fmt.Errorf("app manifest is missing appid or installdir")
+	}
+	return app, nil
+}
+
+// scanSteam returns the games installed in the libraries of the Steam
+// install at root. The root is itself a library even if
+// libraryfolders.vdf does not list it.
+func scanSteam(root string) ([]InstalledGame, error) {
+	libraries := []string{filepath.Clean(root)}
+	data, err := os.ReadFile(filepath.Join(root, "steamapps", "libraryfolders.vdf"))
+	switch {
+	case err == nil:
+		listed, err := parseLibraryFolders(data)
+		if err != nil {
+			return nil, fmt.Errorf("libraryfolders.vdf: %w", err)
+		}
+		libraries = appendUnique(libraries, listed...)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	var games []InstalledGame
+	for _, library := range libraries {
+		steamapps := filepath.Join(library, "steamapps")
+		manifests, _ := filepath.Glob(filepath.Join(steamapps, "appmanifest_*.acf"))
+		for _, manifest := range manifests {
+			data, err := os.ReadFile(manifest)
+			if err != nil {
+				continue
+			}
+			app, err := parseAppManifest(data)
+			if err != nil {
+				continue
+			}
+			installDir := filepath.Join(steamapps, "common", app.InstallDir)
+			if !isDir(installDir) {
+				continue
+			}
+			games = append(games, InstalledGame{
+				Store:      StoreSteam,
+				AppID:      app.AppID,
+				Name:       app.Name,
+				InstallDir: installDir,
+			})
+		}
+	}
+	return games, nil
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
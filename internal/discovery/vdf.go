@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// vdfNode is a node of a Valve KeyValues ("VDF") text document, the format
+// of Steam's libraryfolders.vdf and appmanifest_*.acf files. A node is
+// either a string value or a block of child nodes. Keys are matched
+// case-insensitively, as Steam does, and keep their file order.
+type vdfNode struct {
+	value    string
+	keys     []string // Child keys as written, in file order
+	children map[string]*vdfNode
+}
+
+// get returns the child reached by following path, or nil.
+func (n *vdfNode) get(path ...string) *vdfNode {
+	for _, key := range path {
+		if n == nil {
+			return nil
+		}
+		n = n.children[strings.ToLower(key)]
+	}
+	return n
+}
+
+// str returns the string value of the child key, or "".
+func (n *vdfNode) str(key string) string {
+	if child := n.get(key); child != nil {
+		return child.value
+	}
+	return ""
+}
+
+func (n *vdfNode) set(key string, child *vdfNode) {
+	if n.children == nil {
+		n.children = make(map[string]*vdfNode)
+	}
+	lower := strings.ToLower(key)
+	if _, ok := n.children[lower]; !ok {
+		n.keys = append(n.keys, key)
+	}
+	n.children[lower] = child
+}
+
+// parseVDF parses a KeyValues text document and returns its root block.
+// Conditionals such as [$WIN32] are ignored.
+func parseVDF(data []byte) (*vdfNode, error) {
+	p := &vdfParser{data: string(data)}
+	root, err := p.block(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line(), err)
+	}
+	return root, nil
+}
+
+type vdfParser struct {
+	data string
+	pos  int
+}
+
+// block parses key/value pairs up to the closing brace, or to the end of
+// the input for the root block.
+func (p *vdfParser) block(nested bool) (*vdfNode, error) {
+	node := &vdfNode{}
+	for {
+		tok, quoted, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok == "" && !quoted:
+			if nested {
+				return nil, errors.New("unexpected end of file, missing '}'")
+			}
+			return node, nil
+		case tok == "}" && !quoted:
+			if !nested {
+				return nil, errors.New("unexpected '}'")
+			}
+			return node, nil
+		case tok == "{" && !quoted:
+			return nil, errors.New("unexpected '{', expected a key")
+		}
+
+		key := tok
+		value, quoted, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case value == "{" && !quoted:
+			child, err := p.block(true)
+			if err != nil {
+				return nil, err
+			}
+			node.set(key, child)
+		case (value == "" || value == "}") && !quoted:
+			return nil, fmt.Errorf("missing value for key %q", key)
+		default:
+			node.set(key, &vdfNode{value: value})
+		}
+	}
+}
+
+// token returns the next string, brace or "" at the end of the input,
+// skipping whitespace, comments and conditionals. quoted tells a quoted
+// "{" or "" apart from the real thing.
+func (p *vdfParser) token() (tok string, quoted bool, err error) {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		case strings.HasPrefix(p.data[p.pos:], "//"):
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '[':
+			end := strings.IndexByte(p.data[p.pos:], ']')
+			if end < 0 {
+				return "", false, errors.New("unterminated conditional")
+			}
+			p.pos += end + 1
+		case c == '{' || c == '}':
+			p.pos++
+			return string(c), false, nil
+		case c == '"':
+			return p.quoted()
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && !strings.ContainsRune(" \t\r\n{}\"", rune(p.data[p.pos])) {
+				p.pos++
+			}
+			return p.data[start:p.pos], false, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (p *vdfParser) quoted() (string, bool, error) {
+	var b strings.Builder
+	p.pos++ // Opening quote
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), true, nil
+		case '\\':
+			if p.pos >= len(p.data) {
+				break
+			}
+			esc := p.data[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false, errors.New("unterminated string")
+}
+
+func (p *vdfParser) line() int {
+	return strings.Count(p.data[:min(p.pos, len(p.data))], "\n") + 1
+}
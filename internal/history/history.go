@@ -0,0 +1,253 @@
+// Package history keeps a permanent record of every retoc operation and
+// UAsset export or import: what ran, with which options and engine version,
+// how long it took, whether it succeeded, and hashes of the files it
+// packed. Records are appended to a log in the data directory and never
+// rewritten. Any recorded operation can be run again exactly as it ran.
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+)
+
+// EventRecorded is emitted with a [Record] once it has been appended.
+const EventRecorded = "history:recorded"
+
+// Record is one finished operation. Tool is [retoc.OperationOwner] or
+// [uasset.OperationOwner], and exactly one of Retoc and UAsset holds the
+// operation as it ran, with all its options.
+type Record struct {
+	ID         string       `json:"id"` // The operation ID
+	Tool       string       `json:"tool"`
+	Command    string       `json:"command"`
+	ProfileID  string       `json:"profile_id"`
+	InputPath  string       `json:"input_path"`
+	OutputPath string       `json:"output_path"`
+	UEVersion  string       `json:"ue_version"`
+	StartedAt  time.Time    `json:"started_at"`
+	Duration   string       `json:"duration"`
+	Success    bool         `json:"success"`
+	Error      string       `json:"error,omitempty"`
+	Outputs    []OutputFile `json:"outputs,omitempty"` // Packed files of a "to-zen"
+
+	Retoc  *retoc.RetocOperation `json:"retoc,omitempty"`
+	UAsset *uasset.UAssetRequest `json:"uasset,omitempty"`
+}
+
+// OutputFile is a file an operation wrote, as it was when it finished.
+type OutputFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Query selects records. Zero fields match everything.
+type Query struct {
+	From      time.Time `json:"from,omitzero"` // Started at or after
+	To        time.Time `json:"to,omitzero"`   // Started before
+	ProfileID string    `json:"profile_id"`
+	Tool      string    `json:"tool"`
+	Command   string    `json:"command"`
+	Limit     int       `json:"limit"` // At most this many, newest first
+}
+
+func (q Query) matches(r Record) bool {
+	return (q.From.IsZero() || !r.StartedAt.Before(q.From)) &&
+		(q.To.IsZero() || r.StartedAt.Before(q.To)) &&
+		(q.ProfileID == "" || r.ProfileID == q.ProfileID) &&
+		(q.Tool == "" || r.Tool == q.Tool) &&
+		(q.Command == "" || r.Command == q.Command)
+}
+
+// RerunResult is the result of running a recorded operation again; the
+// field of the record's tool is set.
+type RerunResult struct {
+	Retoc  *retoc.RetocResult   `json:"retoc,omitempty"`
+	UAsset *uasset.UAssetResult `json:"uasset,omitempty"`
+}
+
+// HistoryService records operations as the services finish them and
+// answers queries about them. It subscribes to the services' events when
+// created, so operations run headless are recorded too. Dry runs are not
+// recorded.
+//
+// HistoryService is safe for concurrent use by multiple goroutines.
+type HistoryService struct {
+	app    *app.App
+	retoc  *retoc.RetocService
+	uasset *uasset.UAssetService
+
+	mu     sync.Mutex // Serializes appends
+	unsubs []func()
+}
+
+// NewHistoryService creates a HistoryService that records the operations
+// of r and u, and runs recorded ones again with them.
+func NewHistoryService(a *app.App, r *retoc.RetocService, u *uasset.UAssetService) *HistoryService {
+	h := &HistoryService{app: a, retoc: r, uasset: u}
+	h.unsubs = []func(){
+		a.Subscribe(retoc.EventFinished, func(data any) {
+			if finished, ok := data.(retoc.RetocFinished); ok && !finished.Operation.DryRun {
+				h.record(retocRecord(finished))
+			}
+		}),
+		a.Subscribe(uasset.EventFinished, func(data any) {
+			if finished, ok := data.(uasset.UAssetFinished); ok && !finished.Request.DryRun {
+				h.record(h.uassetRecord(finished))
+			}
+		}),
+	}
+	return h
+}
+
+// ServiceShutdown stops recording.
+func (h *HistoryService) ServiceShutdown() error {
+	for _, unsub := range h.unsubs {
+		unsub()
+	}
+	return nil
+}
+
+// Query returns the records matching q, newest first.
+func (h *HistoryService) Query(ctx context.Context, q Query) ([]Record, error) {
+	records, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	matched := []Record{}
+	for i := len(records) - 1; i >= 0; i-- {
+		if q.matches(records[i]) {
+			matched = append(matched, records[i])
+			if q.Limit > 0 && len(matched) == q.Limit {
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// Get returns the record of the operation with the given ID.
+func (h *HistoryService) Get(ctx context.Context, id string) (Record, error) {
+	records, err := h.read()
+	if err != nil {
+		return Record{}, err
+	}
+	i := slices.IndexFunc(records, func(r Record) bool { return r.ID == id })
+	if i < 0 {
+		return Record{}, fmt.Errorf("no history record for operation %s", id)
+	}
+	return records[i], nil
+}
+
+// Rerun runs the recorded operation with the given ID again, with the same
+// options, engine version, paths and mappings. A pack is forced, so it runs
+// retoc even if its input has not changed since. It is recorded as a new
+// operation.
+func (h *HistoryService) Rerun(ctx context.Context, id string) (RerunResult, error) {
+	record, err := h.Get(ctx, id)
+	if err != nil {
+		return RerunResult{}, err
+	}
+	switch {
+	case record.Retoc != nil:
+		operation := *record.Retoc
+		operation.Force = true
+		result := h.retoc.RunRetoc(ctx, operation)
+		return RerunResult{Retoc: &result}, nil
+	case record.UAsset != nil:
+		result := h.uasset.RunUAsset(ctx, *record.UAsset)
+		return RerunResult{UAsset: &result}, nil
+	}
+	return RerunResult{}, fmt.Errorf("operation %s cannot be run again", id)
+}
+
+func retocRecord(f retoc.RetocFinished) Record {
+	op := f.Operation
+	version := op.UEVersion
+	if op.Pack != nil && op.Pack.EngineVersion != "" {
+		version = op.Pack.EngineVersion
+	}
+	record := Record{
+		ID:         f.Result.OperationID,
+		Tool:       retoc.OperationOwner,
+		Command:    op.Command,
+		ProfileID:  op.ProfileID,
+		InputPath:  op.InputPath,
+		OutputPath: op.OutputPath,
+		UEVersion:  version,
+		StartedAt:  f.StartedAt,
+		Duration:   f.Result.Duration,
+		Success:    f.Result.Success,
+		Error:      f.Result.Error,
+		Retoc:      &op,
+	}
+	for _, path := range f.OutputFiles {
+		file, err := hashOutput(path)
+		if err != nil {
+			log.Printf("Failed to hash %s for history: %v", path, err)
+			continue
+		}
+		record.Outputs = append(record.Outputs, file)
+	}
+	return record
+}
+
+func (h *HistoryService) uassetRecord(f uasset.UAssetFinished) Record {
+	req := f.Request
+	record := Record{
+		ID:         f.Result.OperationID,
+		Tool:       uasset.OperationOwner,
+		Command:    req.Command,
+		ProfileID:  req.ProfileID,
+		InputPath:  req.FolderPath,
+		OutputPath: req.FolderPath, // The bridge converts in place
+		StartedAt:  f.StartedAt,
+		Duration:   f.Result.Duration,
+		Success:    f.Result.Success,
+		Error:      f.Result.Error,
+		UAsset:     &req,
+	}
+	if req.ProfileID != "" {
+		if profile, err := h.app.GetProfile(req.ProfileID); err == nil {
+			record.UEVersion = profile.UEVersion
+		}
+	}
+	return record
+}
+
+// hashOutput returns the size and SHA-256 of the file at path.
+func hashOutput(path string) (OutputFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return OutputFile{}, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return OutputFile{}, err
+	}
+	return OutputFile{Path: path, Size: n, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// record appends r to the log and emits [EventRecorded].
+func (h *HistoryService) record(r Record) {
+	if err := h.append(r); err != nil {
+		log.Printf("Failed to record operation %s in history: %v", r.ID, err)
+		return
+	}
+	h.app.Emit(EventRecorded, r)
+}
@@ -0,0 +1,251 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+)
+
+// newTestHistory returns a HistoryService whose data directory is
+// temporary, with services whose tools are missing so that every
+// operation fails quickly.
+func newTestHistory(t *testing.T) (*HistoryService, *app.App) {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	a := app.NewApp()
+	if err := a.LoadConfiguration(); err != nil {
+		t.Fatalf("LoadConfiguration failed: %v", err)
+	}
+	depsDir := filepath.Join(dataDir, "deps")
+	h := NewHistoryService(a, retoc.NewRetocService(a, depsDir), uasset.NewUAssetService(a, depsDir))
+	t.Cleanup(func() { h.ServiceShutdown() })
+	return h, a
+}
+
+func TestHistory_RetocOperation_RecordedAndRerun(t *testing.T) {
+	h, _ := newTestHistory(t)
+	ctx := context.Background()
+	input := t.TempDir()
+
+	operation := retoc.RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: t.TempDir(),
+		Pack:       &retoc.PackOptions{ModName: "MyMod", EngineVersion: "UE5_3", Compression: "Zlib"},
+	}
+	result := h.retoc.RunRetoc(ctx, operation)
+
+	record, err := h.Get(ctx, result.OperationID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if record.Tool != retoc.OperationOwner || record.Command != "to-zen" || record.InputPath != input {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if record.UEVersion != "UE5_3" {
+		t.Errorf("Expected the pack engine version, got %q", record.UEVersion)
+	}
+	if record.Success || record.Error == "" || record.Duration == "" {
+		t.Errorf("Expected a failed record with an error and duration, got %+v", record)
+	}
+	if record.Retoc == nil || record.Retoc.Pack == nil || record.Retoc.Pack.Compression != "Zlib" {
+		t.Errorf("Expected the pack options to be recorded, got %+v", record.Retoc)
+	}
+
+	rerun, err := h.Rerun(ctx, result.OperationID)
+	if err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	if rerun.Retoc == nil || rerun.Retoc.OperationID == result.OperationID {
+		t.Fatalf("Expected a new retoc operation, got %+v", rerun)
+	}
+	again, err := h.Get(ctx, rerun.Retoc.OperationID)
+	if err != nil {
+		t.Fatalf("Rerun was not recorded: %v", err)
+	}
+	if again.Retoc.Pack.ModName != "MyMod" || again.UEVersion != "UE5_3" {
+		t.Errorf("Expected the rerun to repeat the operation, got %+v", again.Retoc)
+	}
+}
+
+func TestHistory_RerunUnchangedPack_RunsRetocAgain(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	a := app.NewApp()
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		utoc := cmd.Args[len(cmd.Args)-1]
+		for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+			os.WriteFile(strings.TrimSuffix(utoc, ".utoc")+ext, []byte("packed"), 0644)
+		}
+		return nil
+	})
+	depsDir := filepath.Join(dataDir, "deps")
+	h := NewHistoryService(a, retoc.NewRetocServiceWithRunner(a, depsDir, fake), uasset.NewUAssetService(a, depsDir))
+	t.Cleanup(func() { h.ServiceShutdown() })
+	ctx := context.Background()
+
+	input := filepath.Join(t.TempDir(), "MyMod")
+	os.MkdirAll(input, 0755)
+	os.WriteFile(filepath.Join(input, "A.uasset"), []byte("a"), 0644)
+	result := h.retoc.RunRetoc(ctx, retoc.RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: t.TempDir(),
+		Pack:       &retoc.PackOptions{EngineVersion: "UE5_3"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected the pack to succeed, got %+v", result)
+	}
+
+	rerun, err := h.Rerun(ctx, result.OperationID)
+	if err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	if !rerun.Retoc.Success || rerun.Retoc.Cached || len(fake.Calls()) != 2 {
+		t.Errorf("Expected the rerun to pack again, got %+v after %d retoc runs", rerun.Retoc, len(fake.Calls()))
+	}
+}
+
+func TestHistory_UAssetRequest_RecordsProfileMappings(t *testing.T) {
+	h, a := newTestHistory(t)
+	ctx := context.Background()
+	profile, err := a.SaveProfile(config.GameProfile{Name: "My Game", UEVersion: "UE5_4", MappingsPath: "C:/Mappings/Game.usmap"})
+	if err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	result := h.uasset.RunUAsset(ctx, uasset.UAssetRequest{Command: "export", FolderPath: t.TempDir(), ProfileID: profile.ID})
+
+	record, err := h.Get(ctx, result.OperationID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if record.UAsset == nil || record.UAsset.MappingsPath != "C:/Mappings/Game.usmap" {
+		t.Errorf("Expected the profile's mappings to be recorded, got %+v", record.UAsset)
+	}
+	if record.UEVersion != "UE5_4" || record.ProfileID != profile.ID {
+		t.Errorf("Expected the profile's engine version and ID, got %+v", record)
+	}
+}
+
+func TestHistory_DryRun_NotRecorded(t *testing.T) {
+	h, _ := newTestHistory(t)
+	ctx := context.Background()
+
+	result := h.uasset.RunUAsset(ctx, uasset.UAssetRequest{Command: "export", FolderPath: t.TempDir(), DryRun: true})
+	if _, err := h.Get(ctx, result.OperationID); err == nil {
+		t.Error("Expected the dry run not to be recorded")
+	}
+}
+
+func TestHistory_Outputs_Hashed(t *testing.T) {
+	h, a := newTestHistory(t)
+	path := filepath.Join(t.TempDir(), "z_MyMod_0001_p.utoc")
+	os.WriteFile(path, []byte("packed"), 0644)
+
+	a.Emit(retoc.EventFinished, retoc.RetocFinished{
+		Operation:   retoc.RetocOperation{Command: "to-zen"},
+		Result:      retoc.RetocResult{Success: true, OperationID: "op-1"},
+		OutputFiles: []string{path},
+	})
+
+	record, err := h.Get(context.Background(), "op-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	sum := sha256.Sum256([]byte("packed"))
+	want := OutputFile{Path: path, Size: 6, SHA256: hex.EncodeToString(sum[:])}
+	if len(record.Outputs) != 1 || record.Outputs[0] != want {
+		t.Errorf("Expected outputs [%+v], got %+v", want, record.Outputs)
+	}
+}
+
+func TestHistory_Query_FiltersNewestFirst(t *testing.T) {
+	h, a := newTestHistory(t)
+	day := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	emit := func(id, command, profile string, at time.Time) {
+		a.Emit(retoc.EventFinished, retoc.RetocFinished{
+			Operation: retoc.RetocOperation{Command: command, ProfileID: profile},
+			StartedAt: at,
+			Result:    retoc.RetocResult{Success: true, OperationID: id},
+		})
+	}
+	emit("1", "to-zen", "game-a", day.AddDate(0, 0, -2))
+	emit("2", "unpack", "game-a", day)
+	emit("3", "to-zen", "game-b", day.Add(time.Hour))
+	emit("4", "to-zen", "game-a", day.Add(2*time.Hour))
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all", Query{}, []string{"4", "3", "2", "1"}},
+		{"game", Query{ProfileID: "game-a"}, []string{"4", "2", "1"}},
+		{"command", Query{Command: "to-zen"}, []string{"4", "3", "1"}},
+		{"date", Query{From: day, To: day.Add(2 * time.Hour)}, []string{"3", "2"}},
+		{"limit", Query{Tool: retoc.OperationOwner, Limit: 2}, []string{"4", "3"}},
+	}
+	for _, tt := range tests {
+		records, err := h.Query(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: Query failed: %v", tt.name, err)
+		}
+		var got []string
+		for _, r := range records {
+			got = append(got, r.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestHistory_Log_AppendOnlyAndSkipsTornLines(t *testing.T) {
+	h, a := newTestHistory(t)
+	a.Emit(retoc.EventFinished, retoc.RetocFinished{Result: retoc.RetocResult{OperationID: "1"}})
+
+	// A crash mid-write leaves a partial last line
+	f, _ := os.OpenFile(h.historyPath(), os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"id":"torn","too`)
+	f.Close()
+	before, _ := os.ReadFile(h.historyPath())
+
+	a.Emit(retoc.EventFinished, retoc.RetocFinished{Result: retoc.RetocResult{OperationID: "2"}})
+
+	after, _ := os.ReadFile(h.historyPath())
+	if string(after[:len(before)]) != string(before) {
+		t.Error("Expected existing records to be left untouched")
+	}
+	records, err := h.Query(context.Background(), Query{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "2" || records[1].ID != "1" {
+		t.Errorf("Expected records 2 and 1 around the torn line, got %+v", records)
+	}
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// HistoryFileName is the log in the data directory records are appended
+// to, one JSON object per line.
+const HistoryFileName = "history.jsonl"
+
+func (h *HistoryService) historyPath() string {
+	return filepath.Join(h.app.DataDir(), HistoryFileName)
+}
+
+// append writes r as a new line at the end of the log. If the log does not
+// end with a newline, because a write was cut short, the record starts a
+// new line so that only the partial one is lost.
+func (h *HistoryService) append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(h.historyPath()), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.historyPath(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// read returns all records, oldest first. A missing log has no records;
+// lines that cannot be parsed, such as one cut short by a crash, are
+// logged and skipped.
+func (h *HistoryService) read() ([]Record, error) {
+	f, err := os.Open(h.historyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	reader := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var r Record
+			if jsonErr := json.Unmarshal(line, &r); jsonErr != nil {
+				log.Printf("Skipping unreadable line %d of %s: %v", n, HistoryFileName, jsonErr)
+			} else {
+				records = append(records, r)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
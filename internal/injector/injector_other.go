@@ -0,0 +1,22 @@
+//go:build !windows
+
+package injector
+
+import "context"
+
+// GetRunningProcesses returns [ErrUnsupported].
+func (i *InjectorService) GetRunningProcesses(ctx context.Context) ([]ProcessInfo, error) {
+	return nil, ErrUnsupported
+}
+
+func (i *InjectorService) injectDLL(ctx context.Context, targetPID uint32, dllPath string) error {
+	return ErrUnsupported
+}
+
+func (i *InjectorService) checkAdminPrivileges() (bool, error) {
+	return false, ErrUnsupported
+}
+
+func formatSystemError(err error) string {
+	return err.Error()
+}
@@ -0,0 +1,77 @@
+package iostore
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry describes one chunk of a container together with the file path the
+// directory index assigns to it, if any.
+type Entry struct {
+	TocEntryIndex  int       `json:"toc_entry_index"`
+	ChunkID        ChunkID   `json:"chunk_id"`
+	Path           string    `json:"path"`         // Mounted file path; empty for unindexed chunks
+	PackagePath    string    `json:"package_path"` // Long package name derived from Path
+	Size           uint64    `json:"size"`
+	CompressedSize uint64    `json:"compressed_size"`
+	Compression    []string  `json:"compression"`
+	Type           ChunkType `json:"type"`
+}
+
+// Entries returns every chunk in the container in TOC order. Paths are
+// only filled in when the directory index has been decoded.
+func (t *Toc) Entries() []Entry {
+	paths := make(map[uint32]string)
+	if t.DirectoryIndex != nil {
+		for _, f := range t.DirectoryIndex.Files {
+			paths[f.TocEntryIndex] = t.DirectoryIndex.FullPath(f.Path)
+		}
+	}
+
+	entries := make([]Entry, len(t.ChunkIDs))
+	for i, id := range t.ChunkIDs {
+		e := Entry{
+			TocEntryIndex:  i,
+			ChunkID:        id,
+			Type:           id.Type,
+			CompressedSize: t.ChunkCompressedSize(i),
+			Compression:    t.ChunkCompressionMethods(i),
+		}
+		if i < len(t.OffsetLengths) {
+			e.Size = t.OffsetLengths[i].Length
+		}
+		if p, ok := paths[uint32(i)]; ok {
+			e.Path = p
+			e.PackagePath = PackagePath(p)
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+// FindContainers returns the .utoc files under root, recursing into
+// subdirectories such as ~mods. If root is itself a .utoc file it is
+// returned alone. Results are sorted by path.
+func FindContainers(root string) ([]string, error) {
+	if strings.EqualFold(filepath.Ext(root), ".utoc") {
+		return []string{root}, nil
+	}
+
+	var found []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".utoc") {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(found)
+	return found, nil
+}
@@ -0,0 +1,59 @@
+package iostore
+
+import (
+	"crypto/aes"
+	"errors"
+	"fmt"
+)
+
+// ErrWrongKey is returned when an AES key does not decrypt a container's
+// directory index.
+var ErrWrongKey = errors.New("wrong AES key")
+
+// DecryptDirectoryIndex decrypts the container's directory index with the
+// AES-256 key and sets DirectoryIndex. Because a wrong key yields garbage
+// that fails to parse, this doubles as a check that key belongs to the
+// container; it returns [ErrWrongKey] if not. It is a no-op for containers
+// whose index is not encrypted.
+func (t *Toc) DecryptDirectoryIndex(key []byte) error {
+	if !t.IsEncrypted() || t.rawDirectoryIndex == nil {
+		return nil
+	}
+	plain, err := decryptECB(key, t.rawDirectoryIndex)
+	if err != nil {
+		return err
+	}
+	index, err := ParseDirectoryIndex(plain)
+	if err != nil {
+		return fmt.Errorf("%w for %s", ErrWrongKey, t.Path)
+	}
+	t.DirectoryIndex = index
+	return nil
+}
+
+// decryptECB decrypts data with AES-256 in ECB mode, the mode Unreal uses
+// for pak and IoStore encryption. len(data) must be a multiple of the AES
+// block size.
+func decryptECB(key, data []byte) ([]byte, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("AES key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted data length %d is not a multiple of %d", len(data), aes.BlockSize)
+	}
+	out := make([]byte, len(data))
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Decrypt(out[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+	}
+	return out, nil
+}
+
+// alignAES rounds n up to the AES block size, the on-disk size of an
+// encrypted compression block.
+func alignAES(n uint64) uint64 {
+	return (n + aes.BlockSize - 1) &^ (aes.BlockSize - 1)
+}
@@ -0,0 +1,224 @@
+package iostore
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// invalidIndex marks an absent link in the directory index tree.
+const invalidIndex = ^uint32(0)
+
+// maxIndexEntries guards against absurd counts in corrupt or still-encrypted
+// directory indices.
+const maxIndexEntries = 1 << 24
+
+// FileEntry maps a file path in the container to its TOC entry.
+type FileEntry struct {
+	Path          string `json:"path"`            // Relative to the mount point, "/"-separated
+	TocEntryIndex uint32 `json:"toc_entry_index"` // Index into Toc.ChunkIDs
+}
+
+// DirectoryIndex is the decoded FIoDirectoryIndexResource of a container.
+type DirectoryIndex struct {
+	MountPoint string      `json:"mount_point"`
+	Files      []FileEntry `json:"files"` // Sorted by Path
+}
+
+// ParseDirectoryIndex decodes a plaintext serialized directory index.
+func ParseDirectoryIndex(data []byte) (*DirectoryIndex, error) {
+	r := &reader{data: data}
+
+	mountPoint, err := readFString(r)
+	if err != nil {
+		return nil, fmt.Errorf("mount point: %w", err)
+	}
+
+	type dirEntry struct{ name, firstChild, nextSibling, firstFile uint32 }
+	type fileEntry struct{ name, nextFile, userData uint32 }
+
+	dirCount := r.i32()
+	if dirCount < 0 || dirCount > maxIndexEntries {
+		return nil, fmt.Errorf("invalid directory count %d", dirCount)
+	}
+	dirs := make([]dirEntry, dirCount)
+	for i := range dirs {
+		dirs[i] = dirEntry{r.u32(), r.u32(), r.u32(), r.u32()}
+	}
+
+	fileCount := r.i32()
+	if fileCount < 0 || fileCount > maxIndexEntries {
+		return nil, fmt.Errorf("invalid file count %d", fileCount)
+	}
+	files := make([]fileEntry, fileCount)
+	for i := range files {
+		files[i] = fileEntry{r.u32(), r.u32(), r.u32()}
+	}
+
+	stringCount := r.i32()
+	if stringCount < 0 || stringCount > maxIndexEntries {
+		return nil, fmt.Errorf("invalid string count %d", stringCount)
+	}
+	strs := make([]string, stringCount)
+	for i := range strs {
+		if strs[i], err = readFString(r); err != nil {
+			return nil, fmt.Errorf("string table: %w", err)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	name := func(i uint32) (string, error) {
+		if i == invalidIndex {
+			return "", nil
+		}
+		if int(i) >= len(strs) {
+			return "", fmt.Errorf("name index %d out of range", i)
+		}
+		return strs[i], nil
+	}
+
+	index := &DirectoryIndex{MountPoint: mountPoint}
+	if len(dirs) == 0 {
+		return index, nil
+	}
+
+	// Walk the tree iteratively; visited guards against cycles in corrupt
+	// data.
+	type frame struct {
+		dir    uint32
+		prefix string
+	}
+	visited := make(map[uint32]bool)
+	stack := []frame{{0, ""}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.dir == invalidIndex || int(f.dir) >= len(dirs) || visited[f.dir] {
+			continue
+		}
+		visited[f.dir] = true
+		d := dirs[f.dir]
+
+		for fi, steps := d.firstFile, 0; fi != invalidIndex; fi, steps = files[fi].nextFile, steps+1 {
+			if int(fi) >= len(files) || steps > len(files) {
+				return nil, fmt.Errorf("file index %d out of range", fi)
+			}
+			fileName, err := name(files[fi].name)
+			if err != nil {
+				return nil, err
+			}
+			index.Files = append(index.Files, FileEntry{
+				Path:          path.Join(f.prefix, fileName),
+				TocEntryIndex: files[fi].userData,
+			})
+		}
+
+		for ci, steps := d.firstChild, 0; ci != invalidIndex; steps++ {
+			if int(ci) >= len(dirs) || steps > len(dirs) {
+				return nil, fmt.Errorf("directory index %d out of range", ci)
+			}
+			dirName, err := name(dirs[ci].name)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, frame{ci, path.Join(f.prefix, dirName)})
+			ci = dirs[ci].nextSibling
+		}
+	}
+
+	sort.Slice(index.Files, func(i, j int) bool { return index.Files[i].Path < index.Files[j].Path })
+	return index, nil
+}
+
+// readFString decodes an FArchive FString: an int32 length followed by a
+// null-terminated ANSI string, or by UTF-16 code units when the length is
+// negative.
+func readFString(r *reader) (string, error) {
+	n := r.i32()
+	if r.err != nil {
+		return "", r.err
+	}
+	switch {
+	case n == 0:
+		return "", nil
+	case n > 0:
+		if n > maxIndexEntries {
+			return "", fmt.Errorf("invalid string length %d", n)
+		}
+		b := r.bytes(int(n))
+		if r.err != nil {
+			return "", r.err
+		}
+		return strings.TrimRight(string(b), "\x00"), nil
+	default:
+		if -n > maxIndexEntries {
+			return "", fmt.Errorf("invalid string length %d", n)
+		}
+		units := make([]uint16, -n)
+		for i := range units {
+			b := r.bytes(2)
+			if r.err != nil {
+				return "", r.err
+			}
+			units[i] = uint16(b[0]) | uint16(b[1])<<8
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00"), nil
+	}
+}
+
+// FullPath joins the mount point and a file path from the index into the
+// path the engine sees, e.g. "../../../Game/Content/Data/DT_Items.uasset".
+func (d *DirectoryIndex) FullPath(filePath string) string {
+	return path.Join(d.MountPoint, filePath)
+}
+
+// PackagePath converts a mounted file path into a long package name, e.g.
+// "../../../MyGame/Content/Data/DT_Items.uasset" becomes "/Game/Data/DT_Items"
+// and "../../../Engine/Plugins/Foo/Content/Bar.uasset" becomes "/Foo/Bar".
+// The first Content directory owned by the project, Engine or a plugin is
+// the root, so folders named Content inside it stay part of the name.
+// Paths outside such a directory are returned cleaned but otherwise
+// unchanged, with their extension removed. Package names cannot contain a
+// dot, so the whole of a multi-part extension such as ".m.ubulk" goes.
+func PackagePath(filePath string) string {
+	p := strings.TrimLeft(path.Clean("/"+strings.ReplaceAll(filePath, "\\", "/")), "/")
+	for strings.HasPrefix(p, "../") {
+		p = strings.TrimPrefix(p, "../")
+	}
+	if dot := strings.IndexByte(path.Base(p), '.'); dot >= 0 {
+		p = p[:len(p)-len(path.Base(p))+dot]
+	}
+
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts)-1; i++ {
+		if !strings.EqualFold(parts[i], "Content") {
+			continue
+		}
+		rest := strings.Join(parts[i+1:], "/")
+		owner := parts[i-1]
+		switch {
+		case containsFold(parts[:i-1], "Plugins"):
+			return "/" + owner + "/" + rest
+		case i > 1:
+			continue // Not the content root of the project or Engine
+		case strings.EqualFold(owner, "Engine"):
+			return "/Engine/" + rest
+		default:
+			return "/Game/" + rest
+		}
+	}
+	return "/" + p
+}
+
+func containsFold(parts []string, s string) bool {
+	for _, p := range parts {
+		if strings.EqualFold(p, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package iostore
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDirectoryIndex_PackagePath_MapsMountedPaths(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"../../../MyGame/Content/Data/DT_Items.uasset", "/Game/Data/DT_Items"},
+		{"../../../Engine/Content/EngineMaterials/Default.uasset", "/Engine/EngineMaterials/Default"},
+		{"../../../MyGame/Plugins/Weapons/Content/BP_Rifle.uexp", "/Weapons/BP_Rifle"},
+		{"../../../MyGame/Plugins/GameFeatures/Raid/Content/DA_Raid.uasset", "/Raid/DA_Raid"},
+		{"../../../MyGame/Content/Maps/Content/Foo.umap", "/Game/Maps/Content/Foo"},
+		{"../../../MyGame/Content/Maps/Arena.m.ubulk", "/Game/Maps/Arena"},
+		{"../../../MyGame/Plugins/Weapons/Content/Content/BP_Rifle.uasset", "/Weapons/Content/BP_Rifle"},
+		{"MyGame/Config/DefaultGame.ini", "/MyGame/Config/DefaultGame"},
+	}
+
+	for _, tt := range tests {
+		if got := PackagePath(tt.in); got != tt.expected {
+			t.Errorf("PackagePath(%q): expected '%s', got '%s'", tt.in, tt.expected, got)
+		}
+	}
+}
+
+func TestDirectoryIndex_ReadFString_DecodesUTF16(t *testing.T) {
+	// -3 code units: 'é', 'x', null terminator
+	data := make([]byte, 4+6)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(0xFFFFFFFD))
+	binary.LittleEndian.PutUint16(data[4:6], 0x00E9)
+	binary.LittleEndian.PutUint16(data[6:8], 'x')
+
+	s, err := readFString(&reader{data: data})
+	if err != nil {
+		t.Fatalf("readFString failed: %v", err)
+	}
+	if s != "éx" {
+		t.Errorf("Expected 'éx', got '%s'", s)
+	}
+}
+
+func TestDirectoryIndex_CorruptCounts_ReturnError(t *testing.T) {
+	// Empty mount point followed by a negative directory count.
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(0xFFFFFFFF))
+
+	if _, err := ParseDirectoryIndex(data); err == nil {
+		t.Error("Expected error for negative directory count")
+	}
+}
@@ -0,0 +1,318 @@
+// Package iostoretest builds small IoStore containers for tests. The output
+// follows the on-disk layout read by package iostore closely enough for
+// round-trip tests; it is not meant to produce containers a game will load.
+package iostoretest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"encoding/binary"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// File is one chunk to place in a container.
+type File struct {
+	Path      string            // Relative to the mount point, e.g. "MyGame/Content/DT_Items.uasset"
+	Data      []byte            // Uncompressed chunk contents
+	ChunkType iostore.ChunkType // Defaults to ExportBundleData
+	ChunkID   uint64            // Defaults to a value derived from the file's position
+}
+
+// Options controls the container layout.
+type Options struct {
+	Version    iostore.TocVersion     // Defaults to PerfectHashWithOverflow (UE 5.0-5.2)
+	MountPoint string                 // Defaults to "../../../"
+	Flags      iostore.ContainerFlags // Indexed is always added
+	BlockSize  uint32                 // Defaults to 64 KiB
+	// Compression is "" (uncompressed) or "Zlib". Any other name is
+	// written to the method table as-is with the blocks left uncompressed,
+	// which lets tests fake containers using methods such as Oodle.
+	Compression string
+	// AESKey, when set, encrypts the directory index and blocks with
+	// AES-256-ECB and marks the container Encrypted.
+	AESKey  []byte
+	KeyGUID [16]byte // Written as the container's encryption key GUID
+}
+
+const invalidIndex = ^uint32(0)
+
+// Build returns the .utoc and .ucas contents for files.
+func Build(files []File, opts Options) (utoc, ucas []byte) {
+	if opts.Version == 0 {
+		opts.Version = iostore.TocVersionPerfectHashWithOverflow
+	}
+	if opts.MountPoint == "" {
+		opts.MountPoint = "../../../"
+	}
+	if opts.BlockSize == 0 {
+		opts.BlockSize = 64 * 1024
+	}
+	opts.Flags |= iostore.ContainerFlagIndexed
+	var method uint8
+	if opts.Compression != "" {
+		method = 1
+		opts.Flags |= iostore.ContainerFlagCompressed
+	}
+	if opts.AESKey != nil {
+		opts.Flags |= iostore.ContainerFlagEncrypted
+	}
+
+	var chunkIDs, offsets, blocks bytes.Buffer
+	var data bytes.Buffer
+	blockCount := uint32(0)
+	for i, f := range files {
+		id := f.ChunkID
+		if id == 0 {
+			id = uint64(i + 1)
+		}
+		chunkType := f.ChunkType
+		if chunkType == iostore.ChunkTypeInvalid {
+			chunkType = iostore.ChunkTypeExportBundleData
+		}
+		var rawID [12]byte
+		binary.LittleEndian.PutUint64(rawID[0:8], id)
+		rawID[11] = rawChunkType(opts.Version, chunkType)
+		chunkIDs.Write(rawID[:])
+
+		offset := uint64(blockCount) * uint64(opts.BlockSize)
+		offsets.Write(put40BE(offset))
+		offsets.Write(put40BE(uint64(len(f.Data))))
+
+		bs := int(opts.BlockSize)
+		n := (len(f.Data) + bs - 1) / bs
+		if n == 0 {
+			n = 1
+		}
+		for b := 0; b < n; b++ {
+			end := min((b+1)*bs, len(f.Data))
+			block := f.Data[min(b*bs, end):end]
+			stored := block
+			if opts.Compression == "Zlib" {
+				stored = zlibCompress(block)
+			}
+			blocks.Write(packBlock(uint64(data.Len()), uint32(len(stored)), uint32(len(block)), method))
+			if opts.AESKey != nil {
+				stored = encryptECB(opts.AESKey, stored)
+			}
+			data.Write(stored)
+			blockCount++
+		}
+	}
+
+	index := buildDirectoryIndex(opts.MountPoint, files)
+	if opts.AESKey != nil {
+		index = encryptECB(opts.AESKey, index)
+	}
+
+	var toc bytes.Buffer
+	toc.WriteString(iostore.TocMagic)
+	toc.WriteByte(byte(opts.Version))
+	toc.Write(make([]byte, 3))
+	writeU32(&toc, 144)
+	writeU32(&toc, uint32(len(files)))
+	writeU32(&toc, blockCount)
+	writeU32(&toc, 12)
+	writeU32(&toc, uint32(method)) // compression method name count
+	writeU32(&toc, 32)
+	writeU32(&toc, opts.BlockSize)
+	writeU32(&toc, uint32(len(index)))
+	writeU32(&toc, 1)                     // partition count
+	writeU64(&toc, 0x1234_5678_9abc_def0) // container ID
+	toc.Write(opts.KeyGUID[:])
+	toc.WriteByte(byte(opts.Flags))
+	toc.Write(make([]byte, 3))
+	writeU32(&toc, 0)          // perfect hash seeds
+	writeU64(&toc, ^uint64(0)) // partition size
+	writeU32(&toc, 0)          // chunks without perfect hash
+	toc.Write(make([]byte, 4+40))
+
+	toc.Write(chunkIDs.Bytes())
+	toc.Write(offsets.Bytes())
+	toc.Write(blocks.Bytes())
+	if method != 0 {
+		name := make([]byte, 32)
+		copy(name, opts.Compression)
+		toc.Write(name)
+	}
+	toc.Write(index)
+
+	return toc.Bytes(), data.Bytes()
+}
+
+// WriteContainer writes name.utoc and name.ucas into dir and returns the
+// path of the .utoc file.
+func WriteContainer(dir, name string, files []File, opts Options) (string, error) {
+	utoc, ucas := Build(files, opts)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	utocPath := filepath.Join(dir, name+".utoc")
+	if err := os.WriteFile(utocPath, utoc, 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".ucas"), ucas, 0644); err != nil {
+		return "", err
+	}
+	return utocPath, nil
+}
+
+// buildDirectoryIndex serializes an FIoDirectoryIndexResource for files.
+func buildDirectoryIndex(mountPoint string, files []File) []byte {
+	type dir struct {
+		name                               uint32
+		firstChild, nextSibling, firstFile uint32
+	}
+	type file struct{ name, nextFile, userData uint32 }
+
+	var strs []string
+	strIndex := map[string]uint32{}
+	intern := func(s string) uint32 {
+		if i, ok := strIndex[s]; ok {
+			return i
+		}
+		strIndex[s] = uint32(len(strs))
+		strs = append(strs, s)
+		return strIndex[s]
+	}
+
+	dirs := []dir{{invalidIndex, invalidIndex, invalidIndex, invalidIndex}}
+	var fileEntries []file
+	dirByPath := map[string]uint32{"": 0}
+
+	var ensureDir func(p string) uint32
+	ensureDir = func(p string) uint32 {
+		if i, ok := dirByPath[p]; ok {
+			return i
+		}
+		parent := ensureDir(parentDir(p))
+		i := uint32(len(dirs))
+		dirs = append(dirs, dir{intern(path.Base(p)), invalidIndex, dirs[parent].firstChild, invalidIndex})
+		dirs[parent].firstChild = i
+		dirByPath[p] = i
+		return i
+	}
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return files[order[a]].Path < files[order[b]].Path })
+
+	for _, i := range order {
+		p := strings.Trim(files[i].Path, "/")
+		d := ensureDir(parentDir(p))
+		fi := uint32(len(fileEntries))
+		fileEntries = append(fileEntries, file{intern(path.Base(p)), dirs[d].firstFile, uint32(i)})
+		dirs[d].firstFile = fi
+	}
+
+	var buf bytes.Buffer
+	writeFString(&buf, mountPoint)
+	writeU32(&buf, uint32(len(dirs)))
+	for _, d := range dirs {
+		writeU32(&buf, d.name)
+		writeU32(&buf, d.firstChild)
+		writeU32(&buf, d.nextSibling)
+		writeU32(&buf, d.firstFile)
+	}
+	writeU32(&buf, uint32(len(fileEntries)))
+	for _, f := range fileEntries {
+		writeU32(&buf, f.name)
+		writeU32(&buf, f.nextFile)
+		writeU32(&buf, f.userData)
+	}
+	writeU32(&buf, uint32(len(strs)))
+	for _, s := range strs {
+		writeFString(&buf, s)
+	}
+	return buf.Bytes()
+}
+
+func parentDir(p string) string {
+	d := path.Dir(p)
+	if d == "." || d == "/" {
+		return ""
+	}
+	return d
+}
+
+// rawChunkType converts a ChunkType back to the on-disk value for version.
+func rawChunkType(version iostore.TocVersion, t iostore.ChunkType) byte {
+	if version >= iostore.TocVersionPerfectHash {
+		return byte(t)
+	}
+	ue4 := map[iostore.ChunkType]byte{
+		iostore.ChunkTypeExportBundleData:     2,
+		iostore.ChunkTypeBulkData:             3,
+		iostore.ChunkTypeOptionalBulkData:     4,
+		iostore.ChunkTypeMemoryMappedBulkData: 5,
+		iostore.ChunkTypeContainerHeader:      10,
+	}
+	return ue4[t]
+}
+
+func packBlock(offset uint64, compressed, uncompressed uint32, method uint8) []byte {
+	b := make([]byte, 12)
+	for i := 0; i < 5; i++ {
+		b[i] = byte(offset >> (8 * i))
+	}
+	for i := 0; i < 3; i++ {
+		b[5+i] = byte(compressed >> (8 * i))
+		b[8+i] = byte(uncompressed >> (8 * i))
+	}
+	b[11] = method
+	return b
+}
+
+// encryptECB zero-pads data to the AES block size and encrypts it with
+// AES-256-ECB.
+func encryptECB(key, data []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	padded := make([]byte, (len(data)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, data)
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		block.Encrypt(padded[i:i+aes.BlockSize], padded[i:i+aes.BlockSize])
+	}
+	return padded
+}
+
+func zlibCompress(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func put40BE(v uint64) []byte {
+	b := make([]byte, 5)
+	for i := 4; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+func writeU32(buf *bytes.Buffer, v uint32) {
+	binary.Write(buf, binary.LittleEndian, v)
+}
+
+func writeU64(buf *bytes.Buffer, v uint64) {
+	binary.Write(buf, binary.LittleEndian, v)
+}
+
+func writeFString(buf *bytes.Buffer, s string) {
+	writeU32(buf, uint32(len(s)+1))
+	buf.WriteString(s)
+	buf.WriteByte(0)
+}
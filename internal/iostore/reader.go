@@ -0,0 +1,181 @@
+package iostore
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedCompression is returned when a chunk uses a compression
+// method that cannot be decoded in pure Go, such as Oodle.
+var ErrUnsupportedCompression = errors.New("unsupported compression method")
+
+// ErrEncrypted is returned when reading an encrypted container before a key
+// has been set with [Container.SetKey].
+var ErrEncrypted = errors.New("container is encrypted")
+
+// Container provides random access to the chunk data of an IoStore
+// container: the parsed .utoc plus its .ucas partition files.
+type Container struct {
+	Toc        *Toc
+	partitions []*os.File
+	key        []byte // AES-256 key for encrypted containers
+}
+
+// OpenContainer reads the .utoc at utocPath and opens its .ucas partitions.
+// The caller must call Close when done.
+func OpenContainer(utocPath string) (*Container, error) {
+	toc, err := ReadToc(utocPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Container{Toc: toc}
+	base := strings.TrimSuffix(utocPath, filepath.Ext(utocPath))
+	for i := uint32(0); i < toc.Header.PartitionCount; i++ {
+		name := base + ".ucas"
+		if i > 0 {
+			name = fmt.Sprintf("%s_s%d.ucas", base, i)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.partitions = append(c.partitions, f)
+	}
+	return c, nil
+}
+
+// SetKey sets the AES-256 key used to read an encrypted container. The key
+// is checked by decrypting the directory index, if the container has one,
+// and [ErrWrongKey] is returned when it does not match.
+func (c *Container) SetKey(key []byte) error {
+	if err := c.Toc.DecryptDirectoryIndex(key); err != nil {
+		return err
+	}
+	c.key = key
+	return nil
+}
+
+// Close closes the container's partition files.
+func (c *Container) Close() error {
+	var first error
+	for _, f := range c.partitions {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	c.partitions = nil
+	return first
+}
+
+// CanRead reports whether the chunk at entry index i can be decoded
+// natively, returning ErrEncrypted or ErrUnsupportedCompression if not.
+func (c *Container) CanRead(i int) error {
+	if i < 0 || i >= len(c.Toc.ChunkIDs) {
+		return fmt.Errorf("chunk index %d out of range", i)
+	}
+	if c.Toc.IsEncrypted() && c.key == nil {
+		return ErrEncrypted
+	}
+	for _, method := range c.Toc.ChunkCompressionMethods(i) {
+		if !isSupportedCompression(method) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCompression, method)
+		}
+	}
+	return nil
+}
+
+// ReadChunk returns the uncompressed contents of the chunk at entry index i.
+func (c *Container) ReadChunk(i int) ([]byte, error) {
+	if err := c.CanRead(i); err != nil {
+		return nil, err
+	}
+
+	ol := c.Toc.OffsetLengths[i]
+	if ol.Length == 0 {
+		return []byte{}, nil
+	}
+	first, last, ok := c.Toc.chunkBlockRange(i)
+	if !ok {
+		return nil, fmt.Errorf("chunk %d has no compression blocks", i)
+	}
+	if last >= len(c.Toc.CompressionBlocks) {
+		return nil, fmt.Errorf("chunk %d references missing compression block %d", i, last)
+	}
+
+	var out bytes.Buffer
+	for b := first; b <= last; b++ {
+		data, err := c.readBlock(c.Toc.CompressionBlocks[b])
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", b, err)
+		}
+		out.Write(data)
+	}
+
+	start := ol.Offset % uint64(c.Toc.Header.CompressionBlockSize)
+	all := out.Bytes()
+	if start+ol.Length > uint64(len(all)) {
+		return nil, fmt.Errorf("chunk %d: decoded %d bytes, need %d", i, len(all), start+ol.Length)
+	}
+	return all[start : start+ol.Length], nil
+}
+
+// readBlock reads and decompresses one compression block.
+func (c *Container) readBlock(block CompressionBlock) ([]byte, error) {
+	partitionSize := c.Toc.Header.PartitionSize
+	partition := int(block.Offset / partitionSize)
+	if partition >= len(c.partitions) {
+		return nil, fmt.Errorf("partition %d not open", partition)
+	}
+
+	// Encrypted blocks are padded on disk to the AES block size
+	size := uint64(block.CompressedSize)
+	if c.Toc.IsEncrypted() {
+		size = alignAES(size)
+	}
+	raw := make([]byte, size)
+	if _, err := c.partitions[partition].ReadAt(raw, int64(block.Offset%partitionSize)); err != nil {
+		return nil, err
+	}
+	if c.Toc.IsEncrypted() {
+		plain, err := decryptECB(c.key, raw)
+		if err != nil {
+			return nil, err
+		}
+		raw = plain[:block.CompressedSize]
+	}
+
+	method := c.Toc.compressionMethodName(block.MethodIndex)
+	switch strings.ToLower(method) {
+	case "none":
+		return raw, nil
+	case "zlib":
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		out := make([]byte, block.UncompressedSize)
+		if _, err := io.ReadFull(zr, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, method)
+	}
+}
+
+func isSupportedCompression(method string) bool {
+	switch strings.ToLower(method) {
+	case "none", "zlib":
+		return true
+	}
+	return false
+}
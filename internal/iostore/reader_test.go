@@ -0,0 +1,137 @@
+package iostore_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func TestReader_ReadChunk_Uncompressed_RoundTrips(t *testing.T) {
+	utocPath, err := iostoretest.WriteContainer(t.TempDir(), "pakchunk0-Windows", testFiles(), iostoretest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	c, err := iostore.OpenContainer(utocPath)
+	if err != nil {
+		t.Fatalf("OpenContainer failed: %v", err)
+	}
+	defer c.Close()
+
+	for i, f := range testFiles() {
+		data, err := c.ReadChunk(i)
+		if err != nil {
+			t.Fatalf("ReadChunk(%d) failed: %v", i, err)
+		}
+		if !bytes.Equal(data, f.Data) {
+			t.Errorf("Chunk %d: expected %d bytes of %d, got %d bytes", i, len(f.Data), f.Data[0], len(data))
+		}
+	}
+}
+
+func TestReader_ReadChunk_Zlib_SpansBlocks(t *testing.T) {
+	utocPath, err := iostoretest.WriteContainer(t.TempDir(), "pakchunk0-Windows", testFiles(), iostoretest.Options{
+		Compression: "Zlib",
+	})
+	if err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	c, err := iostore.OpenContainer(utocPath)
+	if err != nil {
+		t.Fatalf("OpenContainer failed: %v", err)
+	}
+	defer c.Close()
+
+	// The .ubulk chunk is larger than one 64 KiB block
+	data, err := c.ReadChunk(1)
+	if err != nil {
+		t.Fatalf("ReadChunk failed: %v", err)
+	}
+	if !bytes.Equal(data, testFiles()[1].Data) {
+		t.Errorf("Expected decompressed data to match, got %d bytes", len(data))
+	}
+	if c.Toc.ChunkCompressedSize(1) >= uint64(len(data)) {
+		t.Error("Expected chunk to be stored compressed")
+	}
+}
+
+func TestReader_ReadChunk_Oodle_ReturnsUnsupported(t *testing.T) {
+	utocPath, err := iostoretest.WriteContainer(t.TempDir(), "pakchunk0-Windows", testFiles(), iostoretest.Options{
+		Compression: "Oodle",
+	})
+	if err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	c, err := iostore.OpenContainer(utocPath)
+	if err != nil {
+		t.Fatalf("OpenContainer failed: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.ReadChunk(0); !errors.Is(err, iostore.ErrUnsupportedCompression) {
+		t.Errorf("Expected ErrUnsupportedCompression, got %v", err)
+	}
+}
+
+func TestReader_OpenContainer_MissingUcas_ReturnsError(t *testing.T) {
+	dir := t.TempDir()
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{})
+	utocPath := filepath.Join(dir, "pakchunk0-Windows.utoc")
+	if err := os.WriteFile(utocPath, utoc, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := iostore.OpenContainer(utocPath); err == nil {
+		t.Error("Expected error when the .ucas file is missing")
+	}
+}
+
+func TestReader_Encrypted_RequiresMatchingKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	utocPath, err := iostoretest.WriteContainer(t.TempDir(), "pakchunk0-Windows", testFiles(), iostoretest.Options{
+		Compression: "Zlib",
+		AESKey:      key,
+	})
+	if err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	c, err := iostore.OpenContainer(utocPath)
+	if err != nil {
+		t.Fatalf("OpenContainer failed: %v", err)
+	}
+	defer c.Close()
+
+	if c.Toc.DirectoryIndex != nil {
+		t.Error("Expected directory index to stay encrypted until a key is set")
+	}
+	if _, err := c.ReadChunk(0); !errors.Is(err, iostore.ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted without a key, got %v", err)
+	}
+	if err := c.SetKey(bytes.Repeat([]byte{0x24}, 32)); !errors.Is(err, iostore.ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey, got %v", err)
+	}
+
+	if err := c.SetKey(key); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	if c.Toc.MountPoint() != "../../../" || len(c.Toc.DirectoryIndex.Files) != 3 {
+		t.Errorf("Expected decrypted index with 3 files, got %+v", c.Toc.DirectoryIndex)
+	}
+	for i, f := range testFiles() {
+		data, err := c.ReadChunk(i)
+		if err != nil {
+			t.Fatalf("ReadChunk(%d) failed: %v", i, err)
+		}
+		if !bytes.Equal(data, f.Data) {
+			t.Errorf("Chunk %d: decrypted data does not match", i)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Package iostore reads Unreal Engine IoStore containers (.utoc/.ucas)
+// natively, without retoc.exe. It understands the TOC layouts written by
+// UE 4.26 through 5.5: header, chunk IDs, offsets, compression blocks,
+// container flags and the directory index that maps file paths to chunks.
+package iostore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TocMagic is the 16-byte magic at the start of every .utoc file.
+const TocMagic = "-==--==--==--==-"
+
+// Sizes of fixed-layout records in a .utoc file.
+const (
+	tocHeaderSize            = 144
+	chunkIDSize              = 12
+	offsetLengthSize         = 10
+	compressionBlockSize     = 12
+	compressionMethodNameLen = 32
+	shaHashSize              = 20
+)
+
+// ErrNotIoStore is returned when a file does not start with [TocMagic].
+var ErrNotIoStore = errors.New("not an IoStore table of contents")
+
+// TocHeader is the fixed 144-byte FIoStoreTocHeader.
+type TocHeader struct {
+	Version                    TocVersion     `json:"version"`
+	HeaderSize                 uint32         `json:"header_size"`
+	EntryCount                 uint32         `json:"entry_count"`
+	CompressedBlockEntryCount  uint32         `json:"compressed_block_entry_count"`
+	CompressedBlockEntrySize   uint32         `json:"compressed_block_entry_size"`
+	CompressionMethodNameCount uint32         `json:"compression_method_name_count"`
+	CompressionMethodNameLen   uint32         `json:"compression_method_name_length"`
+	CompressionBlockSize       uint32         `json:"compression_block_size"`
+	DirectoryIndexSize         uint32         `json:"directory_index_size"`
+	PartitionCount             uint32         `json:"partition_count"`
+	ContainerID                uint64         `json:"container_id"`
+	EncryptionKeyGUID          [16]byte       `json:"encryption_key_guid"`
+	ContainerFlags             ContainerFlags `json:"container_flags"`
+	PerfectHashSeedsCount      uint32         `json:"perfect_hash_seeds_count"`
+	PartitionSize              uint64         `json:"partition_size"`
+	ChunksWithoutPerfectHash   uint32         `json:"chunks_without_perfect_hash_count"`
+}
+
+// Toc is a parsed .utoc file. DirectoryIndex is nil when the container has
+// no index, or when the index is encrypted and has not been decrypted with
+// [Toc.DecryptDirectoryIndex].
+type Toc struct {
+	Path               string             `json:"path"`
+	Header             TocHeader          `json:"header"`
+	ChunkIDs           []ChunkID          `json:"chunk_ids"`
+	OffsetLengths      []OffsetLength     `json:"offset_lengths"`
+	CompressionBlocks  []CompressionBlock `json:"compression_blocks"`
+	CompressionMethods []string           `json:"compression_methods"` // Index 0 is always "None"
+	DirectoryIndex     *DirectoryIndex    `json:"directory_index,omitempty"`
+
+	// rawDirectoryIndex holds the serialized index as stored on disk, which
+	// is ciphertext for encrypted containers.
+	rawDirectoryIndex []byte
+}
+
+// ReadToc reads and parses the .utoc file at path.
+func ReadToc(path string) (*Toc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	toc, err := ParseToc(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	toc.Path = path
+	return toc, nil
+}
+
+// ReadTocHeader reads only the fixed header of the .utoc file at path,
+// which is enough to tell the container's TOC version.
+func ReadTocHeader(path string) (TocHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TocHeader{}, err
+	}
+	defer f.Close()
+
+	data := make([]byte, tocHeaderSize)
+	if _, err := io.ReadFull(f, data); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return TocHeader{}, fmt.Errorf("%s: %w", path, ErrNotIoStore)
+		}
+		return TocHeader{}, err
+	}
+	h, err := ParseTocHeader(data)
+	if err != nil {
+		return TocHeader{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return h, nil
+}
+
+// ParseTocHeader parses and validates the fixed header at the start of data.
+// Fields that older TOC versions do not have are set to their defaults.
+func ParseTocHeader(data []byte) (TocHeader, error) {
+	r := &reader{data: data}
+
+	magic := r.bytes(16)
+	if r.err != nil || string(magic) != TocMagic {
+		return TocHeader{}, ErrNotIoStore
+	}
+
+	h := TocHeader{}
+	h.Version = TocVersion(r.u8())
+	r.skip(1 + 2) // Reserved0, Reserved1
+	h.HeaderSize = r.u32()
+	h.EntryCount = r.u32()
+	h.CompressedBlockEntryCount = r.u32()
+	h.CompressedBlockEntrySize = r.u32()
+	h.CompressionMethodNameCount = r.u32()
+	h.CompressionMethodNameLen = r.u32()
+	h.CompressionBlockSize = r.u32()
+	h.DirectoryIndexSize = r.u32()
+	h.PartitionCount = r.u32()
+	h.ContainerID = r.u64()
+	copy(h.EncryptionKeyGUID[:], r.bytes(16))
+	h.ContainerFlags = ContainerFlags(r.u8())
+	r.skip(1 + 2) // Reserved3, Reserved4
+	h.PerfectHashSeedsCount = r.u32()
+	h.PartitionSize = r.u64()
+	h.ChunksWithoutPerfectHash = r.u32()
+	if r.err != nil {
+		return TocHeader{}, fmt.Errorf("truncated header: %w", r.err)
+	}
+
+	if h.Version == TocVersionInvalid || h.Version > TocVersionLatest {
+		return TocHeader{}, fmt.Errorf("unsupported TOC version %d", uint8(h.Version))
+	}
+	if h.HeaderSize != tocHeaderSize {
+		return TocHeader{}, fmt.Errorf("unexpected TOC header size %d", h.HeaderSize)
+	}
+	if h.CompressedBlockEntryCount > 0 && h.CompressedBlockEntrySize != compressionBlockSize {
+		return TocHeader{}, fmt.Errorf("unexpected compression block entry size %d", h.CompressedBlockEntrySize)
+	}
+	if h.CompressionBlockSize == 0 {
+		return TocHeader{}, errors.New("compression block size is zero")
+	}
+	if h.Version < TocVersionPartitionSize || h.PartitionCount == 0 {
+		h.PartitionCount = 1
+		h.PartitionSize = ^uint64(0)
+	} else if h.PartitionSize == 0 {
+		return TocHeader{}, errors.New("partition size is zero")
+	}
+	if h.Version < TocVersionPerfectHash {
+		h.PerfectHashSeedsCount = 0
+	}
+	if h.Version < TocVersionPerfectHashWithOverflow {
+		h.ChunksWithoutPerfectHash = 0
+	}
+	return h, nil
+}
+
+// ParseToc parses the contents of a .utoc file.
+func ParseToc(data []byte) (*Toc, error) {
+	h, err := ParseTocHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{data: data, pos: int(h.HeaderSize)}
+	toc := &Toc{Header: h}
+
+	toc.ChunkIDs = make([]ChunkID, 0, h.EntryCount)
+	for i := uint32(0); i < h.EntryCount && r.err == nil; i++ {
+		if b := r.bytes(chunkIDSize); r.err == nil {
+			toc.ChunkIDs = append(toc.ChunkIDs, parseChunkID(h.Version, b))
+		}
+	}
+
+	toc.OffsetLengths = make([]OffsetLength, 0, h.EntryCount)
+	for i := uint32(0); i < h.EntryCount && r.err == nil; i++ {
+		if b := r.bytes(offsetLengthSize); r.err == nil {
+			toc.OffsetLengths = append(toc.OffsetLengths, parseOffsetLength(b))
+		}
+	}
+
+	r.skip(int(h.PerfectHashSeedsCount) * 4)
+	r.skip(int(h.ChunksWithoutPerfectHash) * 4)
+
+	toc.CompressionBlocks = make([]CompressionBlock, 0, h.CompressedBlockEntryCount)
+	for i := uint32(0); i < h.CompressedBlockEntryCount && r.err == nil; i++ {
+		if b := r.bytes(compressionBlockSize); r.err == nil {
+			toc.CompressionBlocks = append(toc.CompressionBlocks, parseCompressionBlock(b))
+		}
+	}
+
+	toc.CompressionMethods = []string{"None"}
+	for i := uint32(0); i < h.CompressionMethodNameCount && r.err == nil; i++ {
+		name := r.bytes(int(h.CompressionMethodNameLen))
+		if r.err == nil {
+			toc.CompressionMethods = append(toc.CompressionMethods, string(bytes.TrimRight(name, "\x00")))
+		}
+	}
+
+	if h.ContainerFlags.Has(ContainerFlagSigned) {
+		hashSize := int(r.u32())
+		r.skip(hashSize * 2) // TOC and block signatures
+		r.skip(int(h.CompressedBlockEntryCount) * shaHashSize)
+	}
+
+	if h.Version >= TocVersionDirectoryIndex && h.ContainerFlags.Has(ContainerFlagIndexed) && h.DirectoryIndexSize > 0 {
+		toc.rawDirectoryIndex = r.bytes(int(h.DirectoryIndexSize))
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("truncated table of contents: %w", r.err)
+	}
+
+	if toc.rawDirectoryIndex != nil && !h.ContainerFlags.Has(ContainerFlagEncrypted) {
+		index, err := ParseDirectoryIndex(toc.rawDirectoryIndex)
+		if err != nil {
+			return nil, fmt.Errorf("directory index: %w", err)
+		}
+		toc.DirectoryIndex = index
+	}
+
+	return toc, nil
+}
+
+// IsEncrypted reports whether the container's index and data are encrypted.
+func (t *Toc) IsEncrypted() bool {
+	return t.Header.ContainerFlags.Has(ContainerFlagEncrypted)
+}
+
+// IsSigned reports whether the container carries block signatures.
+func (t *Toc) IsSigned() bool {
+	return t.Header.ContainerFlags.Has(ContainerFlagSigned)
+}
+
+// HasDirectoryIndex reports whether the container stores a directory index,
+// whether or not it has been decoded yet.
+func (t *Toc) HasDirectoryIndex() bool {
+	return t.rawDirectoryIndex != nil
+}
+
+// MountPoint returns the directory index mount point, or an empty string if
+// the index is absent or still encrypted.
+func (t *Toc) MountPoint() string {
+	if t.DirectoryIndex == nil {
+		return ""
+	}
+	return t.DirectoryIndex.MountPoint
+}
+
+// ChunkCompressedSize returns the number of bytes the chunk at entry index i
+// occupies in the .ucas file, summed over its compression blocks.
+func (t *Toc) ChunkCompressedSize(i int) uint64 {
+	first, last, ok := t.chunkBlockRange(i)
+	if !ok {
+		return 0
+	}
+	var total uint64
+	for b := first; b <= last && b < len(t.CompressionBlocks); b++ {
+		total += uint64(t.CompressionBlocks[b].CompressedSize)
+	}
+	return total
+}
+
+// ChunkCompressionMethods returns the distinct compression methods used by
+// the chunk at entry index i.
+func (t *Toc) ChunkCompressionMethods(i int) []string {
+	first, last, ok := t.chunkBlockRange(i)
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var methods []string
+	for b := first; b <= last && b < len(t.CompressionBlocks); b++ {
+		name := t.compressionMethodName(t.CompressionBlocks[b].MethodIndex)
+		if !seen[name] {
+			seen[name] = true
+			methods = append(methods, name)
+		}
+	}
+	return methods
+}
+
+func (t *Toc) compressionMethodName(index uint8) string {
+	if int(index) < len(t.CompressionMethods) {
+		return t.CompressionMethods[index]
+	}
+	return fmt.Sprintf("Unknown(%d)", index)
+}
+
+// chunkBlockRange returns the first and last compression block indices that
+// hold the chunk at entry index i.
+func (t *Toc) chunkBlockRange(i int) (first, last int, ok bool) {
+	if i < 0 || i >= len(t.OffsetLengths) || t.Header.CompressionBlockSize == 0 {
+		return 0, 0, false
+	}
+	ol := t.OffsetLengths[i]
+	if ol.Length == 0 {
+		return 0, 0, false
+	}
+	blockSize := uint64(t.Header.CompressionBlockSize)
+	first = int(ol.Offset / blockSize)
+	last = int((ol.Offset + ol.Length - 1) / blockSize)
+	return first, last, true
+}
+
+// reader is a bounds-checked little-endian cursor over a byte slice. The
+// first out-of-range read sets err; later reads return zero values.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) i32() int32 {
+	return int32(r.u32())
+}
+
+func (r *reader) u64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package iostore_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func testFiles() []iostoretest.File {
+	return []iostoretest.File{
+		{Path: "MyGame/Content/Data/DT_Items.uasset", Data: bytes.Repeat([]byte{1}, 100)},
+		{Path: "MyGame/Content/Data/DT_Items.ubulk", Data: bytes.Repeat([]byte{2}, 70000), ChunkType: iostore.ChunkTypeBulkData},
+		{Path: "MyGame/Content/Maps/Arena.umap", Data: bytes.Repeat([]byte{3}, 10)},
+	}
+}
+
+func TestToc_ParseHeader_ReadsVersionAndFlags(t *testing.T) {
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{
+		Version: iostore.TocVersionReplaceIoChunkHashWithIoHash,
+		Flags:   iostore.ContainerFlagCompressed,
+	})
+
+	toc, err := iostore.ParseToc(utoc)
+	if err != nil {
+		t.Fatalf("ParseToc failed: %v", err)
+	}
+
+	if toc.Header.Version != iostore.TocVersionReplaceIoChunkHashWithIoHash {
+		t.Errorf("Expected version %v, got %v", iostore.TocVersionReplaceIoChunkHashWithIoHash, toc.Header.Version)
+	}
+	if toc.Header.EntryCount != 3 {
+		t.Errorf("Expected 3 entries, got %d", toc.Header.EntryCount)
+	}
+	if !toc.Header.ContainerFlags.Has(iostore.ContainerFlagIndexed) || !toc.Header.ContainerFlags.Has(iostore.ContainerFlagCompressed) {
+		t.Errorf("Expected Compressed|Indexed flags, got %v", toc.Header.ContainerFlags)
+	}
+	if toc.IsEncrypted() || toc.IsSigned() {
+		t.Error("Expected container to be neither encrypted nor signed")
+	}
+}
+
+func TestToc_Entries_ResolvesPathsAndSizes(t *testing.T) {
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{})
+
+	toc, err := iostore.ParseToc(utoc)
+	if err != nil {
+		t.Fatalf("ParseToc failed: %v", err)
+	}
+
+	entries := toc.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	bulk := entries[1]
+	if bulk.Path != "../../../MyGame/Content/Data/DT_Items.ubulk" {
+		t.Errorf("Unexpected path: %s", bulk.Path)
+	}
+	if bulk.PackagePath != "/Game/Data/DT_Items" {
+		t.Errorf("Unexpected package path: %s", bulk.PackagePath)
+	}
+	if bulk.Type != iostore.ChunkTypeBulkData {
+		t.Errorf("Expected BulkData, got %v", bulk.Type)
+	}
+	if bulk.Size != 70000 || bulk.CompressedSize != 70000 {
+		t.Errorf("Expected size 70000/70000, got %d/%d", bulk.Size, bulk.CompressedSize)
+	}
+}
+
+func TestToc_UE4Version_NormalisesChunkTypes(t *testing.T) {
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{Version: iostore.TocVersionPartitionSize})
+
+	toc, err := iostore.ParseToc(utoc)
+	if err != nil {
+		t.Fatalf("ParseToc failed: %v", err)
+	}
+
+	if got := toc.ChunkIDs[0].Type; got != iostore.ChunkTypeExportBundleData {
+		t.Errorf("Expected ExportBundleData, got %v", got)
+	}
+	if got := toc.ChunkIDs[1].Type; got != iostore.ChunkTypeBulkData {
+		t.Errorf("Expected BulkData, got %v", got)
+	}
+}
+
+func TestToc_BadMagic_ReturnsErrNotIoStore(t *testing.T) {
+	_, err := iostore.ParseToc([]byte("definitely not a utoc file at all"))
+	if !errors.Is(err, iostore.ErrNotIoStore) {
+		t.Errorf("Expected ErrNotIoStore, got %v", err)
+	}
+}
+
+func TestToc_Truncated_ReturnsError(t *testing.T) {
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{})
+
+	if _, err := iostore.ParseToc(utoc[:len(utoc)-20]); err == nil {
+		t.Error("Expected error for truncated TOC")
+	}
+}
+
+func TestToc_ZeroBlockOrPartitionSize_ReturnsError(t *testing.T) {
+	utoc, _ := iostoretest.Build(testFiles(), iostoretest.Options{})
+
+	// Either would divide by zero when reading a chunk
+	for name, field := range map[string][2]int{
+		"compression block size": {44, 48},
+		"partition size":         {88, 96},
+	} {
+		corrupt := bytes.Clone(utoc)
+		clear(corrupt[field[0]:field[1]])
+		if _, err := iostore.ParseToc(corrupt); err == nil {
+			t.Errorf("Expected an error for a zero %s", name)
+		}
+	}
+}
+
+func TestToc_FindContainers_RecursesIntoSubdirectories(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := iostoretest.WriteContainer(dir, "pakchunk0-Windows", testFiles(), iostoretest.Options{}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+	if _, err := iostoretest.WriteContainer(filepath.Join(dir, "~mods"), "z_Mod_0001_P", testFiles(), iostoretest.Options{}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	found, err := iostore.FindContainers(dir)
+	if err != nil {
+		t.Fatalf("FindContainers failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Expected 2 containers, got %d: %v", len(found), found)
+	}
+}
@@ -0,0 +1,235 @@
+package iostore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// TocVersion is the EIoStoreTocVersion stored in a .utoc header.
+type TocVersion uint8
+
+// Known TOC versions. UE 4.26 writes DirectoryIndex, 4.27 writes
+// PartitionSize, 5.0-5.2 write PerfectHashWithOverflow, and 5.3-5.5 write
+// the later versions.
+const (
+	TocVersionInvalid TocVersion = iota
+	TocVersionInitial
+	TocVersionDirectoryIndex
+	TocVersionPartitionSize
+	TocVersionPerfectHash
+	TocVersionPerfectHashWithOverflow
+	TocVersionOnDemandMetaData
+	TocVersionRemovedOnDemandMetaData
+	TocVersionReplaceIoChunkHashWithIoHash
+
+	// TocVersionLatest is the newest version this package understands.
+	TocVersionLatest = TocVersionReplaceIoChunkHashWithIoHash
+)
+
+var tocVersionNames = map[TocVersion]string{
+	TocVersionInvalid:                      "Invalid",
+	TocVersionInitial:                      "Initial",
+	TocVersionDirectoryIndex:               "DirectoryIndex",
+	TocVersionPartitionSize:                "PartitionSize",
+	TocVersionPerfectHash:                  "PerfectHash",
+	TocVersionPerfectHashWithOverflow:      "PerfectHashWithOverflow",
+	TocVersionOnDemandMetaData:             "OnDemandMetaData",
+	TocVersionRemovedOnDemandMetaData:      "RemovedOnDemandMetaData",
+	TocVersionReplaceIoChunkHashWithIoHash: "ReplaceIoChunkHashWithIoHash",
+}
+
+func (v TocVersion) String() string {
+	if name, ok := tocVersionNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", uint8(v))
+}
+
+// ContainerFlags is the EIoContainerFlags bit set stored in a .utoc header.
+type ContainerFlags uint8
+
+// Container flag bits.
+const (
+	ContainerFlagCompressed ContainerFlags = 1 << iota
+	ContainerFlagEncrypted
+	ContainerFlagSigned
+	ContainerFlagIndexed
+	ContainerFlagOnDemand
+)
+
+// Has reports whether all bits in flag are set.
+func (f ContainerFlags) Has(flag ContainerFlags) bool {
+	return f&flag == flag
+}
+
+func (f ContainerFlags) String() string {
+	names := []string{}
+	for _, n := range []struct {
+		flag ContainerFlags
+		name string
+	}{
+		{ContainerFlagCompressed, "Compressed"},
+		{ContainerFlagEncrypted, "Encrypted"},
+		{ContainerFlagSigned, "Signed"},
+		{ContainerFlagIndexed, "Indexed"},
+		{ContainerFlagOnDemand, "OnDemand"},
+	} {
+		if f.Has(n.flag) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "|")
+}
+
+// ChunkType identifies what a chunk contains. Values follow the UE5
+// EIoChunkType enum; UE4 containers are normalised to these values when
+// parsed, with UE4-only types mapped to the constants above 0x40.
+type ChunkType uint8
+
+// Chunk types.
+const (
+	ChunkTypeInvalid ChunkType = iota
+	ChunkTypeExportBundleData
+	ChunkTypeBulkData
+	ChunkTypeOptionalBulkData
+	ChunkTypeMemoryMappedBulkData
+	ChunkTypeScriptObjects
+	ChunkTypeContainerHeader
+	ChunkTypeExternalFile
+	ChunkTypeShaderCodeLibrary
+	ChunkTypeShaderCode
+	ChunkTypePackageStoreEntry
+	ChunkTypeDerivedData
+	ChunkTypeEditorDerivedData
+	ChunkTypePackageResource
+)
+
+// UE4-only chunk types.
+const (
+	ChunkTypeInstallManifest ChunkType = 0x40 + iota
+	ChunkTypeLoaderGlobalMeta
+	ChunkTypeLoaderInitialLoadMeta
+	ChunkTypeLoaderGlobalNames
+	ChunkTypeLoaderGlobalNameHashes
+)
+
+var chunkTypeNames = map[ChunkType]string{
+	ChunkTypeInvalid:                "Invalid",
+	ChunkTypeExportBundleData:       "ExportBundleData",
+	ChunkTypeBulkData:               "BulkData",
+	ChunkTypeOptionalBulkData:       "OptionalBulkData",
+	ChunkTypeMemoryMappedBulkData:   "MemoryMappedBulkData",
+	ChunkTypeScriptObjects:          "ScriptObjects",
+	ChunkTypeContainerHeader:        "ContainerHeader",
+	ChunkTypeExternalFile:           "ExternalFile",
+	ChunkTypeShaderCodeLibrary:      "ShaderCodeLibrary",
+	ChunkTypeShaderCode:             "ShaderCode",
+	ChunkTypePackageStoreEntry:      "PackageStoreEntry",
+	ChunkTypeDerivedData:            "DerivedData",
+	ChunkTypeEditorDerivedData:      "EditorDerivedData",
+	ChunkTypePackageResource:        "PackageResource",
+	ChunkTypeInstallManifest:        "InstallManifest",
+	ChunkTypeLoaderGlobalMeta:       "LoaderGlobalMeta",
+	ChunkTypeLoaderInitialLoadMeta:  "LoaderInitialLoadMeta",
+	ChunkTypeLoaderGlobalNames:      "LoaderGlobalNames",
+	ChunkTypeLoaderGlobalNameHashes: "LoaderGlobalNameHashes",
+}
+
+func (t ChunkType) String() string {
+	if name, ok := chunkTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", uint8(t))
+}
+
+// ue4ChunkTypes maps the UE4 EIoChunkType values to ChunkType.
+var ue4ChunkTypes = map[uint8]ChunkType{
+	0:  ChunkTypeInvalid,
+	1:  ChunkTypeInstallManifest,
+	2:  ChunkTypeExportBundleData,
+	3:  ChunkTypeBulkData,
+	4:  ChunkTypeOptionalBulkData,
+	5:  ChunkTypeMemoryMappedBulkData,
+	6:  ChunkTypeLoaderGlobalMeta,
+	7:  ChunkTypeLoaderInitialLoadMeta,
+	8:  ChunkTypeLoaderGlobalNames,
+	9:  ChunkTypeLoaderGlobalNameHashes,
+	10: ChunkTypeContainerHeader,
+}
+
+// chunkTypeFromRaw normalises a raw chunk type byte for the given version.
+// Containers older than PerfectHash were written by UE4.
+func chunkTypeFromRaw(version TocVersion, raw uint8) ChunkType {
+	if version < TocVersionPerfectHash {
+		if t, ok := ue4ChunkTypes[raw]; ok {
+			return t
+		}
+		return ChunkType(raw)
+	}
+	return ChunkType(raw)
+}
+
+// ChunkID is the 12-byte FIoChunkId identifying a chunk in a container.
+type ChunkID struct {
+	ID    uint64    `json:"id"`    // Package ID or other identifier
+	Index uint16    `json:"index"` // Disambiguates multiple chunks of one type
+	Type  ChunkType `json:"type"`
+	Raw   [12]byte  `json:"-"`
+}
+
+func parseChunkID(version TocVersion, b []byte) ChunkID {
+	var id ChunkID
+	copy(id.Raw[:], b[:12])
+	id.ID = binary.LittleEndian.Uint64(b[0:8])
+	id.Index = binary.BigEndian.Uint16(b[8:10])
+	id.Type = chunkTypeFromRaw(version, b[11])
+	return id
+}
+
+func (c ChunkID) String() string {
+	return fmt.Sprintf("%016x:%d:%s", c.ID, c.Index, c.Type)
+}
+
+// OffsetLength locates a chunk in the container's uncompressed address space.
+type OffsetLength struct {
+	Offset uint64 `json:"offset"`
+	Length uint64 `json:"length"`
+}
+
+// parseOffsetLength decodes the 10-byte FIoOffsetAndLength, which stores two
+// 40-bit big-endian integers.
+func parseOffsetLength(b []byte) OffsetLength {
+	var o OffsetLength
+	for i := 0; i < 5; i++ {
+		o.Offset = o.Offset<<8 | uint64(b[i])
+		o.Length = o.Length<<8 | uint64(b[5+i])
+	}
+	return o
+}
+
+// CompressionBlock is one FIoStoreTocCompressedBlockEntry.
+type CompressionBlock struct {
+	Offset           uint64 `json:"offset"` // Offset in the .ucas partition space
+	CompressedSize   uint32 `json:"compressed_size"`
+	UncompressedSize uint32 `json:"uncompressed_size"`
+	MethodIndex      uint8  `json:"method_index"` // Index into Toc.CompressionMethods
+}
+
+// parseCompressionBlock decodes the 12-byte packed entry: a 40-bit offset,
+// two 24-bit sizes and an 8-bit method index, all little-endian.
+func parseCompressionBlock(b []byte) CompressionBlock {
+	var offset uint64
+	for i := 4; i >= 0; i-- {
+		offset = offset<<8 | uint64(b[i])
+	}
+	return CompressionBlock{
+		Offset:           offset,
+		CompressedSize:   uint32(b[5]) | uint32(b[6])<<8 | uint32(b[7])<<16,
+		UncompressedSize: uint32(b[8]) | uint32(b[9])<<8 | uint32(b[10])<<16,
+		MethodIndex:      b[11],
+	}
+}
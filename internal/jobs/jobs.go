@@ -0,0 +1,387 @@
+// Package jobs queues retoc and UAsset work so that a batch such as
+// "unpack the game, export its assets, pack five mods" can be submitted at
+// once and run unattended. Jobs run with a per-tool parallelism limit,
+// highest priority first, each once the jobs it depends on have succeeded.
+// The queue can be paused, and queued jobs are saved to the data directory
+// so they survive a restart.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Tools a job can run.
+const (
+	ToolRetoc  = "retoc"
+	ToolUAsset = "uasset"
+)
+
+// Preference keys holding how many jobs of each tool run at once.
+const (
+	PrefRetocWorkers  = "jobs_retoc_workers"
+	PrefUAssetWorkers = "jobs_uasset_workers"
+)
+
+// defaultWorkers is the parallelism of each tool unless its preference is
+// set. retoc.exe is I/O bound and runs alone; bridge workers are cheap.
+var defaultWorkers = map[string]int{
+	ToolRetoc:  1,
+	ToolUAsset: 2,
+}
+
+// workerPrefs maps each tool to its parallelism preference.
+var workerPrefs = map[string]string{
+	ToolRetoc:  PrefRetocWorkers,
+	ToolUAsset: PrefUAssetWorkers,
+}
+
+// EventJob is emitted with a [Job] whenever a job is submitted or changes
+// state.
+const EventJob = "jobs:changed"
+
+// maxFinishedJobs bounds how many finished jobs are kept; the oldest are
+// dropped.
+const maxFinishedJobs = 200
+
+// Job is a queued retoc operation or UAsset request. Exactly one of Retoc
+// and UAsset is set; Tool is derived from it when empty.
+//
+// DependsOn lists the IDs of jobs that must succeed before this one runs.
+// They must already be queued or come earlier in the same submission. If
+// one of them fails or is cancelled, so does this job.
+type Job struct {
+	ID        string                `json:"id"` // Assigned on submission when empty
+	Name      string                `json:"name"`
+	Tool      string                `json:"tool"`     // ToolRetoc or ToolUAsset
+	Priority  int                   `json:"priority"` // Higher runs first; ties run in submission order
+	DependsOn []string              `json:"depends_on"`
+	Retoc     *retoc.RetocOperation `json:"retoc,omitempty"`
+	UAsset    *uasset.UAssetRequest `json:"uasset,omitempty"`
+
+	State       app.OperationState `json:"state"`
+	Error       string             `json:"error,omitempty"`
+	Result      json.RawMessage    `json:"result,omitempty"` // The RetocResult or UAssetResult
+	SubmittedAt time.Time          `json:"submitted_at"`
+	StartedAt   time.Time          `json:"started_at,omitzero"`
+	EndedAt     time.Time          `json:"ended_at,omitzero"`
+}
+
+// runner runs the work of a job and returns its result.
+type runner func(ctx context.Context, job Job) (result any, success bool, errMessage string)
+
+// JobQueue runs submitted jobs in the background. It is registered as a
+// Wails service: ServiceStartup loads the saved queue and starts running
+// it, and ServiceShutdown stops it, leaving unfinished jobs queued for the
+// next start.
+//
+// JobQueue is safe for concurrent use by multiple goroutines.
+type JobQueue struct {
+	app     *app.App
+	runners map[string]runner
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	order   []string // Job IDs in submission order
+	paused  bool
+	started bool
+	running map[string]int                // tool -> running jobs
+	cancels map[string]context.CancelFunc // job ID -> cancel, running jobs only
+	pending []Job                         // Changes to emit once mu is released
+	dirty   bool                          // The queue must be saved
+	wg      sync.WaitGroup
+}
+
+// NewJobQueue creates a JobQueue that runs jobs with the given services.
+// Nothing runs until ServiceStartup.
+func NewJobQueue(a *app.App, r *retoc.RetocService, u *uasset.UAssetService) *JobQueue {
+	return newJobQueue(a, map[string]runner{
+		ToolRetoc: func(ctx context.Context, job Job) (any, bool, string) {
+			result := r.RunRetoc(ctx, *job.Retoc)
+			return result, result.Success, result.Error
+		},
+		ToolUAsset: func(ctx context.Context, job Job) (any, bool, string) {
+			result := u.RunUAsset(ctx, *job.UAsset)
+			return result, result.Success, result.Error
+		},
+	})
+}
+
+func newJobQueue(a *app.App, runners map[string]runner) *JobQueue {
+	return &JobQueue{
+		app:     a,
+		runners: runners,
+		jobs:    make(map[string]*Job),
+		running: make(map[string]int),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// ServiceStartup loads the saved queue and starts running it.
+func (q *JobQueue) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	state, err := q.load()
+	if err != nil {
+		log.Printf("Failed to load job queue: %v", err)
+	}
+	q.update(func() {
+		for i := range state.Jobs {
+			job := state.Jobs[i]
+			if _, exists := q.jobs[job.ID]; exists {
+				continue
+			}
+			// A job interrupted by the last shutdown runs again
+			if job.State == app.OperationRunning {
+				job.State = app.OperationQueued
+				job.StartedAt = time.Time{}
+			}
+			q.jobs[job.ID] = &job
+			q.order = append(q.order, job.ID)
+		}
+		q.paused = q.paused || state.Paused
+		q.started = true
+		q.schedule()
+	})
+	return nil
+}
+
+// ServiceShutdown stops running jobs and saves the queue. Jobs that were
+// running are queued again for the next start.
+func (q *JobQueue) ServiceShutdown() error {
+	q.update(func() {
+		q.started = false
+		for _, cancel := range q.cancels {
+			cancel()
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		log.Printf("Timed out waiting for running jobs to stop")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.save()
+}
+
+// Submit validates and queues jobs, in order, and returns them as queued.
+// Either all of them are queued or, on error, none are.
+func (q *JobQueue) Submit(ctx context.Context, jobs []Job) ([]Job, error) {
+	var queued []Job
+	var err error
+	q.update(func() {
+		batch := make(map[string]bool)
+		prepared := make([]*Job, 0, len(jobs))
+		for i := range jobs {
+			job := jobs[i]
+			if err = q.prepare(&job, batch); err != nil {
+				err = fmt.Errorf("job %d: %w", i+1, err)
+				return
+			}
+			batch[job.ID] = true
+			prepared = append(prepared, &job)
+		}
+
+		for _, job := range prepared {
+			q.jobs[job.ID] = job
+			q.order = append(q.order, job.ID)
+			q.touch(job)
+			queued = append(queued, *job)
+		}
+		q.schedule()
+	})
+	return queued, err
+}
+
+// prepare validates a submitted job, fills in its ID and tool and resets
+// its state. batch holds the IDs submitted before it in the same call.
+func (q *JobQueue) prepare(job *Job, batch map[string]bool) error {
+	switch {
+	case job.Retoc != nil && job.UAsset != nil:
+		return errors.New("set either retoc or uasset, not both")
+	case job.Retoc != nil:
+		if job.Tool == "" {
+			job.Tool = ToolRetoc
+		}
+	case job.UAsset != nil:
+		if job.Tool == "" {
+			job.Tool = ToolUAsset
+		}
+	default:
+		return errors.New("nothing to run; set retoc or uasset")
+	}
+	if (job.Tool == ToolRetoc) != (job.Retoc != nil) || q.runners[job.Tool] == nil {
+		return fmt.Errorf("tool %q does not match the job", job.Tool)
+	}
+
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	} else if _, exists := q.jobs[job.ID]; exists || batch[job.ID] {
+		return fmt.Errorf("duplicate job ID %q", job.ID)
+	}
+	for _, dep := range job.DependsOn {
+		if _, exists := q.jobs[dep]; !exists && !batch[dep] {
+			return fmt.Errorf("unknown dependency %q", dep)
+		}
+	}
+
+	job.State = app.OperationQueued
+	job.Error, job.Result = "", nil
+	job.SubmittedAt = time.Now()
+	job.StartedAt, job.EndedAt = time.Time{}, time.Time{}
+	return nil
+}
+
+// List returns all jobs in submission order.
+func (q *JobQueue) List(ctx context.Context) []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.order))
+	for _, id := range q.order {
+		jobs = append(jobs, *q.jobs[id])
+	}
+	return jobs
+}
+
+// Get returns the job with the given ID.
+func (q *JobQueue) Get(ctx context.Context, id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	return *job, nil
+}
+
+// Cancel cancels a queued or running job, and with it the jobs that depend
+// on it.
+func (q *JobQueue) Cancel(ctx context.Context, id string) error {
+	var err error
+	q.update(func() {
+		job, ok := q.jobs[id]
+		switch {
+		case !ok:
+			err = fmt.Errorf("job %s not found", id)
+		case job.State.Finished():
+			err = fmt.Errorf("job %s has already finished", id)
+		case job.State == app.OperationRunning:
+			q.cancels[id]()
+		default:
+			q.finish(job, app.OperationCancelled, "")
+			q.schedule()
+		}
+	})
+	return err
+}
+
+// Pause stops new jobs from starting. Running jobs continue.
+func (q *JobQueue) Pause(ctx context.Context) {
+	q.update(func() {
+		q.paused = true
+		q.dirty = true
+	})
+}
+
+// Resume starts queued jobs again after Pause.
+func (q *JobQueue) Resume(ctx context.Context) {
+	q.update(func() {
+		q.paused = false
+		q.dirty = true
+		q.schedule()
+	})
+}
+
+// IsPaused reports whether the queue is paused.
+func (q *JobQueue) IsPaused(ctx context.Context) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.paused
+}
+
+// ClearFinished removes finished jobs that no queued job depends on and
+// returns how many were removed.
+func (q *JobQueue) ClearFinished(ctx context.Context) int {
+	removed := 0
+	q.update(func() {
+		removed = q.prune(0)
+	})
+	return removed
+}
+
+// GetConcurrency returns how many jobs of each tool may run at once.
+func (q *JobQueue) GetConcurrency(ctx context.Context) map[string]int {
+	limits := make(map[string]int, len(workerPrefs))
+	for tool := range workerPrefs {
+		limits[tool] = q.workers(tool)
+	}
+	return limits
+}
+
+// SetConcurrency stores how many jobs of tool may run at once. Lowering it
+// does not stop running jobs.
+func (q *JobQueue) SetConcurrency(ctx context.Context, tool string, workers int) error {
+	pref, ok := workerPrefs[tool]
+	if !ok {
+		return fmt.Errorf("unknown tool %q", tool)
+	}
+	if workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", workers)
+	}
+	q.app.SetPreference(pref, strconv.Itoa(workers))
+	q.update(q.schedule)
+	return nil
+}
+
+// workers returns the parallelism limit of tool.
+func (q *JobQueue) workers(tool string) int {
+	if n, err := strconv.Atoi(q.app.GetPreference(workerPrefs[tool])); err == nil && n > 0 {
+		return n
+	}
+	return defaultWorkers[tool]
+}
+
+// update runs fn with mu held, then saves the queue if fn changed it and
+// emits the jobs it changed. Before ServiceStartup has loaded the saved
+// queue, and after ServiceShutdown, nothing is saved.
+func (q *JobQueue) update(fn func()) {
+	q.mu.Lock()
+	fn()
+	if q.dirty && q.started {
+		if err := q.save(); err != nil {
+			log.Printf("Failed to save job queue: %v", err)
+		}
+		q.dirty = false
+	}
+	changed := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	for _, job := range changed {
+		q.app.Emit(EventJob, job)
+	}
+}
+
+// touch records that job changed. It is called with mu held.
+func (q *JobQueue) touch(job *Job) {
+	q.pending = append(q.pending, *job)
+	q.dirty = true
+}
@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// fakeTools stands in for retoc and the UAsset bridge. Each job blocks
+// until released through its name; jobs named "fail" fail.
+type fakeTools struct {
+	mu      sync.Mutex
+	started []string
+	release map[string]chan struct{}
+}
+
+func (f *fakeTools) runners() map[string]runner {
+	run := func(ctx context.Context, job Job) (any, bool, string) {
+		f.mu.Lock()
+		f.started = append(f.started, job.Name)
+		ch := f.gate(job.Name)
+		f.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, false, "cancelled"
+		}
+		if job.Name == "fail" {
+			return nil, false, "tool failed"
+		}
+		return map[string]string{"name": job.Name}, true, ""
+	}
+	return map[string]runner{ToolRetoc: run, ToolUAsset: run}
+}
+
+// gate returns the channel releasing the job named name. It is called with
+// mu held.
+func (f *fakeTools) gate(name string) chan struct{} {
+	if f.release == nil {
+		f.release = make(map[string]chan struct{})
+	}
+	if f.release[name] == nil {
+		f.release[name] = make(chan struct{})
+	}
+	return f.release[name]
+}
+
+func (f *fakeTools) finish(name string) {
+	f.mu.Lock()
+	close(f.gate(name))
+	f.mu.Unlock()
+}
+
+func (f *fakeTools) startedJobs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.started...)
+}
+
+// newTestQueue returns a started queue whose data directory is temporary.
+func newTestQueue(t *testing.T, tools *fakeTools) (*JobQueue, *app.App) {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	a := app.NewApp()
+	if err := a.LoadConfiguration(); err != nil {
+		t.Fatalf("LoadConfiguration failed: %v", err)
+	}
+	q := newJobQueue(a, tools.runners())
+	q.ServiceStartup(context.Background(), application.ServiceOptions{})
+	return q, a
+}
+
+func retocJob(name string, priority int, deps ...string) Job {
+	return Job{ID: name, Name: name, Priority: priority, DependsOn: deps, Retoc: &retoc.RetocOperation{Command: "unpack"}}
+}
+
+func uassetJob(name string) Job {
+	return Job{ID: name, Name: name, UAsset: &uasset.UAssetRequest{Command: "export"}}
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func jobState(q *JobQueue, id string) app.OperationState {
+	job, _ := q.Get(context.Background(), id)
+	return job.State
+}
+
+func TestJobQueue_Concurrency_LimitsEachTool(t *testing.T) {
+	tools := &fakeTools{}
+	q, _ := newTestQueue(t, tools)
+	ctx := context.Background()
+
+	_, err := q.Submit(ctx, []Job{retocJob("r1", 0), retocJob("r2", 0), uassetJob("u1"), uassetJob("u2"), uassetJob("u3")})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// One retoc and two UAsset workers by default
+	waitFor(t, "three jobs to start", func() bool { return len(tools.startedJobs()) == 3 })
+	if jobState(q, "r2") != app.OperationQueued || jobState(q, "u3") != app.OperationQueued {
+		t.Errorf("Expected r2 and u3 to wait, started %v", tools.startedJobs())
+	}
+
+	tools.finish("r1")
+	waitFor(t, "r2 to start", func() bool { return jobState(q, "r2") == app.OperationRunning })
+	if jobState(q, "r1") != app.OperationSucceeded {
+		t.Errorf("Expected r1 to succeed, got %s", jobState(q, "r1"))
+	}
+
+	if err := q.SetConcurrency(ctx, ToolUAsset, 3); err != nil {
+		t.Fatalf("SetConcurrency failed: %v", err)
+	}
+	waitFor(t, "u3 to start", func() bool { return jobState(q, "u3") == app.OperationRunning })
+
+	for _, name := range []string{"r2", "u1", "u2", "u3"} {
+		tools.finish(name)
+	}
+	q.ServiceShutdown()
+}
+
+func TestJobQueue_Priority_HighestRunsFirst(t *testing.T) {
+	tools := &fakeTools{}
+	q, _ := newTestQueue(t, tools)
+	ctx := context.Background()
+
+	q.Pause(ctx)
+	q.Submit(ctx, []Job{retocJob("low", 0), retocJob("high", 10), retocJob("mid", 5)})
+	if got := tools.startedJobs(); len(got) != 0 {
+		t.Fatalf("Expected nothing to start while paused, started %v", got)
+	}
+
+	q.Resume(ctx)
+	for _, name := range []string{"high", "mid", "low"} {
+		waitFor(t, name+" to start", func() bool { return jobState(q, name) == app.OperationRunning })
+		tools.finish(name)
+	}
+	waitFor(t, "low to finish", func() bool { return jobState(q, "low") == app.OperationSucceeded })
+
+	got := tools.startedJobs()
+	if len(got) != 3 || got[0] != "high" || got[1] != "mid" || got[2] != "low" {
+		t.Errorf("Expected high, mid, low, got %v", got)
+	}
+}
+
+func TestJobQueue_Dependencies_WaitAndFailTogether(t *testing.T) {
+	tools := &fakeTools{}
+	q, _ := newTestQueue(t, tools)
+	ctx := context.Background()
+
+	q.Submit(ctx, []Job{
+		uassetJob("export"),
+		retocJob("pack", 100, "export"),
+		uassetJob("fail"),
+		retocJob("after-fail", 0, "fail"),
+	})
+	waitFor(t, "fail to start", func() bool { return jobState(q, "fail") == app.OperationRunning })
+	if jobState(q, "pack") != app.OperationQueued {
+		t.Errorf("Expected pack to wait for export, got %s", jobState(q, "pack"))
+	}
+
+	tools.finish("fail")
+	waitFor(t, "after-fail to fail", func() bool { return jobState(q, "after-fail") == app.OperationFailed })
+	if job, _ := q.Get(ctx, "after-fail"); job.Error == "" {
+		t.Error("Expected the dependent job to record why it failed")
+	}
+
+	tools.finish("export")
+	waitFor(t, "pack to start", func() bool { return jobState(q, "pack") == app.OperationRunning })
+	tools.finish("pack")
+	waitFor(t, "pack to finish", func() bool { return jobState(q, "pack") == app.OperationSucceeded })
+}
+
+func TestJobQueue_Submit_RejectsInvalidBatch(t *testing.T) {
+	q, _ := newTestQueue(t, &fakeTools{})
+	ctx := context.Background()
+	q.Pause(ctx)
+
+	tests := map[string][]Job{
+		"unknown dependency": {retocJob("a", 0, "missing")},
+		"forward dependency": {retocJob("a", 0, "b"), retocJob("b", 0)},
+		"duplicate ID":       {retocJob("a", 0), retocJob("a", 0)},
+		"nothing to run":     {{ID: "a"}},
+		"wrong tool":         {{ID: "a", Tool: ToolUAsset, Retoc: &retoc.RetocOperation{}}},
+	}
+	for name, batch := range tests {
+		if _, err := q.Submit(ctx, batch); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if jobs := q.List(ctx); len(jobs) != 0 {
+		t.Errorf("Expected no jobs queued from invalid batches, got %d", len(jobs))
+	}
+}
+
+func TestJobQueue_Cancel_QueuedAndRunning(t *testing.T) {
+	tools := &fakeTools{}
+	q, _ := newTestQueue(t, tools)
+	ctx := context.Background()
+
+	q.Submit(ctx, []Job{retocJob("running", 0), retocJob("queued", 0)})
+	waitFor(t, "running to start", func() bool { return jobState(q, "running") == app.OperationRunning })
+
+	if err := q.Cancel(ctx, "queued"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := q.Cancel(ctx, "running"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	waitFor(t, "running to be cancelled", func() bool { return jobState(q, "running") == app.OperationCancelled })
+	if jobState(q, "queued") != app.OperationCancelled {
+		t.Errorf("Expected queued to be cancelled, got %s", jobState(q, "queued"))
+	}
+	if got := tools.startedJobs(); len(got) != 1 {
+		t.Errorf("Expected only one job to start, started %v", got)
+	}
+	if n := q.ClearFinished(ctx); n != 2 {
+		t.Errorf("Expected 2 finished jobs cleared, got %d", n)
+	}
+}
+
+func TestJobQueue_Restart_RestoresQueuedAndInterruptedJobs(t *testing.T) {
+	tools := &fakeTools{}
+	q, a := newTestQueue(t, tools)
+	ctx := context.Background()
+
+	q.Submit(ctx, []Job{retocJob("interrupted", 0), retocJob("waiting", 0, "interrupted")})
+	waitFor(t, "interrupted to start", func() bool { return jobState(q, "interrupted") == app.OperationRunning })
+	q.Pause(ctx)
+	if err := q.ServiceShutdown(); err != nil {
+		t.Fatalf("ServiceShutdown failed: %v", err)
+	}
+
+	restarted := &fakeTools{}
+	q2 := newJobQueue(a, restarted.runners())
+	q2.ServiceStartup(ctx, application.ServiceOptions{})
+	if !q2.IsPaused(ctx) {
+		t.Error("Expected the paused state to be restored")
+	}
+	jobs := q2.List(ctx)
+	if len(jobs) != 2 || jobs[0].ID != "interrupted" || jobs[1].ID != "waiting" {
+		t.Fatalf("Expected both jobs in order, got %+v", jobs)
+	}
+	for _, job := range jobs {
+		if job.State != app.OperationQueued {
+			t.Errorf("Expected %s to be queued, got %s", job.ID, job.State)
+		}
+	}
+
+	q2.Resume(ctx)
+	waitFor(t, "interrupted to run again", func() bool { return jobState(q2, "interrupted") == app.OperationRunning })
+	restarted.finish("interrupted")
+	restarted.finish("waiting")
+	waitFor(t, "waiting to finish", func() bool { return jobState(q2, "waiting") == app.OperationSucceeded })
+}
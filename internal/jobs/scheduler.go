@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+// schedule starts as many runnable jobs as the tools' limits allow,
+// highest priority first. Queued jobs whose dependencies failed are
+// finished as well. It is called with mu held whenever a job is submitted
+// or finishes and whenever the queue or its limits change.
+func (q *JobQueue) schedule() {
+	if !q.started {
+		return
+	}
+	for {
+		next := q.next()
+		if next == nil {
+			return
+		}
+		q.start(next)
+	}
+}
+
+// next returns the queued job to start next, or nil if none can start.
+func (q *JobQueue) next() *Job {
+	var best *Job
+	for _, id := range q.order {
+		job := q.jobs[id]
+		if job.State != app.OperationQueued {
+			continue
+		}
+		if ready, failed := q.dependencies(job); failed != "" {
+			q.finish(job, app.OperationFailed, failed)
+			// The failure may cascade to jobs already passed over
+			return q.next()
+		} else if !ready {
+			continue
+		}
+		if q.paused || q.running[job.Tool] >= q.workers(job.Tool) {
+			continue
+		}
+		if best == nil || job.Priority > best.Priority {
+			best = job
+		}
+	}
+	return best
+}
+
+// dependencies reports whether all of job's dependencies have succeeded
+// and, if one of them will not, why job cannot run.
+func (q *JobQueue) dependencies(job *Job) (ready bool, failed string) {
+	ready = true
+	for _, id := range job.DependsOn {
+		dep, ok := q.jobs[id]
+		switch {
+		case !ok:
+			return false, fmt.Sprintf("dependency %s no longer exists", id)
+		case dep.State == app.OperationSucceeded:
+		case dep.State.Finished():
+			return false, fmt.Sprintf("dependency %s %s", dependencyName(dep), dep.State)
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+func dependencyName(job *Job) string {
+	if job.Name != "" {
+		return fmt.Sprintf("%q", job.Name)
+	}
+	return job.ID
+}
+
+// start runs job in the background. It is called with mu held.
+func (q *JobQueue) start(job *Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job.State = app.OperationRunning
+	job.StartedAt = time.Now()
+	q.running[job.Tool]++
+	q.cancels[job.ID] = cancel
+	q.touch(job)
+
+	q.wg.Add(1)
+	go func(job Job) {
+		defer q.wg.Done()
+		defer cancel()
+		result, success, errMessage := q.runners[job.Tool](ctx, job)
+		data, _ := json.Marshal(result)
+
+		q.update(func() {
+			q.running[job.Tool]--
+			delete(q.cancels, job.ID)
+			stored, ok := q.jobs[job.ID]
+			if !ok {
+				return
+			}
+			stored.Result = data
+			switch {
+			case !q.started:
+				// Stopped by ServiceShutdown; run again on the next start
+				stored.State = app.OperationQueued
+				stored.StartedAt = time.Time{}
+				q.touch(stored)
+			case success:
+				q.finish(stored, app.OperationSucceeded, "")
+			case ctx.Err() != nil:
+				q.finish(stored, app.OperationCancelled, "")
+			default:
+				if errMessage == "" {
+					errMessage = "job failed"
+				}
+				q.finish(stored, app.OperationFailed, errMessage)
+			}
+			q.schedule()
+		})
+	}(*job)
+}
+
+// finish moves job to a final state and drops the oldest finished jobs
+// beyond maxFinishedJobs. It is called with mu held.
+func (q *JobQueue) finish(job *Job, state app.OperationState, errMessage string) {
+	job.State = state
+	job.Error = errMessage
+	job.EndedAt = time.Now()
+	q.touch(job)
+	q.prune(maxFinishedJobs)
+}
+
+// prune removes the oldest finished jobs that no unfinished job depends
+// on, keeping keep of them, and returns how many it removed. It is called
+// with mu held.
+func (q *JobQueue) prune(keep int) int {
+	needed := make(map[string]bool)
+	finished := 0
+	for _, id := range q.order {
+		job := q.jobs[id]
+		if job.State.Finished() {
+			finished++
+			continue
+		}
+		for _, dep := range job.DependsOn {
+			needed[dep] = true
+		}
+	}
+
+	removed := 0
+	order := q.order[:0]
+	for _, id := range q.order {
+		job := q.jobs[id]
+		if finished-removed > keep && job.State.Finished() && !needed[id] {
+			delete(q.jobs, id)
+			removed++
+			continue
+		}
+		order = append(order, id)
+	}
+	q.order = order
+	if removed > 0 {
+		q.dirty = true
+	}
+	return removed
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// QueueFileName is the file in the data directory the queue is saved to.
+const QueueFileName = "jobs.json"
+
+// queueState is the saved form of the queue.
+type queueState struct {
+	Paused bool  `json:"paused"`
+	Jobs   []Job `json:"jobs"` // In submission order
+}
+
+func (q *JobQueue) queuePath() string {
+	return filepath.Join(q.app.DataDir(), QueueFileName)
+}
+
+// load reads the saved queue. A missing file is an empty queue.
+func (q *JobQueue) load() (queueState, error) {
+	var state queueState
+	data, err := os.ReadFile(q.queuePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return queueState{}, fmt.Errorf("failed to parse %s: %v", QueueFileName, err)
+	}
+	return state, nil
+}
+
+// save writes the queue. It is called with mu held.
+func (q *JobQueue) save() error {
+	state := queueState{Paused: q.paused, Jobs: make([]Job, 0, len(q.order))}
+	for _, id := range q.order {
+		state.Jobs = append(state.Jobs, *q.jobs[id])
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(q.queuePath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(q.queuePath(), data, 0644)
+}
@@ -0,0 +1,191 @@
+package mods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// ConflictReport lists the packages provided by more than one mod in a
+// ~mods directory and which mod's copy the engine loads.
+type ConflictReport struct {
+	ModsDir     string       `json:"mods_dir"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Mods        []Mod        `json:"mods"`      // All mods, in load order
+	Conflicts   []Conflict   `json:"conflicts"` // Sorted by package path
+	Skipped     []SkippedMod `json:"skipped"`   // Mods whose contents could not be read
+}
+
+// Conflict is one package path provided by several mods. Mods lists their
+// base names in load order; the last one, Winner, overrides the others.
+type Conflict struct {
+	PackagePath string   `json:"package_path"`
+	Mods        []string `json:"mods"`
+	Winner      string   `json:"winner"`
+}
+
+// SkippedMod is a mod left out of a conflict report, with the reason.
+type SkippedMod struct {
+	BaseName string `json:"base_name"`
+	Reason   string `json:"reason"`
+}
+
+// GetConflicts reads the package list of every mod in the target's ~mods
+// directory and reports the packages that more than one mod provides. The
+// winner of each conflict follows the effective load order (see PakOrder).
+// If the target names a game profile, its unlocked AES keys are tried on
+// encrypted containers.
+func (s *LoadOrderService) GetConflicts(ctx context.Context, target Target) (ConflictReport, error) {
+	dir, err := resolveModsDir(s.app, target, false)
+	if err != nil {
+		return ConflictReport{}, err
+	}
+	mods, err := ScanMods(dir)
+	if err != nil {
+		return ConflictReport{}, err
+	}
+
+	var keys [][]byte
+	if target.ProfileID != "" {
+		if gameKeys, err := s.app.GameKeys(target.ProfileID); err == nil {
+			for _, k := range gameKeys {
+				if key, err := config.ParseAESKey(k.Key); err == nil {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	report := ConflictReport{ModsDir: dir, GeneratedAt: time.Now(), Mods: mods, Conflicts: []Conflict{}, Skipped: []SkippedMod{}}
+	providers := make(map[string][]string) // package path -> base names in load order
+	for _, m := range mods {
+		if err := ctx.Err(); err != nil {
+			return ConflictReport{}, err
+		}
+		packages, err := modPackages(m, keys)
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedMod{BaseName: m.BaseName, Reason: err.Error()})
+			continue
+		}
+		for _, p := range packages {
+			providers[p] = append(providers[p], m.BaseName)
+		}
+	}
+
+	for path, bases := range providers {
+		if len(bases) > 1 {
+			report.Conflicts = append(report.Conflicts, Conflict{
+				PackagePath: path,
+				Mods:        bases,
+				Winner:      bases[len(bases)-1],
+			})
+		}
+	}
+	sort.Slice(report.Conflicts, func(i, j int) bool {
+		return report.Conflicts[i].PackagePath < report.Conflicts[j].PackagePath
+	})
+	return report, nil
+}
+
+// ExportConflictReport writes report to path as "json" or "markdown".
+func (s *LoadOrderService) ExportConflictReport(ctx context.Context, report ConflictReport, format, path string) error {
+	var data []byte
+	switch strings.ToLower(format) {
+	case "json":
+		var err error
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			return err
+		}
+	case "markdown", "md":
+		data = []byte(report.Markdown())
+	default:
+		return fmt.Errorf("unsupported report format %q; use json or markdown", format)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Markdown renders the report as a Markdown document with the load order
+// and one table row per conflict.
+func (r ConflictReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Mod conflicts\n\n")
+	fmt.Fprintf(&b, "Directory: `%s`  \nGenerated: %s\n\n", r.ModsDir, r.GeneratedAt.Format(time.RFC3339))
+
+	fmt.Fprintf(&b, "## Load order\n\n| # | Mod | Priority |\n|---|-----|----------|\n")
+	for _, m := range r.Mods {
+		fmt.Fprintf(&b, "| %d | %s | %d |\n", m.Position, escapeMarkdown(m.BaseName), m.PakOrder)
+	}
+
+	fmt.Fprintf(&b, "\n## Conflicts (%d)\n\n", len(r.Conflicts))
+	if len(r.Conflicts) == 0 {
+		b.WriteString("No conflicts.\n")
+	} else {
+		b.WriteString("| Package | Winner | Overridden |\n|---------|--------|------------|\n")
+		for _, c := range r.Conflicts {
+			losers := make([]string, len(c.Mods)-1)
+			for i, m := range c.Mods[:len(c.Mods)-1] {
+				losers[i] = escapeMarkdown(m)
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", c.PackagePath, escapeMarkdown(c.Winner), strings.Join(losers, ", "))
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		b.WriteString("\n## Skipped\n\n")
+		for _, sk := range r.Skipped {
+			fmt.Fprintf(&b, "- %s: %s\n", escapeMarkdown(sk.BaseName), sk.Reason)
+		}
+	}
+	return b.String()
+}
+
+// modPackages returns the distinct package paths in m's .utoc, decrypting
+// the directory index with the first of keys that fits if needed.
+func modPackages(m Mod, keys [][]byte) ([]string, error) {
+	utoc := ""
+	for _, f := range m.Files {
+		if strings.EqualFold(filepath.Ext(f), ".utoc") {
+			utoc = f
+		}
+	}
+	if utoc == "" {
+		return nil, fmt.Errorf("no .utoc file; legacy .pak contents are not read")
+	}
+
+	toc, err := iostore.ReadToc(utoc)
+	if err != nil {
+		return nil, err
+	}
+	if toc.IsEncrypted() && toc.DirectoryIndex == nil {
+		for _, key := range keys {
+			if toc.DecryptDirectoryIndex(key) == nil {
+				break
+			}
+		}
+		if toc.DirectoryIndex == nil {
+			return nil, fmt.Errorf("container is encrypted and no unlocked key fits")
+		}
+	}
+
+	seen := make(map[string]bool)
+	var packages []string
+	for _, e := range toc.Entries() {
+		if e.PackagePath != "" && !seen[e.PackagePath] {
+			seen[e.PackagePath] = true
+			packages = append(packages, e.PackagePath)
+		}
+	}
+	return packages, nil
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "_", `\_`, "*", `\*`, "~", `\~`).Replace(s)
+}
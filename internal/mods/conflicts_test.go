@@ -0,0 +1,130 @@
+package mods
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+// writeModContainer writes baseName.utoc/.ucas/.pak into modsDir with one
+// package per path, e.g. "MyGame/Content/Data/DT_Items".
+func writeModContainer(t *testing.T, modsDir, baseName string, opts iostoretest.Options, packages ...string) {
+	t.Helper()
+	var files []iostoretest.File
+	for _, p := range packages {
+		files = append(files,
+			iostoretest.File{Path: p + ".uasset", Data: []byte(baseName)},
+			iostoretest.File{Path: p + ".uexp", Data: []byte(baseName)},
+		)
+	}
+	if _, err := iostoretest.WriteContainer(modsDir, baseName, files, opts); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+	os.WriteFile(filepath.Join(modsDir, baseName+".pak"), []byte("pak"), 0644)
+}
+
+func TestConflicts_OverlappingPackages_LastInLoadOrderWins(t *testing.T) {
+	paks := writeMods(t)
+	modsDir := filepath.Join(paks, ModsDirName)
+	writeModContainer(t, modsDir, "z_Late_0009_P", iostoretest.Options{}, "MyGame/Content/Data/DT_Items", "MyGame/Content/Data/DT_Late")
+	writeModContainer(t, modsDir, "z_Early_0002_P", iostoretest.Options{}, "MyGame/Content/Data/DT_Items", "MyGame/Content/UI/W_Menu")
+	writeModContainer(t, modsDir, "z_NoPatch_0050", iostoretest.Options{}, "MyGame/Content/Data/DT_Items", "MyGame/Content/UI/W_Menu")
+	os.WriteFile(filepath.Join(modsDir, "z_Legacy_0001_P.pak"), []byte("pak"), 0644)
+
+	report, err := NewLoadOrderService(app.NewApp()).GetConflicts(context.Background(), Target{PaksDir: paks})
+	if err != nil {
+		t.Fatalf("GetConflicts failed: %v", err)
+	}
+
+	want := []Conflict{
+		{PackagePath: "/Game/Data/DT_Items", Mods: []string{"z_NoPatch_0050", "z_Early_0002_P", "z_Late_0009_P"}, Winner: "z_Late_0009_P"},
+		{PackagePath: "/Game/UI/W_Menu", Mods: []string{"z_NoPatch_0050", "z_Early_0002_P"}, Winner: "z_Early_0002_P"},
+	}
+	if !reflect.DeepEqual(report.Conflicts, want) {
+		t.Errorf("Expected conflicts %+v, got %+v", want, report.Conflicts)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].BaseName != "z_Legacy_0001_P" {
+		t.Errorf("Expected the .pak-only mod to be skipped, got %+v", report.Skipped)
+	}
+}
+
+func TestConflicts_EncryptedMod_UsesUnlockedGameKeys(t *testing.T) {
+	a := newConfiguredApp(t)
+	key := bytes.Repeat([]byte{0x42}, 32)
+	paks := writeMods(t)
+	modsDir := filepath.Join(paks, ModsDirName)
+	writeModContainer(t, modsDir, "z_Secret_0002_P", iostoretest.Options{AESKey: key}, "MyGame/Content/Data/DT_Items")
+	writeModContainer(t, modsDir, "z_Open_0001_P", iostoretest.Options{}, "MyGame/Content/Data/DT_Items")
+	service := NewLoadOrderService(a)
+	profile, err := a.SaveProfile(config.GameProfile{Name: "My Game", PaksDir: paks})
+	if err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	target := Target{ProfileID: profile.ID}
+
+	report, err := service.GetConflicts(context.Background(), target)
+	if err != nil {
+		t.Fatalf("GetConflicts failed: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Conflicts) != 0 {
+		t.Errorf("Expected the encrypted mod to be skipped while locked, got %+v", report)
+	}
+
+	if err := a.SaveGameKeys(profile.ID, []config.AESKey{{Key: "0x" + strings.Repeat("42", 32)}}, "hunter2"); err != nil {
+		t.Fatalf("SaveGameKeys failed: %v", err)
+	}
+	report, err = service.GetConflicts(context.Background(), target)
+	if err != nil {
+		t.Fatalf("GetConflicts failed: %v", err)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Winner != "z_Secret_0002_P" {
+		t.Errorf("Expected the decrypted mod to win, got %+v", report.Conflicts)
+	}
+}
+
+func TestConflicts_Export_WritesJSONAndMarkdown(t *testing.T) {
+	paks := writeMods(t)
+	modsDir := filepath.Join(paks, ModsDirName)
+	writeModContainer(t, modsDir, "z_A_0001_P", iostoretest.Options{}, "MyGame/Content/Data/DT_Items")
+	writeModContainer(t, modsDir, "z_B_0002_P", iostoretest.Options{}, "MyGame/Content/Data/DT_Items")
+	service := NewLoadOrderService(app.NewApp())
+	ctx := context.Background()
+
+	report, err := service.GetConflicts(ctx, Target{PaksDir: paks})
+	if err != nil {
+		t.Fatalf("GetConflicts failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	jsonPath := filepath.Join(outDir, "conflicts.json")
+	if err := service.ExportConflictReport(ctx, report, "json", jsonPath); err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var decoded ConflictReport
+	data, _ := os.ReadFile(jsonPath)
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded.Conflicts, report.Conflicts) {
+		t.Errorf("JSON export did not round-trip: %v", err)
+	}
+
+	mdPath := filepath.Join(outDir, "conflicts.md")
+	if err := service.ExportConflictReport(ctx, report, "markdown", mdPath); err != nil {
+		t.Fatalf("Markdown export failed: %v", err)
+	}
+	md, _ := os.ReadFile(mdPath)
+	if !strings.Contains(string(md), "| `/Game/Data/DT_Items` | z\\_B\\_0002\\_P | z\\_A\\_0001\\_P |") {
+		t.Errorf("Unexpected Markdown:\n%s", md)
+	}
+
+	if err := service.ExportConflictReport(ctx, report, "xml", mdPath); err == nil {
+		t.Error("Expected unsupported format to fail")
+	}
+}
@@ -0,0 +1,518 @@
+package mods
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/google/uuid"
+)
+
+// ManifestFileName is the install manifest ModInstaller keeps in each ~mods
+// directory it installs into. The engine ignores it.
+const ManifestFileName = ".ari-s-manifest.json"
+
+// OperationOwner is the owner of the operations ModInstaller registers with
+// the App.
+const OperationOwner = "mods"
+
+// ErrNotOwned is returned when an install would overwrite a file in ~mods
+// that ModInstaller did not install, or that belongs to another mod.
+var ErrNotOwned = errors.New("file not owned by this mod")
+
+// ErrModified is returned when an installed file has changed since it was
+// installed and the caller did not force the operation.
+var ErrModified = errors.New("installed file was modified")
+
+// ModInstaller copies packed mods into a game's Paks/~mods directory and
+// removes them again. Every install is recorded in a manifest in the ~mods
+// directory with file hashes and the source project. Installing a mod that
+// is already installed keeps the previous version's files in the app data
+// directory so it can be rolled back.
+//
+// Installs, uninstalls and rollbacks are registered with the App's operation
+// registry as owner [OperationOwner].
+//
+// ModInstaller is safe for concurrent use by multiple goroutines.
+type ModInstaller struct {
+	app *app.App
+}
+
+// InstallRequest describes a packed mod to install.
+type InstallRequest struct {
+	Target        Target   `json:"target"`
+	Files         []string `json:"files"`          // Packed files, e.g. from OutputSet
+	ModName       string   `json:"mod_name"`       // Identifies the mod across versions; defaults to the name parsed from the files
+	SourceProject string   `json:"source_project"` // Mod project folder the files were packed from
+}
+
+// InstalledFile is one file of an installed mod.
+type InstalledFile struct {
+	Name   string `json:"name"` // File name in ~mods
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// InstalledMod is a manifest entry. Previous is the version it replaced,
+// whose files are kept for Rollback.
+type InstalledMod struct {
+	ID            string          `json:"id"`
+	ModName       string          `json:"mod_name"`
+	SourceProject string          `json:"source_project"`
+	InstalledAt   time.Time       `json:"installed_at"`
+	Files         []InstalledFile `json:"files"`
+	Previous      *InstalledMod   `json:"previous,omitempty"`
+}
+
+// InstallManifest lists the mods ModInstaller installed in a ~mods directory.
+type InstallManifest struct {
+	Mods []InstalledMod `json:"mods"`
+}
+
+// NewModInstaller creates a new ModInstaller. Backups of replaced versions
+// are stored under the app's data directory.
+func NewModInstaller(a *app.App) *ModInstaller {
+	return &ModInstaller{app: a}
+}
+
+// OutputSet returns the packed files named baseName in dir (.pak, .utoc,
+// .ucas and .sig, whichever exist), as written by a to-zen operation.
+func OutputSet(dir, baseName string) ([]string, error) {
+	var files []string
+	for _, ext := range modExtensions {
+		path := filepath.Join(dir, baseName+ext)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no packed files named %s in %s", baseName, dir)
+	}
+	return files, nil
+}
+
+// ListInstalled returns the mods recorded in the target's manifest.
+func (i *ModInstaller) ListInstalled(ctx context.Context, target Target) ([]InstalledMod, error) {
+	modsDir, err := resolveModsDir(i.app, target, false)
+	if err != nil {
+		return nil, err
+	}
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+	manifest, err := readManifest(modsDir)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Mods, nil
+}
+
+// Install copies the request's files into the target's ~mods directory. If
+// a mod with the same name is installed, its files are replaced and kept as
+// the previous version. Files in ~mods that belong to no installed mod, or
+// to a different one, are never overwritten; ErrNotOwned is returned.
+func (i *ModInstaller) Install(ctx context.Context, req InstallRequest) (InstalledMod, error) {
+	op := i.app.StartOperation(ctx, OperationOwner, "install", installDescription(req))
+	installed, err := i.install(op.Context(), req)
+	op.Finish(err)
+	return installed, err
+}
+
+func (i *ModInstaller) install(ctx context.Context, req InstallRequest) (InstalledMod, error) {
+	if len(req.Files) == 0 {
+		return InstalledMod{}, fmt.Errorf("no files to install")
+	}
+	for _, f := range req.Files {
+		if !isModExtension(strings.ToLower(filepath.Ext(f))) {
+			return InstalledMod{}, fmt.Errorf("not a packed mod file: %s", f)
+		}
+	}
+	modName := req.ModName
+	if modName == "" {
+		base := filepath.Base(req.Files[0])
+		modName = parseModName(strings.TrimSuffix(base, filepath.Ext(base))).Name
+	}
+
+	modsDir, err := resolveModsDir(i.app, req.Target, true)
+	if err != nil {
+		return InstalledMod{}, err
+	}
+
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+
+	manifest, err := readManifest(modsDir)
+	if err != nil {
+		return InstalledMod{}, err
+	}
+	existing := manifest.find(modName)
+
+	entry := InstalledMod{
+		ID:            uuid.New().String(),
+		ModName:       modName,
+		SourceProject: req.SourceProject,
+		InstalledAt:   time.Now(),
+	}
+	for _, f := range req.Files {
+		name := filepath.Base(f)
+		if owner := manifest.owner(name); owner != nil && owner != existing {
+			return InstalledMod{}, fmt.Errorf("%w: %s belongs to %s", ErrNotOwned, name, owner.ModName)
+		} else if owner == nil {
+			if _, err := os.Stat(filepath.Join(modsDir, name)); err == nil {
+				return InstalledMod{}, fmt.Errorf("%w: %s was not installed by ARI-S", ErrNotOwned, name)
+			}
+		}
+		sum, size, err := hashFile(f)
+		if err != nil {
+			return InstalledMod{}, err
+		}
+		entry.Files = append(entry.Files, InstalledFile{Name: name, SHA256: sum, Size: size})
+	}
+
+	// Move the installed version aside before copying, so a failed copy can
+	// put it back.
+	if existing != nil {
+		if err := i.backup(modsDir, existing); err != nil {
+			return InstalledMod{}, err
+		}
+	}
+	for n, f := range req.Files {
+		if err := copyFile(f, filepath.Join(modsDir, entry.Files[n].Name)); err != nil {
+			for _, done := range entry.Files[:n] {
+				os.Remove(filepath.Join(modsDir, done.Name))
+			}
+			if existing != nil {
+				i.restore(modsDir, existing)
+			}
+			return InstalledMod{}, fmt.Errorf("failed to install %s: %v", entry.Files[n].Name, err)
+		}
+	}
+
+	if existing != nil {
+		if existing.Previous != nil {
+			os.RemoveAll(i.backupDir(existing.Previous))
+		}
+		previous := *existing
+		previous.Previous = nil
+		entry.Previous = &previous
+		*existing = entry
+	} else {
+		manifest.Mods = append(manifest.Mods, entry)
+	}
+	if err := writeManifest(modsDir, manifest); err != nil {
+		return entry, fmt.Errorf("mod installed but manifest not written: %v", err)
+	}
+	return entry, nil
+}
+
+// Uninstall removes the files of the mod named modName from the target's
+// ~mods directory, along with any kept previous version. Files changed since
+// they were installed are left alone and ErrModified is returned, unless
+// force is set.
+func (i *ModInstaller) Uninstall(ctx context.Context, target Target, modName string, force bool) error {
+	op := i.app.StartOperation(ctx, OperationOwner, "uninstall", modName)
+	err := i.uninstall(op.Context(), target, modName, force)
+	op.Finish(err)
+	return err
+}
+
+func (i *ModInstaller) uninstall(ctx context.Context, target Target, modName string, force bool) error {
+	modsDir, err := resolveModsDir(i.app, target, false)
+	if err != nil {
+		return err
+	}
+
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+
+	manifest, err := readManifest(modsDir)
+	if err != nil {
+		return err
+	}
+	entry := manifest.find(modName)
+	if entry == nil {
+		return fmt.Errorf("mod not installed: %s", modName)
+	}
+	if !force {
+		if err := checkUnmodified(modsDir, entry); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range entry.Files {
+		if err := os.Remove(filepath.Join(modsDir, f.Name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", f.Name, err)
+		}
+	}
+	if entry.Previous != nil {
+		os.RemoveAll(i.backupDir(entry.Previous))
+	}
+
+	for n := range manifest.Mods {
+		if &manifest.Mods[n] == entry {
+			manifest.Mods = append(manifest.Mods[:n], manifest.Mods[n+1:]...)
+			break
+		}
+	}
+	return writeManifest(modsDir, manifest)
+}
+
+// Rollback replaces the installed version of the mod named modName with the
+// version it replaced. Like Uninstall, it refuses to remove files changed
+// since they were installed unless force is set.
+func (i *ModInstaller) Rollback(ctx context.Context, target Target, modName string, force bool) (InstalledMod, error) {
+	op := i.app.StartOperation(ctx, OperationOwner, "rollback", modName)
+	installed, err := i.rollback(op.Context(), target, modName, force)
+	op.Finish(err)
+	return installed, err
+}
+
+func (i *ModInstaller) rollback(ctx context.Context, target Target, modName string, force bool) (InstalledMod, error) {
+	modsDir, err := resolveModsDir(i.app, target, false)
+	if err != nil {
+		return InstalledMod{}, err
+	}
+
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+
+	manifest, err := readManifest(modsDir)
+	if err != nil {
+		return InstalledMod{}, err
+	}
+	entry := manifest.find(modName)
+	if entry == nil {
+		return InstalledMod{}, fmt.Errorf("mod not installed: %s", modName)
+	}
+	if entry.Previous == nil {
+		return InstalledMod{}, fmt.Errorf("no previous version of %s to roll back to", modName)
+	}
+	if !force {
+		if err := checkUnmodified(modsDir, entry); err != nil {
+			return InstalledMod{}, err
+		}
+	}
+
+	// Refuse before touching anything if a previous file's name is now
+	// taken by a file this mod does not own.
+	for _, f := range entry.Previous.Files {
+		if owner := manifest.owner(f.Name); owner != nil && owner != entry {
+			return InstalledMod{}, fmt.Errorf("%w: %s belongs to %s", ErrNotOwned, f.Name, owner.ModName)
+		} else if owner == nil {
+			if _, err := os.Stat(filepath.Join(modsDir, f.Name)); err == nil {
+				return InstalledMod{}, fmt.Errorf("%w: %s was not installed by ARI-S", ErrNotOwned, f.Name)
+			}
+		}
+	}
+
+	for _, f := range entry.Files {
+		if err := os.Remove(filepath.Join(modsDir, f.Name)); err != nil && !os.IsNotExist(err) {
+			return InstalledMod{}, fmt.Errorf("failed to remove %s: %v", f.Name, err)
+		}
+	}
+	if err := i.restore(modsDir, entry.Previous); err != nil {
+		return InstalledMod{}, err
+	}
+
+	*entry = *entry.Previous
+	if err := writeManifest(modsDir, manifest); err != nil {
+		return *entry, fmt.Errorf("mod rolled back but manifest not written: %v", err)
+	}
+	return *entry, nil
+}
+
+// installDescription names what req installs, for the operation list.
+func installDescription(req InstallRequest) string {
+	if req.ModName != "" || len(req.Files) == 0 {
+		return req.ModName
+	}
+	return filepath.Base(req.Files[0])
+}
+
+// backupDir is where the files of a replaced version are kept. It is
+// outside the game directory so the engine never mounts them.
+func (i *ModInstaller) backupDir(mod *InstalledMod) string {
+	return filepath.Join(i.app.DataDir(), "mod-backups", mod.ID)
+}
+
+// backup moves mod's files from modsDir into its backup directory.
+func (i *ModInstaller) backup(modsDir string, mod *InstalledMod) error {
+	dir := i.backupDir(mod)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+	for n, f := range mod.Files {
+		if err := moveFile(filepath.Join(modsDir, f.Name), filepath.Join(dir, f.Name)); err != nil {
+			for _, done := range mod.Files[:n] {
+				moveFile(filepath.Join(dir, done.Name), filepath.Join(modsDir, done.Name))
+			}
+			return fmt.Errorf("failed to back up %s: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// restore moves mod's files from its backup directory back into modsDir and
+// removes the backup directory.
+func (i *ModInstaller) restore(modsDir string, mod *InstalledMod) error {
+	dir := i.backupDir(mod)
+	for _, f := range mod.Files {
+		if err := moveFile(filepath.Join(dir, f.Name), filepath.Join(modsDir, f.Name)); err != nil {
+			return fmt.Errorf("failed to restore %s: %v", f.Name, err)
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// find returns the entry for modName, compared case-insensitively, or nil.
+func (m *InstallManifest) find(modName string) *InstalledMod {
+	for n := range m.Mods {
+		if strings.EqualFold(m.Mods[n].ModName, modName) {
+			return &m.Mods[n]
+		}
+	}
+	return nil
+}
+
+// owner returns the entry whose installed files include name, or nil.
+func (m *InstallManifest) owner(name string) *InstalledMod {
+	for n := range m.Mods {
+		for _, f := range m.Mods[n].Files {
+			if strings.EqualFold(f.Name, name) {
+				return &m.Mods[n]
+			}
+		}
+	}
+	return nil
+}
+
+// renameInManifest updates the installed file names in modsDir's manifest
+// after renames, so mods reordered by LoadOrderService stay owned. The
+// caller must hold modsDirMutex.
+func renameInManifest(modsDir string, renames []FileRename) error {
+	manifest, err := readManifest(modsDir)
+	if err != nil || len(manifest.Mods) == 0 {
+		return err
+	}
+	to := make(map[string]string, len(renames))
+	for _, r := range renames {
+		to[strings.ToLower(filepath.Base(r.From))] = filepath.Base(r.To)
+	}
+	changed := false
+	for n := range manifest.Mods {
+		for k, f := range manifest.Mods[n].Files {
+			if name, ok := to[strings.ToLower(f.Name)]; ok {
+				manifest.Mods[n].Files[k].Name = name
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeManifest(modsDir, manifest)
+}
+
+// checkUnmodified returns ErrModified if any of mod's files in modsDir no
+// longer match their recorded hash. Missing files are not an error.
+func checkUnmodified(modsDir string, mod *InstalledMod) error {
+	var changed []string
+	for _, f := range mod.Files {
+		sum, _, err := hashFile(filepath.Join(modsDir, f.Name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil || sum != f.SHA256 {
+			changed = append(changed, f.Name)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %s", ErrModified, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func readManifest(modsDir string) (*InstallManifest, error) {
+	manifest := &InstallManifest{}
+	data, err := os.ReadFile(filepath.Join(modsDir, ManifestFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse install manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+func writeManifest(modsDir string, manifest *InstallManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modsDir, ManifestFileName), data, 0644)
+}
+
+// hashFile returns the hex SHA-256 and size of the file at path.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// copyFile copies src to dst through a temporary file, so dst is never
+// left half-written.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".ari-s-tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// moveFile renames src to dst, falling back to copy and delete when they
+// are on different volumes.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
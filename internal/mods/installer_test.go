@@ -0,0 +1,197 @@
+package mods
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+// newConfiguredApp returns an App whose data directory, and so its config
+// and mod backups, live in a temporary directory.
+func newConfiguredApp(t *testing.T) *app.App {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	a := app.NewApp()
+	if err := a.LoadConfiguration(); err != nil {
+		t.Fatalf("LoadConfiguration failed: %v", err)
+	}
+	return a
+}
+
+// writeOutputSet writes a packed .pak/.utoc/.ucas set named baseName into
+// a new output directory, each file containing content, and returns the
+// file paths.
+func writeOutputSet(t *testing.T, baseName, content string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, ext := range []string{".pak", ".utoc", ".ucas"} {
+		if err := os.WriteFile(filepath.Join(dir, baseName+ext), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write output file: %v", err)
+		}
+	}
+	files, err := OutputSet(dir, baseName)
+	if err != nil {
+		t.Fatalf("OutputSet failed: %v", err)
+	}
+	return files
+}
+
+func TestModInstaller_Install_CopiesFilesAndRecordsManifest(t *testing.T) {
+	a := newConfiguredApp(t)
+	paks := filepath.Join(t.TempDir(), "Paks")
+	os.MkdirAll(paks, 0755)
+	profile, err := a.SaveProfile(config.GameProfile{Name: "My Game", PaksDir: paks})
+	if err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	installer := NewModInstaller(a)
+
+	entry, err := installer.Install(context.Background(), InstallRequest{
+		Target:        Target{ProfileID: profile.ID},
+		Files:         writeOutputSet(t, "z_AwesomeMod_0001_p", "v1"),
+		SourceProject: "C:/Mods/AwesomeMod",
+	})
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	if entry.ModName != "AwesomeMod" || len(entry.Files) != 3 || entry.Files[0].Size != 2 {
+		t.Errorf("Unexpected manifest entry: %+v", entry)
+	}
+	if data, _ := os.ReadFile(filepath.Join(paks, ModsDirName, "z_AwesomeMod_0001_p.pak")); string(data) != "v1" {
+		t.Errorf("Expected installed .pak, got %q", data)
+	}
+	installed, err := installer.ListInstalled(context.Background(), Target{ProfileID: profile.ID})
+	if err != nil || len(installed) != 1 || installed[0].SourceProject != "C:/Mods/AwesomeMod" {
+		t.Errorf("Unexpected installed list: %+v (%v)", installed, err)
+	}
+}
+
+func TestModInstaller_Install_RefusesUnownedFiles(t *testing.T) {
+	installer := NewModInstaller(newConfiguredApp(t))
+	paks := writeMods(t, "z_AwesomeMod_0001_p")
+	target := Target{PaksDir: paks}
+
+	_, err := installer.Install(context.Background(), InstallRequest{
+		Target: target,
+		Files:  writeOutputSet(t, "z_AwesomeMod_0001_p", "mine"),
+	})
+	if !errors.Is(err, ErrNotOwned) {
+		t.Fatalf("Expected ErrNotOwned, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(paks, ModsDirName, "z_AwesomeMod_0001_p.pak")); string(data) != "z_AwesomeMod_0001_p" {
+		t.Errorf("Expected the existing file to be untouched, got %q", data)
+	}
+
+	// A file owned by another mod is refused as well.
+	if _, err := installer.Install(context.Background(), InstallRequest{
+		Target: target,
+		Files:  writeOutputSet(t, "z_Other_0002_p", "other"),
+	}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	_, err = installer.Install(context.Background(), InstallRequest{
+		Target:  target,
+		Files:   writeOutputSet(t, "z_Other_0002_p", "mine"),
+		ModName: "Mine",
+	})
+	if !errors.Is(err, ErrNotOwned) {
+		t.Errorf("Expected ErrNotOwned for another mod's file, got %v", err)
+	}
+}
+
+func TestModInstaller_Reinstall_RollsBackToPreviousVersion(t *testing.T) {
+	installer := NewModInstaller(newConfiguredApp(t))
+	paks := writeMods(t)
+	target := Target{PaksDir: paks}
+	ctx := context.Background()
+	modsDir := filepath.Join(paks, ModsDirName)
+
+	if _, err := installer.Install(ctx, InstallRequest{Target: target, Files: writeOutputSet(t, "z_AwesomeMod_0001_p", "v1")}); err != nil {
+		t.Fatalf("Install v1 failed: %v", err)
+	}
+	entry, err := installer.Install(ctx, InstallRequest{Target: target, Files: writeOutputSet(t, "z_AwesomeMod_0002_p", "v2")})
+	if err != nil {
+		t.Fatalf("Install v2 failed: %v", err)
+	}
+	if entry.Previous == nil {
+		t.Fatal("Expected v1 to be kept as the previous version")
+	}
+	if _, err := os.Stat(filepath.Join(modsDir, "z_AwesomeMod_0001_p.pak")); !os.IsNotExist(err) {
+		t.Error("Expected v1 files to be moved out of ~mods")
+	}
+
+	rolledBack, err := installer.Rollback(ctx, target, "AwesomeMod", false)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if rolledBack.Previous != nil {
+		t.Error("Expected no previous version after rollback")
+	}
+	if data, _ := os.ReadFile(filepath.Join(modsDir, "z_AwesomeMod_0001_p.ucas")); string(data) != "v1" {
+		t.Errorf("Expected v1 restored, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(modsDir, "z_AwesomeMod_0002_p.ucas")); !os.IsNotExist(err) {
+		t.Error("Expected v2 files removed")
+	}
+	if _, err := installer.Rollback(ctx, target, "AwesomeMod", false); err == nil {
+		t.Error("Expected a second rollback to fail")
+	}
+}
+
+func TestModInstaller_Uninstall_RefusesModifiedFilesUnlessForced(t *testing.T) {
+	installer := NewModInstaller(newConfiguredApp(t))
+	paks := writeMods(t)
+	target := Target{PaksDir: paks}
+	ctx := context.Background()
+	modsDir := filepath.Join(paks, ModsDirName)
+
+	if _, err := installer.Install(ctx, InstallRequest{Target: target, Files: writeOutputSet(t, "z_AwesomeMod_0001_p", "v1")}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(modsDir, "z_AwesomeMod_0001_p.pak"), []byte("edited"), 0644)
+
+	if err := installer.Uninstall(ctx, target, "AwesomeMod", false); !errors.Is(err, ErrModified) {
+		t.Fatalf("Expected ErrModified, got %v", err)
+	}
+	if err := installer.Uninstall(ctx, target, "AwesomeMod", true); err != nil {
+		t.Fatalf("Forced uninstall failed: %v", err)
+	}
+
+	mods, _ := ScanMods(modsDir)
+	if len(mods) != 0 {
+		t.Errorf("Expected no mods left, got %v", baseNames(mods))
+	}
+	if installed, _ := installer.ListInstalled(ctx, target); len(installed) != 0 {
+		t.Errorf("Expected empty manifest, got %+v", installed)
+	}
+}
+
+func TestModInstaller_LoadOrderRename_KeepsOwnership(t *testing.T) {
+	a := newConfiguredApp(t)
+	installer := NewModInstaller(a)
+	paks := writeMods(t)
+	target := Target{PaksDir: paks}
+	ctx := context.Background()
+
+	if _, err := installer.Install(ctx, InstallRequest{Target: target, Files: writeOutputSet(t, "z_AwesomeMod_0001_p", "v1")}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if _, err := NewLoadOrderService(a).SetPriority(ctx, target, "z_AwesomeMod_0001_p", 5, false); err != nil {
+		t.Fatalf("SetPriority failed: %v", err)
+	}
+
+	if err := installer.Uninstall(ctx, target, "AwesomeMod", false); err != nil {
+		t.Fatalf("Uninstall after rename failed: %v", err)
+	}
+	if mods, _ := ScanMods(filepath.Join(paks, ModsDirName)); len(mods) != 0 {
+		t.Errorf("Expected renamed files to be uninstalled, got %v", baseNames(mods))
+	}
+}
@@ -0,0 +1,383 @@
+package mods
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/google/uuid"
+)
+
+// JournalFileName is the undo journal LoadOrderService keeps in each ~mods
+// directory it changes. The engine ignores it.
+const JournalFileName = ".ari-s-loadorder.json"
+
+// maxJournalEntries bounds the undo journal; the oldest entries are dropped.
+const maxJournalEntries = 50
+
+// maxSerial is the largest serial that fits the four-digit name field.
+const maxSerial = 9999
+
+// LoadOrderService shows and edits the load order of the packed mods in a
+// game's Paks/~mods directory. Every change renames all files of a mod
+// together and is recorded in an undo journal in the directory.
+//
+// LoadOrderService is safe for concurrent use by multiple goroutines.
+type LoadOrderService struct {
+	app *app.App
+}
+
+// FileRename is one file rename of a load-order change.
+type FileRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// LoadOrderPlan describes a load-order change: the file renames and the load
+// order before and after. With DryRun set nothing was renamed.
+type LoadOrderPlan struct {
+	ModsDir string       `json:"mods_dir"`
+	Renames []FileRename `json:"renames"`
+	Before  []Mod        `json:"before"`
+	After   []Mod        `json:"after"`
+	DryRun  bool         `json:"dry_run"`
+}
+
+// JournalEntry records one applied load-order change so it can be undone.
+type JournalEntry struct {
+	ID      string       `json:"id"`
+	Time    time.Time    `json:"time"`
+	Action  string       `json:"action"` // Human-readable summary
+	Renames []FileRename `json:"renames"`
+}
+
+// NewLoadOrderService creates a new LoadOrderService.
+func NewLoadOrderService(a *app.App) *LoadOrderService {
+	return &LoadOrderService{app: a}
+}
+
+// GetLoadOrder returns the mods in the target's ~mods directory in
+// effective load order.
+func (s *LoadOrderService) GetLoadOrder(ctx context.Context, target Target) ([]Mod, error) {
+	dir, err := resolveModsDir(s.app, target, false)
+	if err != nil {
+		return nil, err
+	}
+	return ScanMods(dir)
+}
+
+// ApplyOrder renumbers the mods so they load in the given order, first to
+// last. order must list the base name of every mod in the directory exactly
+// once. Mods get serials 1, 2, 3, ... and a "_P" suffix if they lack one,
+// since without it the serial does not affect load order.
+func (s *LoadOrderService) ApplyOrder(ctx context.Context, target Target, order []string, dryRun bool) (LoadOrderPlan, error) {
+	return s.change(target, dryRun, "Reorder mods", func(mods []Mod) (map[string]string, error) {
+		byBase := make(map[string]Mod, len(mods))
+		for _, m := range mods {
+			byBase[m.BaseName] = m
+		}
+		if len(order) != len(mods) {
+			return nil, fmt.Errorf("order lists %d mods, directory has %d", len(order), len(mods))
+		}
+
+		targets := make(map[string]string, len(order))
+		for i, base := range order {
+			m, ok := byBase[base]
+			if !ok {
+				return nil, fmt.Errorf("mod not found: %s", base)
+			}
+			if _, dup := targets[base]; dup {
+				return nil, fmt.Errorf("mod listed twice: %s", base)
+			}
+			m.Serial = i + 1
+			m.SerialWidth = max(m.SerialWidth, 4)
+			if m.PatchSuffix == "" {
+				m.PatchSuffix = "_P"
+			}
+			targets[base] = m.formatBaseName()
+		}
+		return targets, nil
+	})
+}
+
+// SetPriority changes the serial of the mod named baseName to priority,
+// keeping its prefix, name and patch suffix.
+func (s *LoadOrderService) SetPriority(ctx context.Context, target Target, baseName string, priority int, dryRun bool) (LoadOrderPlan, error) {
+	if priority < 0 || priority > maxSerial {
+		return LoadOrderPlan{}, fmt.Errorf("priority must be between 0 and %d, got %d", maxSerial, priority)
+	}
+	action := fmt.Sprintf("Set priority of %s to %d", baseName, priority)
+	return s.change(target, dryRun, action, func(mods []Mod) (map[string]string, error) {
+		m, err := findMod(mods, baseName)
+		if err != nil {
+			return nil, err
+		}
+		m.Serial = priority
+		m.SerialWidth = max(m.SerialWidth, 4)
+		return map[string]string{baseName: m.formatBaseName()}, nil
+	})
+}
+
+// RenameMod changes the name part of the mod named baseName to newName,
+// keeping its prefix, serial and patch suffix.
+func (s *LoadOrderService) RenameMod(ctx context.Context, target Target, baseName, newName string, dryRun bool) (LoadOrderPlan, error) {
+	switch {
+	case strings.TrimSpace(newName) == "":
+		return LoadOrderPlan{}, fmt.Errorf("mod name must not be empty")
+	case strings.ContainsAny(newName, `<>:"/\|?*`):
+		return LoadOrderPlan{}, fmt.Errorf(`mod name must not contain any of < > : " / \ | ? *`)
+	}
+	action := fmt.Sprintf("Rename %s to %s", baseName, newName)
+	return s.change(target, dryRun, action, func(mods []Mod) (map[string]string, error) {
+		m, err := findMod(mods, baseName)
+		if err != nil {
+			return nil, err
+		}
+		m.Name = newName
+		return map[string]string{baseName: m.formatBaseName()}, nil
+	})
+}
+
+// GetJournal returns the undo journal of the target's ~mods directory,
+// oldest entry first.
+func (s *LoadOrderService) GetJournal(ctx context.Context, target Target) ([]JournalEntry, error) {
+	dir, err := resolveModsDir(s.app, target, false)
+	if err != nil {
+		return nil, err
+	}
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+	return readJournal(dir)
+}
+
+// Undo reverts the most recent change recorded in the journal of the
+// target's ~mods directory and removes it from the journal.
+func (s *LoadOrderService) Undo(ctx context.Context, target Target, dryRun bool) (LoadOrderPlan, error) {
+	dir, err := resolveModsDir(s.app, target, false)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+
+	journal, err := readJournal(dir)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+	if len(journal) == 0 {
+		return LoadOrderPlan{}, fmt.Errorf("nothing to undo")
+	}
+	last := journal[len(journal)-1]
+
+	renames := make([]FileRename, len(last.Renames))
+	for i, r := range last.Renames {
+		renames[i] = FileRename{From: r.To, To: r.From}
+	}
+	plan, err := s.plan(dir, renames, dryRun)
+	if err != nil || dryRun {
+		return plan, err
+	}
+	if err := applyRenames(renames); err != nil {
+		return LoadOrderPlan{}, err
+	}
+	if err := renameInManifest(dir, renames); err != nil {
+		return plan, fmt.Errorf("change undone but install manifest not updated: %v", err)
+	}
+	if err := writeJournal(dir, journal[:len(journal)-1]); err != nil {
+		return plan, fmt.Errorf("change undone but journal not updated: %v", err)
+	}
+	return plan, nil
+}
+
+// change plans the base-name changes returned by targets and, unless
+// dryRun is set, applies them and records them in the journal.
+func (s *LoadOrderService) change(target Target, dryRun bool, action string, targets func([]Mod) (map[string]string, error)) (LoadOrderPlan, error) {
+	dir, err := resolveModsDir(s.app, target, false)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+
+	modsDirMutex.Lock()
+	defer modsDirMutex.Unlock()
+
+	mods, err := ScanMods(dir)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+	newBases, err := targets(mods)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+
+	var renames []FileRename
+	for _, m := range mods {
+		newBase, ok := newBases[m.BaseName]
+		if !ok || newBase == m.BaseName {
+			continue
+		}
+		for _, file := range m.Files {
+			renames = append(renames, FileRename{
+				From: file,
+				To:   filepath.Join(dir, newBase+filepath.Ext(file)),
+			})
+		}
+	}
+
+	plan, err := s.plan(dir, renames, dryRun)
+	if err != nil || dryRun || len(renames) == 0 {
+		return plan, err
+	}
+	if err := applyRenames(renames); err != nil {
+		return LoadOrderPlan{}, err
+	}
+	if err := renameInManifest(dir, renames); err != nil {
+		return plan, fmt.Errorf("mods renamed but install manifest not updated: %v", err)
+	}
+
+	journal, err := readJournal(dir)
+	if err != nil {
+		journal = nil
+	}
+	journal = append(journal, JournalEntry{
+		ID:      uuid.New().String(),
+		Time:    time.Now(),
+		Action:  action,
+		Renames: renames,
+	})
+	if len(journal) > maxJournalEntries {
+		journal = journal[len(journal)-maxJournalEntries:]
+	}
+	if err := writeJournal(dir, journal); err != nil {
+		return plan, fmt.Errorf("mods renamed but journal not written: %v", err)
+	}
+	return plan, nil
+}
+
+// plan checks that renames can be applied in dir and returns the load order
+// before and after them.
+func (s *LoadOrderService) plan(dir string, renames []FileRename, dryRun bool) (LoadOrderPlan, error) {
+	before, err := ScanMods(dir)
+	if err != nil {
+		return LoadOrderPlan{}, err
+	}
+	if err := checkRenames(renames); err != nil {
+		return LoadOrderPlan{}, err
+	}
+
+	// Simulate the renames on the file list to compute the new order.
+	files := make(map[string]string) // lower-case name -> name
+	for _, m := range before {
+		for _, f := range m.Files {
+			files[strings.ToLower(filepath.Base(f))] = filepath.Base(f)
+		}
+	}
+	for _, r := range renames {
+		delete(files, strings.ToLower(filepath.Base(r.From)))
+	}
+	for _, r := range renames {
+		files[strings.ToLower(filepath.Base(r.To))] = filepath.Base(r.To)
+	}
+	after := groupMods(dir, files)
+
+	return LoadOrderPlan{
+		ModsDir: dir,
+		Renames: renames,
+		Before:  before,
+		After:   after,
+		DryRun:  dryRun,
+	}, nil
+}
+
+// checkRenames verifies that every source exists and that no target is
+// taken by a file outside the rename set or by another target.
+func checkRenames(renames []FileRename) error {
+	sources := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		sources[strings.ToLower(r.From)] = true
+	}
+	targets := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		if _, err := os.Stat(r.From); err != nil {
+			return fmt.Errorf("file not found: %s", r.From)
+		}
+		key := strings.ToLower(r.To)
+		if targets[key] {
+			return fmt.Errorf("two files would be renamed to %s", filepath.Base(r.To))
+		}
+		targets[key] = true
+		if _, err := os.Stat(r.To); err == nil && !sources[key] {
+			return fmt.Errorf("target already exists: %s", filepath.Base(r.To))
+		}
+	}
+	return nil
+}
+
+// applyRenames renames the files in two passes through temporary names, so
+// swaps and case-only changes work. On failure the completed renames are
+// reverted.
+func applyRenames(renames []FileRename) error {
+	type step struct{ from, to string }
+	var done []step
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			os.Rename(done[i].to, done[i].from)
+		}
+	}
+
+	temps := make([]string, len(renames))
+	for i, r := range renames {
+		temps[i] = r.From + ".ari-s-tmp"
+		if err := os.Rename(r.From, temps[i]); err != nil {
+			rollback()
+			return fmt.Errorf("failed to rename %s: %v", filepath.Base(r.From), err)
+		}
+		done = append(done, step{r.From, temps[i]})
+	}
+	for i, r := range renames {
+		if err := os.Rename(temps[i], r.To); err != nil {
+			rollback()
+			return fmt.Errorf("failed to rename %s: %v", filepath.Base(r.From), err)
+		}
+		done = append(done, step{temps[i], r.To})
+	}
+	return nil
+}
+
+func findMod(mods []Mod, baseName string) (Mod, error) {
+	for _, m := range mods {
+		if m.BaseName == baseName {
+			return m, nil
+		}
+	}
+	return Mod{}, fmt.Errorf("mod not found: %s", baseName)
+}
+
+func readJournal(dir string) ([]JournalEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, JournalFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var journal []JournalEntry
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse load-order journal: %v", err)
+	}
+	return journal, nil
+}
+
+func writeJournal(dir string, journal []JournalEntry) error {
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, JournalFileName), data, 0644)
+}
@@ -0,0 +1,202 @@
+package mods
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+// writeMods creates a Paks/~mods directory holding a .pak/.utoc/.ucas set
+// for each base name and returns the Paks directory.
+func writeMods(t *testing.T, bases ...string) string {
+	t.Helper()
+	paks := filepath.Join(t.TempDir(), "Paks")
+	modsDir := filepath.Join(paks, ModsDirName)
+	if err := os.MkdirAll(modsDir, 0755); err != nil {
+		t.Fatalf("Failed to create mods dir: %v", err)
+	}
+	for _, base := range bases {
+		for _, ext := range []string{".pak", ".utoc", ".ucas"} {
+			if err := os.WriteFile(filepath.Join(modsDir, base+ext), []byte(base), 0644); err != nil {
+				t.Fatalf("Failed to write mod file: %v", err)
+			}
+		}
+	}
+	return paks
+}
+
+func baseNames(mods []Mod) []string {
+	names := make([]string, len(mods))
+	for i, m := range mods {
+		names[i] = m.BaseName
+	}
+	return names
+}
+
+func TestPakOrder_PatchSuffix_FollowsEngineRules(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"z_Mod_0042", 0},
+		{"z_Mod_P", 100},
+		{"z_Mod_0000_P", 100},
+		{"z_Mod_0001_p", 200},
+		{"z_Mod_0042_P", 4300},
+	}
+	for _, tt := range tests {
+		if got := PakOrder(tt.name); got != tt.want {
+			t.Errorf("PakOrder(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseModName_Presets_SplitIntoFields(t *testing.T) {
+	tests := []struct {
+		base                string
+		prefix, name, patch string
+		serial              int
+	}{
+		{"z_AwesomeMod_0042_p", "z_", "AwesomeMod", "_p", 42},
+		{"zzz_My_Mod_0003_P", "zzz_", "My_Mod", "_P", 3},
+		{"~AwesomeMod_0010_P", "~", "AwesomeMod", "_P", 10},
+		{"AwesomeMod_0007", "", "AwesomeMod", "", 7},
+		{"AwesomeMod", "", "AwesomeMod", "", -1},
+	}
+	for _, tt := range tests {
+		m := parseModName(tt.base)
+		if m.Prefix != tt.prefix || m.Name != tt.name || m.PatchSuffix != tt.patch || m.Serial != tt.serial {
+			t.Errorf("parseModName(%q) = %+v", tt.base, m)
+		}
+		if got := m.formatBaseName(); got != tt.base {
+			t.Errorf("formatBaseName round trip of %q gave %q", tt.base, got)
+		}
+	}
+}
+
+func TestLoadOrder_Scan_SortsByEffectiveOrder(t *testing.T) {
+	paks := writeMods(t, "z_Late_0005_P", "z_Early_0001_P", "z_NoPatch_0009", "a_Same_0005_P")
+	os.WriteFile(filepath.Join(paks, ModsDirName, "z_Early_0001_P.sig"), []byte("sig"), 0644)
+	os.WriteFile(filepath.Join(paks, ModsDirName, "readme.txt"), []byte("ignored"), 0644)
+	service := NewLoadOrderService(app.NewApp())
+
+	mods, err := service.GetLoadOrder(context.Background(), Target{PaksDir: paks})
+	if err != nil {
+		t.Fatalf("GetLoadOrder failed: %v", err)
+	}
+
+	want := []string{"z_NoPatch_0009", "z_Early_0001_P", "a_Same_0005_P", "z_Late_0005_P"}
+	if got := baseNames(mods); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected order %v, got %v", want, got)
+	}
+	if len(mods[1].Files) != 4 || !mods[1].Complete || mods[1].Position != 2 {
+		t.Errorf("Unexpected mod entry: %+v", mods[1])
+	}
+}
+
+func TestLoadOrder_ApplyOrder_DryRunLeavesFilesAlone(t *testing.T) {
+	paks := writeMods(t, "z_A_0001_P", "z_B_0002_P")
+	service := NewLoadOrderService(app.NewApp())
+
+	plan, err := service.ApplyOrder(context.Background(), Target{PaksDir: paks}, []string{"z_B_0002_P", "z_A_0001_P"}, true)
+	if err != nil {
+		t.Fatalf("ApplyOrder failed: %v", err)
+	}
+
+	if !plan.DryRun || len(plan.Renames) != 6 {
+		t.Errorf("Expected 6 planned renames, got %+v", plan.Renames)
+	}
+	if got := baseNames(plan.After); !reflect.DeepEqual(got, []string{"z_B_0001_P", "z_A_0002_P"}) {
+		t.Errorf("Unexpected planned order: %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(paks, ModsDirName, "z_A_0001_P.pak")); err != nil {
+		t.Error("Expected dry run to leave files in place")
+	}
+	if _, err := os.Stat(filepath.Join(paks, ModsDirName, JournalFileName)); err == nil {
+		t.Error("Expected dry run not to write a journal")
+	}
+}
+
+func TestLoadOrder_ApplyOrder_SwapsAndUndoes(t *testing.T) {
+	paks := writeMods(t, "z_A_0001_P", "z_B_0002_P", "z_C_0003")
+	modsDir := filepath.Join(paks, ModsDirName)
+	service := NewLoadOrderService(app.NewApp())
+	ctx := context.Background()
+	target := Target{PaksDir: paks}
+
+	if _, err := service.ApplyOrder(ctx, target, []string{"z_B_0002_P", "z_C_0003", "z_A_0001_P"}, false); err != nil {
+		t.Fatalf("ApplyOrder failed: %v", err)
+	}
+
+	mods, _ := service.GetLoadOrder(ctx, target)
+	if got := baseNames(mods); !reflect.DeepEqual(got, []string{"z_B_0001_P", "z_C_0002_P", "z_A_0003_P"}) {
+		t.Fatalf("Unexpected order after apply: %v", got)
+	}
+	// File contents follow their mod through the swap.
+	if data, _ := os.ReadFile(filepath.Join(modsDir, "z_A_0003_P.ucas")); string(data) != "z_A_0001_P" {
+		t.Errorf("Expected z_A's data in z_A_0003_P.ucas, got %q", data)
+	}
+
+	journal, err := service.GetJournal(ctx, target)
+	if err != nil || len(journal) != 1 {
+		t.Fatalf("Expected one journal entry, got %v (%v)", journal, err)
+	}
+
+	if _, err := service.Undo(ctx, target, false); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	mods, _ = service.GetLoadOrder(ctx, target)
+	if got := baseNames(mods); !reflect.DeepEqual(got, []string{"z_C_0003", "z_A_0001_P", "z_B_0002_P"}) {
+		t.Errorf("Unexpected order after undo: %v", got)
+	}
+	if _, err := service.Undo(ctx, target, false); err == nil {
+		t.Error("Expected undo with an empty journal to fail")
+	}
+}
+
+func TestLoadOrder_ApplyOrder_RejectsIncompleteOrder(t *testing.T) {
+	paks := writeMods(t, "z_A_0001_P", "z_B_0002_P")
+	service := NewLoadOrderService(app.NewApp())
+
+	for _, order := range [][]string{
+		{"z_A_0001_P"},
+		{"z_A_0001_P", "z_A_0001_P"},
+		{"z_A_0001_P", "z_Missing_0002_P"},
+	} {
+		if _, err := service.ApplyOrder(context.Background(), Target{PaksDir: paks}, order, false); err == nil {
+			t.Errorf("Expected order %v to be rejected", order)
+		}
+	}
+}
+
+func TestLoadOrder_SetPriority_RefusesExistingTarget(t *testing.T) {
+	paks := writeMods(t, "z_A_0001_P", "z_A_0002_P")
+	service := NewLoadOrderService(app.NewApp())
+
+	if _, err := service.SetPriority(context.Background(), Target{PaksDir: paks}, "z_A_0001_P", 2, false); err == nil {
+		t.Error("Expected renaming onto an existing mod to fail")
+	}
+	if _, err := os.Stat(filepath.Join(paks, ModsDirName, "z_A_0001_P.pak")); err != nil {
+		t.Error("Expected files to be left in place")
+	}
+}
+
+func TestLoadOrder_RenameMod_KeepsPrefixSerialAndSuffix(t *testing.T) {
+	paks := writeMods(t, "zzz_Old_0042_P")
+	os.WriteFile(filepath.Join(paks, ModsDirName, "zzz_Old_0042_P.sig"), []byte("sig"), 0644)
+	service := NewLoadOrderService(app.NewApp())
+
+	if _, err := service.RenameMod(context.Background(), Target{PaksDir: paks}, "zzz_Old_0042_P", "New", false); err != nil {
+		t.Fatalf("RenameMod failed: %v", err)
+	}
+
+	for _, ext := range []string{".pak", ".utoc", ".ucas", ".sig"} {
+		if _, err := os.Stat(filepath.Join(paks, ModsDirName, "zzz_New_0042_P"+ext)); err != nil {
+			t.Errorf("Expected zzz_New_0042_P%s to exist", ext)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package mods manages packed mods in a game's Paks/~mods directory: the
+// effective load order, renumbering and renaming of mod file sets, and
+// installing and uninstalling mods with a manifest.
+package mods
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+// ModsDirName is the directory under a game's Paks folder that the engine
+// mounts mod paks from.
+const ModsDirName = "~mods"
+
+// modsDirMutex serializes changes to ~mods directories and the journals and
+// manifests kept in them, across LoadOrderService and ModInstaller.
+var modsDirMutex sync.Mutex
+
+// modExtensions are the files that make up one packed mod, in the order
+// they are listed in [Mod.Files].
+var modExtensions = []string{".pak", ".utoc", ".ucas", ".sig"}
+
+// Mod is one packed mod in a ~mods directory: the .pak/.utoc/.ucas files
+// (and optional .sig) sharing a base name such as "z_AwesomeMod_0042_p".
+type Mod struct {
+	BaseName    string   `json:"base_name"`
+	Prefix      string   `json:"prefix"`       // "z_", "zzz_", "~" or ""
+	Name        string   `json:"name"`         // Mod name without prefix, serial or suffix
+	Serial      int      `json:"serial"`       // -1 if the name has no serial number
+	SerialWidth int      `json:"serial_width"` // Digits in the serial as written
+	PatchSuffix string   `json:"patch_suffix"` // "_P", "_p" or ""
+	Files       []string `json:"files"`        // Full paths
+	Complete    bool     `json:"complete"`     // Has .pak, .utoc and .ucas
+	PakOrder    int      `json:"pak_order"`    // Mount priority, see PakOrder
+	Position    int      `json:"position"`     // 1-based load position; the last mod wins conflicts
+}
+
+// modNamePattern splits a base name into prefix, name, serial and patch
+// suffix, e.g. "z_AwesomeMod_0042_p".
+var modNamePattern = regexp.MustCompile(`^(zzz_|z_|~)?(.+?)(?:_(\d+))?(_[pP])?$`)
+
+// parseModName fills the name fields of a Mod from baseName.
+func parseModName(baseName string) Mod {
+	m := Mod{BaseName: baseName, Serial: -1}
+	parts := modNamePattern.FindStringSubmatch(baseName)
+	if parts == nil {
+		m.Name = baseName
+		return m
+	}
+	m.Prefix, m.Name, m.PatchSuffix = parts[1], parts[2], parts[4]
+	if parts[3] != "" {
+		m.Serial, _ = strconv.Atoi(parts[3])
+		m.SerialWidth = len(parts[3])
+	}
+	m.PakOrder = PakOrder(baseName)
+	return m
+}
+
+// formatBaseName renders m's name fields back into a base name.
+func (m Mod) formatBaseName() string {
+	name := m.Prefix + m.Name
+	if m.Serial >= 0 {
+		name += fmt.Sprintf("_%0*d", max(m.SerialWidth, 1), m.Serial)
+	}
+	return name + m.PatchSuffix
+}
+
+// PakOrder returns the mount priority the engine gives a mod pak from its
+// base name, following FPakPlatformFile::Mount: a "_P" suffix adds 100,
+// or 100*(N+1) when the name ends in "_N_P" with N >= 1. Paks with higher
+// order override lower ones; ties are broken by file name, later names
+// winning.
+func PakOrder(baseName string) int {
+	if !strings.HasSuffix(strings.ToUpper(baseName), "_P") {
+		return 0
+	}
+	version := 1
+	stripped := baseName[:len(baseName)-2]
+	if i := strings.LastIndex(stripped, "_"); i >= 0 {
+		if n, err := strconv.Atoi(stripped[i+1:]); err == nil && n >= 1 {
+			version = n + 1
+		}
+	}
+	return 100 * version
+}
+
+// Target selects a game's ~mods directory: PaksDir if set, which may be a
+// Paks directory or the ~mods directory itself, otherwise the mods
+// directory of the game profile ProfileID.
+type Target struct {
+	ProfileID string `json:"profile_id"`
+	PaksDir   string `json:"paks_dir"`
+}
+
+// resolveModsDir returns the ~mods directory of target, creating it if
+// create is set and it does not exist.
+func resolveModsDir(a *app.App, target Target, create bool) (string, error) {
+	var modsDir string
+	switch {
+	case target.PaksDir != "":
+		modsDir = target.PaksDir
+		if !strings.EqualFold(filepath.Base(modsDir), ModsDirName) {
+			modsDir = filepath.Join(modsDir, ModsDirName)
+		}
+	case target.ProfileID != "":
+		profile, err := a.GetProfile(target.ProfileID)
+		if err != nil {
+			return "", err
+		}
+		if modsDir = profile.ResolvedModsDir(); modsDir == "" {
+			return "", fmt.Errorf("game profile '%s' has no Paks or mods directory", target.ProfileID)
+		}
+	default:
+		return "", fmt.Errorf("no game profile or Paks directory specified")
+	}
+
+	if create {
+		if info, err := os.Stat(filepath.Dir(modsDir)); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("Paks directory not found: %s", filepath.Dir(modsDir))
+		}
+		if err := os.MkdirAll(modsDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", modsDir, err)
+		}
+	}
+	if info, err := os.Stat(modsDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("mods directory not found: %s", modsDir)
+	}
+	return modsDir, nil
+}
+
+// ScanMods returns the mods in modsDir in effective load order. Only files
+// directly in modsDir are considered.
+func ScanMods(modsDir string) ([]Mod, error) {
+	entries, err := os.ReadDir(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	for _, e := range entries {
+		if !e.IsDir() && isModExtension(strings.ToLower(filepath.Ext(e.Name()))) {
+			files[strings.ToLower(e.Name())] = e.Name()
+		}
+	}
+	return groupMods(modsDir, files), nil
+}
+
+// groupMods builds the mods for a set of file names in dir, in load order.
+func groupMods(dir string, files map[string]string) []Mod {
+	byBase := make(map[string][]string)
+	var bases []string
+	for _, name := range files {
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		if byBase[base] == nil {
+			bases = append(bases, base)
+		}
+		byBase[base] = append(byBase[base], name)
+	}
+
+	mods := make([]Mod, 0, len(bases))
+	for _, base := range bases {
+		m := parseModName(base)
+		exts := make(map[string]string)
+		for _, name := range byBase[base] {
+			exts[strings.ToLower(filepath.Ext(name))] = filepath.Join(dir, name)
+		}
+		for _, ext := range modExtensions {
+			if p, ok := exts[ext]; ok {
+				m.Files = append(m.Files, p)
+			}
+		}
+		m.Complete = exts[".pak"] != "" && exts[".utoc"] != "" && exts[".ucas"] != ""
+		mods = append(mods, m)
+	}
+	sortLoadOrder(mods)
+	return mods
+}
+
+// sortLoadOrder sorts mods into the order the engine mounts them, lowest
+// priority first, and sets Position.
+func sortLoadOrder(mods []Mod) {
+	sort.SliceStable(mods, func(i, j int) bool {
+		if mods[i].PakOrder != mods[j].PakOrder {
+			return mods[i].PakOrder < mods[j].PakOrder
+		}
+		return strings.ToLower(mods[i].BaseName) < strings.ToLower(mods[j].BaseName)
+	})
+	for i := range mods {
+		mods[i].Position = i + 1
+	}
+}
+
+func isModExtension(ext string) bool {
+	for _, e := range modExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
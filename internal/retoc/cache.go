@@ -0,0 +1,225 @@
+package retoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// manifestExtension names the manifest a to-zen operation writes next to
+// its output, e.g. z_MyMod_0001_p.manifest.json.
+const manifestExtension = ".manifest.json"
+
+// packManifest records what a to-zen operation packed: the content of
+// every input file, the arguments retoc was run with and the files it
+// wrote. A later pack of the same input to the same output is skipped
+// when none of them changed.
+type packManifest struct {
+	Options PackOptions          `json:"options"`
+	Args    string               `json:"args"`    // SHA-256 of retoc's arguments, which may include an AES key
+	Inputs  map[string]fileState `json:"inputs"`  // By slash-separated path relative to the input folder
+	Outputs []outputState        `json:"outputs"` // In the order packedFiles returned them
+}
+
+// fileState identifies the content of a file. Inputs are hashed; outputs,
+// which are large and written only by retoc, are compared by size and
+// modification time.
+type fileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// outputState is the state of a packed file.
+type outputState struct {
+	Path string `json:"path"`
+	fileState
+}
+
+func (s fileState) sameStat(info fs.FileInfo) bool {
+	return s.Size == info.Size() && s.ModTime.Equal(info.ModTime())
+}
+
+// PackChanges describes how a to-zen operation differs from the last pack
+// of its input to the same output. Input paths are slash-separated and
+// relative to the input folder.
+type PackChanges struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+	Options  bool     `json:"options"` // Pack options or AES key
+	Outputs  []string `json:"outputs"` // Packed files since deleted or replaced
+}
+
+// None reports whether nothing changed.
+func (c PackChanges) None() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0 && !c.Options && len(c.Outputs) == 0
+}
+
+// packCache is the manifest state of one to-zen operation.
+type packCache struct {
+	path     string        // Manifest path
+	previous *packManifest // Nil if the output was never packed with a manifest
+	current  packManifest  // Outputs are filled in by save
+	changes  PackChanges   // Against previous
+}
+
+// checkPackCache hashes the operation's input and compares it, pack and
+// args with the manifest of the last pack to the same output.
+func (r *RetocService) checkPackCache(operation RetocOperation, pack PackOptions, args []string) (*packCache, error) {
+	newName, err := r.packBaseName(operation.ProfileID, pack)
+	if err != nil {
+		return nil, err
+	}
+	c := &packCache{path: filepath.Join(operation.OutputPath, newName+manifestExtension)}
+	if c.previous, err = readManifest(c.path); err != nil {
+		return nil, err
+	}
+
+	var previousInputs map[string]fileState
+	if c.previous != nil {
+		previousInputs = c.previous.Inputs
+	}
+	inputs, err := hashInputs(operation.InputPath, previousInputs)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	c.current = packManifest{Options: pack, Args: hex.EncodeToString(sum[:]), Inputs: inputs}
+	if c.previous != nil {
+		c.changes = c.previous.diff(c.current)
+	}
+	return c, nil
+}
+
+// unchanged reports whether the last pack is still current.
+func (c *packCache) unchanged() bool {
+	return c.previous != nil && c.changes.None()
+}
+
+// outputs returns the files the last pack wrote.
+func (c *packCache) outputs() []string {
+	paths := make([]string, len(c.previous.Outputs))
+	for i, output := range c.previous.Outputs {
+		paths[i] = output.Path
+	}
+	return paths
+}
+
+// save records outputs as written from the current input and writes the
+// manifest.
+func (c *packCache) save(outputs []string) error {
+	c.current.Outputs = make([]outputState, len(outputs))
+	for i, path := range outputs {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		c.current.Outputs[i] = outputState{Path: path, fileState: fileState{Size: info.Size(), ModTime: info.ModTime()}}
+	}
+	data, err := json.MarshalIndent(c.current, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// diff returns how current differs from m.
+func (m *packManifest) diff(current packManifest) PackChanges {
+	var changes PackChanges
+	for path, state := range current.Inputs {
+		previous, ok := m.Inputs[path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, path)
+		case previous.SHA256 != state.SHA256:
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range m.Inputs {
+		if _, ok := current.Inputs[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	changes.Options = m.Args != current.Args
+	for _, output := range m.Outputs {
+		if info, err := os.Stat(output.Path); err != nil || !output.sameStat(info) {
+			changes.Outputs = append(changes.Outputs, output.Path)
+		}
+	}
+	for _, list := range [][]string{changes.Added, changes.Modified, changes.Removed} {
+		slices.Sort(list)
+	}
+	return changes
+}
+
+// readManifest reads the manifest at path. A missing manifest is nil.
+func readManifest(path string) (*packManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m packManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid pack manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// hashInputs returns the state of every file under root. A file whose size
+// and modification time match its entry in previous keeps that entry's
+// hash instead of being read again.
+func hashInputs(root string, previous map[string]fileState) (map[string]fileState, error) {
+	inputs := make(map[string]fileState)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		state := fileState{Size: info.Size(), ModTime: info.ModTime()}
+		if p, ok := previous[rel]; ok && p.sameStat(info) && p.SHA256 != "" {
+			state.SHA256 = p.SHA256
+		} else if state.SHA256, err = hashFile(path); err != nil {
+			return err
+		}
+		inputs[rel] = state
+		return nil
+	})
+	return inputs, err
+}
+
+// hashFile returns the hex SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
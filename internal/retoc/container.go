@@ -0,0 +1,152 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// ContainerInfo is the container-level metadata of one IoStore container,
+// as returned by [RetocService.InspectContainer].
+type ContainerInfo struct {
+	Path                 string   `json:"path"` // Path to the .utoc file
+	Name                 string   `json:"name"` // File name without extension
+	Version              string   `json:"version"`
+	TocVersion           int      `json:"toc_version"`
+	ContainerID          string   `json:"container_id"`
+	MountPoint           string   `json:"mount_point"`
+	Encrypted            bool     `json:"encrypted"`
+	EncryptionKeyGUID    string   `json:"encryption_key_guid"`
+	Signed               bool     `json:"signed"`
+	Compressed           bool     `json:"compressed"`
+	Indexed              bool     `json:"indexed"`
+	Flags                string   `json:"flags"`
+	CompressionMethods   []string `json:"compression_methods"`
+	CompressionBlockSize uint32   `json:"compression_block_size"`
+	PartitionCount       int      `json:"partition_count"`
+	EntryCount           int      `json:"entry_count"`
+	FileCount            int      `json:"file_count"`
+	TotalSize            uint64   `json:"total_size"`
+	TotalCompressedSize  uint64   `json:"total_compressed_size"`
+}
+
+// ContainerEntry describes one chunk of a container, as returned by
+// [RetocService.ListContainer]. PackagePath and FilePath are empty for
+// chunks the directory index does not name, such as the container header.
+type ContainerEntry struct {
+	Container      string   `json:"container"` // Path to the .utoc file
+	PackagePath    string   `json:"package_path"`
+	FilePath       string   `json:"file_path"`
+	ChunkID        string   `json:"chunk_id"`
+	ChunkType      string   `json:"chunk_type"`
+	Size           uint64   `json:"size"`
+	CompressedSize uint64   `json:"compressed_size"`
+	Compression    []string `json:"compression"`
+}
+
+// InspectContainer returns the metadata of the .utoc file at utocPath
+// without invoking retoc.exe. If profileID is set, an encrypted directory
+// index is decrypted with the profile's unlocked AES keys, so the mount
+// point and file count are known.
+func (r *RetocService) InspectContainer(ctx context.Context, utocPath, profileID string) (ContainerInfo, error) {
+	if !strings.EqualFold(filepath.Ext(utocPath), ".utoc") {
+		return ContainerInfo{}, fmt.Errorf("not a .utoc file: %s", utocPath)
+	}
+	keys, err := r.profileKeys(profileID)
+	if err != nil {
+		return ContainerInfo{}, err
+	}
+	toc, err := readTocWithKeys(utocPath, keys)
+	if err != nil {
+		return ContainerInfo{}, err
+	}
+	return containerInfoFromToc(toc), nil
+}
+
+// ListContainer returns every chunk in the container at path, or in all
+// containers under path if it is a directory, without invoking retoc.exe.
+// Entries are returned grouped by container in path order. If profileID is
+// set, encrypted directory indices are decrypted with the profile's
+// unlocked AES keys; without them, chunks of an encrypted container have
+// no package or file path.
+func (r *RetocService) ListContainer(ctx context.Context, path, profileID string) ([]ContainerEntry, error) {
+	keys, err := r.profileKeys(profileID)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := iostore.FindContainers(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .utoc containers found in: %s", path)
+	}
+
+	var entries []ContainerEntry
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		toc, err := readTocWithKeys(p, keys)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, containerEntriesFromToc(toc)...)
+	}
+	return entries, nil
+}
+
+func containerInfoFromToc(toc *iostore.Toc) ContainerInfo {
+	h := toc.Header
+	info := ContainerInfo{
+		Path:                 toc.Path,
+		Name:                 strings.TrimSuffix(filepath.Base(toc.Path), filepath.Ext(toc.Path)),
+		Version:              h.Version.String(),
+		TocVersion:           int(h.Version),
+		ContainerID:          fmt.Sprintf("0x%016x", h.ContainerID),
+		MountPoint:           toc.MountPoint(),
+		Encrypted:            toc.IsEncrypted(),
+		Signed:               toc.IsSigned(),
+		Compressed:           h.ContainerFlags.Has(iostore.ContainerFlagCompressed),
+		Indexed:              h.ContainerFlags.Has(iostore.ContainerFlagIndexed),
+		Flags:                h.ContainerFlags.String(),
+		CompressionMethods:   toc.CompressionMethods,
+		CompressionBlockSize: h.CompressionBlockSize,
+		PartitionCount:       int(h.PartitionCount),
+		EntryCount:           int(h.EntryCount),
+	}
+	if info.Encrypted {
+		info.EncryptionKeyGUID = formatGUID(h.EncryptionKeyGUID)
+	}
+	if toc.DirectoryIndex != nil {
+		info.FileCount = len(toc.DirectoryIndex.Files)
+	}
+	for i := range toc.ChunkIDs {
+		if i < len(toc.OffsetLengths) {
+			info.TotalSize += toc.OffsetLengths[i].Length
+		}
+		info.TotalCompressedSize += toc.ChunkCompressedSize(i)
+	}
+	return info
+}
+
+func containerEntriesFromToc(toc *iostore.Toc) []ContainerEntry {
+	tocEntries := toc.Entries()
+	entries := make([]ContainerEntry, len(tocEntries))
+	for i, e := range tocEntries {
+		entries[i] = ContainerEntry{
+			Container:      toc.Path,
+			PackagePath:    e.PackagePath,
+			FilePath:       e.Path,
+			ChunkID:        e.ChunkID.String(),
+			ChunkType:      e.Type.String(),
+			Size:           e.Size,
+			CompressedSize: e.CompressedSize,
+			Compression:    e.Compression,
+		}
+	}
+	return entries
+}
@@ -0,0 +1,432 @@
+package retoc
+
+import (
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// Confidence rates how likely an [EngineCandidate] is to be right.
+type Confidence string
+
+// Confidence levels, most likely first.
+const (
+	ConfidenceHigh   Confidence = "high"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceLow    Confidence = "low"
+)
+
+var confidenceRank = map[Confidence]int{ConfidenceHigh: 0, ConfidenceMedium: 1, ConfidenceLow: 2}
+
+// EngineCandidate is one possible --version value for a game.
+type EngineCandidate struct {
+	Version    string     `json:"version"` // One of EngineVersions
+	Confidence Confidence `json:"confidence"`
+}
+
+// EngineDetection is the result of [DetectEngineVersion]. Candidates are
+// ordered most likely first and are empty if the game's files rule out
+// every version retoc supports or nothing could be read. The other fields
+// record the evidence the candidates were drawn from.
+type EngineDetection struct {
+	Candidates        []EngineCandidate `json:"candidates"`
+	TocVersion        string            `json:"toc_version"`        // Newest .utoc version found, e.g. "PerfectHashWithOverflow"
+	PakVersion        int               `json:"pak_version"`        // Newest .pak footer version found; 0 if none
+	Executable        string            `json:"executable"`         // Game executable with a version resource
+	ExecutableVersion string            `json:"executable_version"` // e.g. "5.3.2-29314046+++UE5+Release-5.3"
+}
+
+// Best returns the most likely engine version if it has high confidence.
+func (d EngineDetection) Best() (string, bool) {
+	if len(d.Candidates) == 0 || d.Candidates[0].Confidence != ConfidenceHigh {
+		return "", false
+	}
+	return d.Candidates[0].Version, true
+}
+
+// tocEngineVersions maps each TOC version to the engine versions that
+// write it. Minor versions sharing a TOC version can only be told apart by
+// the game executable.
+var tocEngineVersions = map[iostore.TocVersion][]string{
+	iostore.TocVersionDirectoryIndex:               {"UE4_26"},
+	iostore.TocVersionPartitionSize:                {"UE4_27"},
+	iostore.TocVersionPerfectHash:                  {"UE5_0"},
+	iostore.TocVersionPerfectHashWithOverflow:      {"UE5_0", "UE5_1", "UE5_2"},
+	iostore.TocVersionOnDemandMetaData:             {"UE5_3", "UE5_4"},
+	iostore.TocVersionRemovedOnDemandMetaData:      {"UE5_4", "UE5_5"},
+	iostore.TocVersionReplaceIoChunkHashWithIoHash: {"UE5_5"},
+}
+
+// pakMagic is the magic number of the FPakInfo footer at the end of a .pak.
+const pakMagic = 0x5A6F12E1
+
+// pakVersionPathHashIndex is the first .pak version written by UE 4.26,
+// the oldest engine retoc supports. Every later engine writes it too.
+const pakVersionPathHashIndex = 10
+
+// pakFooterOffsets are the distances from the end of a .pak file to the
+// footer magic: 44 bytes up to version 7, plus the compression method
+// names from version 8 (4 names in UE 4.22, 5 after) and the frozen index
+// flag of version 9.
+var pakFooterOffsets = []int64{44, 44 + 4*32, 44 + 5*32, 44 + 5*32 + 1}
+
+// DetectEngineVersion infers the engine version of the game at path, which
+// may be the install directory, a Content/Paks directory, or a single
+// .utoc, .pak or .exe file. The .utoc TOC version narrows the candidates to
+// a few engine versions, the .pak footer version rules out engines older
+// than UE 4.26, and the version resource of the game executable picks the
+// minor version.
+func DetectEngineVersion(path string) (EngineDetection, error) {
+	utocs, paks, exes, err := findEngineFiles(path)
+	if err != nil {
+		return EngineDetection{}, err
+	}
+	d := EngineDetection{Candidates: []EngineCandidate{}}
+
+	var tocVersion iostore.TocVersion
+	for _, utoc := range utocs {
+		if h, err := iostore.ReadTocHeader(utoc); err == nil && h.Version > tocVersion {
+			tocVersion = h.Version
+		}
+	}
+	if tocVersion != iostore.TocVersionInvalid {
+		d.TocVersion = tocVersion.String()
+	}
+	for _, pak := range paks {
+		if v, err := pakFooterVersion(pak); err == nil && v > d.PakVersion {
+			d.PakVersion = v
+		}
+	}
+
+	var exeVersion string
+	var exeConfidence Confidence
+	for _, exe := range exes {
+		info, err := exeVersionInfo(exe)
+		if err != nil {
+			continue
+		}
+		display, version, confidence := parseVersionInfo(info)
+		if display == "" {
+			continue
+		}
+		d.Executable, d.ExecutableVersion = exe, display
+		exeVersion, exeConfidence = version, confidence
+		break
+	}
+
+	if d.PakVersion > 0 && d.PakVersion < pakVersionPathHashIndex {
+		return d, nil
+	}
+	var possible []string
+	switch {
+	case tocVersion != iostore.TocVersionInvalid:
+		possible = tocEngineVersions[tocVersion]
+	case d.PakVersion > 0:
+		possible = EngineVersions
+	}
+	d.Candidates = rankCandidates(possible, tocVersion != iostore.TocVersionInvalid, exeVersion, exeConfidence)
+	return d, nil
+}
+
+// DetectEngineVersion infers the engine version of the game at path. See
+// the package-level [DetectEngineVersion].
+func (r *RetocService) DetectEngineVersion(ctx context.Context, path string) (EngineDetection, error) {
+	return DetectEngineVersion(path)
+}
+
+// rankCandidates combines the versions the containers allow with the one
+// the executable names. fromToc tells whether possible came from a TOC
+// version rather than only ruling out old engines.
+func rankCandidates(possible []string, fromToc bool, exeVersion string, exeConfidence Confidence) []EngineCandidate {
+	candidates := []EngineCandidate{}
+	add := func(version string, confidence Confidence) {
+		if slices.ContainsFunc(candidates, func(c EngineCandidate) bool { return c.Version == version }) {
+			return
+		}
+		candidates = append(candidates, EngineCandidate{Version: version, Confidence: confidence})
+	}
+
+	agrees := exeVersion != "" && (possible == nil || slices.Contains(possible, exeVersion))
+	switch {
+	case agrees:
+		// Containers and executable agree, which is as sure as it gets.
+		if fromToc {
+			exeConfidence = ConfidenceHigh
+		}
+		add(exeVersion, exeConfidence)
+		for _, v := range possible {
+			add(v, ConfidenceLow)
+		}
+	case fromToc:
+		confidence := ConfidenceMedium
+		if len(possible) == 1 {
+			confidence = ConfidenceHigh
+		}
+		if exeVersion != "" {
+			// The executable contradicts the containers; a project may
+			// have overridden its file version, so trust it less.
+			confidence = ConfidenceMedium
+		}
+		for _, v := range possible {
+			add(v, confidence)
+		}
+	default:
+		for _, v := range possible {
+			add(v, ConfidenceLow)
+		}
+	}
+	if exeVersion != "" {
+		add(exeVersion, ConfidenceLow)
+	}
+
+	// Most likely first; among equals, newest first.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.Confidence != cj.Confidence {
+			return confidenceRank[ci.Confidence] < confidenceRank[cj.Confidence]
+		}
+		return slices.Index(EngineVersions, ci.Version) > slices.Index(EngineVersions, cj.Version)
+	})
+	return candidates
+}
+
+// findEngineFiles returns the .utoc and .pak files and the game executables
+// to inspect for path. Mods in ~mods are not inspected, as they may have
+// been packed for another engine version.
+func findEngineFiles(path string) (utocs, paks, exes []string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !info.IsDir() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".utoc":
+			return []string{path}, nil, nil, nil
+		case ".pak":
+			return nil, []string{path}, nil, nil
+		case ".exe":
+			return nil, nil, []string{path}, nil
+		}
+		return nil, nil, nil, fmt.Errorf("not a .utoc, .pak or .exe file: %s", path)
+	}
+
+	var paksDirs []string
+	if utocs, paks = containerFiles(path); len(utocs)+len(paks) > 0 {
+		// A Paks directory: <Project>/Content/Paks
+		paksDirs = []string{path}
+		exes = globAll(filepath.Join(path, "..", "..", "Binaries", "*", "*.exe"))
+	} else {
+		// An install directory: <Project>/Content/Paks next to a launcher
+		paksDirs = globAll(filepath.Join(path, "*", "Content", "Paks"), filepath.Join(path, "Content", "Paks"))
+		exes = globAll(filepath.Join(path, "*", "Binaries", "*", "*.exe"), filepath.Join(path, "Binaries", "*", "*.exe"), filepath.Join(path, "*.exe"))
+	}
+	for _, dir := range paksDirs {
+		if dir == path {
+			continue
+		}
+		u, p := containerFiles(dir)
+		utocs, paks = append(utocs, u...), append(paks, p...)
+	}
+	if len(utocs)+len(paks)+len(exes) == 0 {
+		return nil, nil, nil, fmt.Errorf("no Unreal Engine game files found in %s", path)
+	}
+
+	// The shipping binary carries the engine's version resource; launcher
+	// stubs and tools may not.
+	sort.SliceStable(exes, func(i, j int) bool {
+		return strings.Contains(exes[i], "-Shipping") && !strings.Contains(exes[j], "-Shipping")
+	})
+	return utocs, paks, exes, nil
+}
+
+// containerFiles returns the .utoc and .pak files directly in dir.
+func containerFiles(dir string) (utocs, paks []string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".utoc":
+			utocs = append(utocs, filepath.Join(dir, e.Name()))
+		case ".pak":
+			paks = append(paks, filepath.Join(dir, e.Name()))
+		}
+	}
+	return utocs, paks
+}
+
+func globAll(patterns ...string) []string {
+	var matches []string
+	for _, pattern := range patterns {
+		m, _ := filepath.Glob(pattern)
+		matches = append(matches, m...)
+	}
+	return matches
+}
+
+// pakFooterVersion returns the version in the FPakInfo footer of the .pak
+// file at path.
+func pakFooterVersion(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	for _, offset := range pakFooterOffsets {
+		if offset > size {
+			break
+		}
+		var header [8]byte
+		if _, err := f.ReadAt(header[:], size-offset); err != nil {
+			return 0, err
+		}
+		if binary.LittleEndian.Uint32(header[0:]) == pakMagic {
+			return int(binary.LittleEndian.Uint32(header[4:])), nil
+		}
+	}
+	return 0, fmt.Errorf("%s: no pak footer found", path)
+}
+
+// rtVersion is the resource type ID of version information (RT_VERSION).
+const rtVersion = 16
+
+var errNoVersionInfo = errors.New("no version resource")
+
+// exeVersionInfo returns the raw VS_VERSIONINFO resource of the PE file at
+// path.
+func exeVersionInfo(path string) ([]byte, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_RESOURCE || dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress == 0 {
+		return nil, errNoVersionInfo
+	}
+	rva := dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress
+
+	for _, s := range f.Sections {
+		if rva < s.VirtualAddress || rva >= s.VirtualAddress+s.VirtualSize {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return nil, err
+		}
+		return versionResource(data, s.VirtualAddress, rva-s.VirtualAddress)
+	}
+	return nil, errNoVersionInfo
+}
+
+// versionResource walks the resource directory starting at offset root of
+// section, whose virtual address is sectionRVA, to the first language of
+// the first RT_VERSION resource and returns its data.
+func versionResource(section []byte, sectionRVA, root uint32) ([]byte, error) {
+	offset := root
+	for level := 0; level < 3; level++ {
+		target, ok := resourceChild(section, offset, level == 0)
+		if !ok {
+			return nil, errNoVersionInfo
+		}
+		isDir := target&0x80000000 != 0
+		if isDir != (level < 2) {
+			return nil, errNoVersionInfo
+		}
+		offset = root + target&0x7FFFFFFF
+	}
+
+	// IMAGE_RESOURCE_DATA_ENTRY: data RVA, size, code page, reserved
+	if int(offset)+8 > len(section) {
+		return nil, errNoVersionInfo
+	}
+	dataRVA := binary.LittleEndian.Uint32(section[offset:])
+	size := binary.LittleEndian.Uint32(section[offset+4:])
+	start := int64(dataRVA) - int64(sectionRVA)
+	if start < 0 || start+int64(size) > int64(len(section)) {
+		return nil, errNoVersionInfo
+	}
+	return section[start : start+int64(size)], nil
+}
+
+// resourceChild returns the target of an entry of the resource directory at
+// offset dir: the RT_VERSION entry if byType is set, otherwise the first.
+func resourceChild(section []byte, dir uint32, byType bool) (uint32, bool) {
+	if int(dir)+16 > len(section) {
+		return 0, false
+	}
+	count := int(binary.LittleEndian.Uint16(section[dir+12:])) + int(binary.LittleEndian.Uint16(section[dir+14:]))
+	for i := 0; i < count; i++ {
+		entry := int(dir) + 16 + i*8
+		if entry+8 > len(section) {
+			return 0, false
+		}
+		name := binary.LittleEndian.Uint32(section[entry:])
+		if !byType || name == rtVersion {
+			return binary.LittleEndian.Uint32(section[entry+4:]), true
+		}
+	}
+	return 0, false
+}
+
+// engineBuildPattern matches the engine build UE stamps into an
+// executable's ProductVersion string, e.g. "5.3.2-29314046+++UE5+Release-5.3".
+var engineBuildPattern = regexp.MustCompile(`(?:\d+\.\d+\.\d+-\d+\+)?\+\+UE[45]\+Release-(\d+)\.(\d+)`)
+
+// fixedFileInfoSignature starts the VS_FIXEDFILEINFO of a version resource.
+const fixedFileInfoSignature = 0xFEEF04BD
+
+// parseVersionInfo returns the engine version a VS_VERSIONINFO resource
+// names. The engine build in the ProductVersion string is reliable. The
+// fixed FILEVERSION defaults to the engine version but projects may set
+// their own, so it only yields medium confidence. version is empty if
+// display names no engine version retoc supports.
+func parseVersionInfo(info []byte) (display, version string, confidence Confidence) {
+	units := make([]uint16, len(info)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(info[i*2:])
+	}
+	if m := engineBuildPattern.FindStringSubmatch(string(utf16.Decode(units))); m != nil {
+		return m[0], canonical(fmt.Sprintf("UE%s_%s", m[1], m[2]), EngineVersions), ConfidenceHigh
+	}
+
+	for i := 0; i+16 <= len(info); i += 4 {
+		if binary.LittleEndian.Uint32(info[i:]) != fixedFileInfoSignature {
+			continue
+		}
+		ms := binary.LittleEndian.Uint32(info[i+8:])
+		ls := binary.LittleEndian.Uint32(info[i+12:])
+		major, minor := ms>>16, ms&0xFFFF
+		display = fmt.Sprintf("%d.%d.%d.%d", major, minor, ls>>16, ls&0xFFFF)
+		return display, canonical(fmt.Sprintf("UE%d_%d", major, minor), EngineVersions), ConfidenceMedium
+	}
+	return "", "", ""
+}
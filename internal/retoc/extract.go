@@ -0,0 +1,140 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// legacyExtensions are the files a package may be split into after
+// conversion to the legacy format, in the order they are reported.
+var legacyExtensions = []string{".uasset", ".umap", ".uexp", ".ubulk", ".uptnl", ".m.ubulk"}
+
+// ExtractedAsset describes the legacy files written by
+// [RetocService.ExtractAsset].
+type ExtractedAsset struct {
+	PackagePath string   `json:"package_path"`
+	Container   string   `json:"container"` // .utoc the package was found in
+	Files       []string `json:"files"`     // Written files, .uasset/.umap first
+	OperationID string   `json:"operation_id"`
+	Duration    string   `json:"duration"`
+}
+
+// ExtractAsset converts a single package from the IoStore containers in
+// containerDir to legacy .uasset/.uexp/.ubulk files under outDir.
+// packagePath is a long package name such as "/Game/Data/DT_Items"; an
+// object path ("/Game/Data/DT_Items.DT_Items") or file extension is
+// accepted and ignored. If profileID is set, encrypted containers are
+// searched and converted with the profile's unlocked AES keys.
+//
+// The package is located natively first so a typo fails fast, before
+// retoc.exe is started. The conversion itself runs retoc's to-legacy
+// command restricted to the package, and progress is reported like any
+// other operation.
+func (r *RetocService) ExtractAsset(ctx context.Context, containerDir, packagePath, outDir, profileID string) (ExtractedAsset, error) {
+	packagePath = normalizePackagePath(packagePath)
+	if packagePath == "" || strings.ContainsAny(packagePath, "*?") {
+		return ExtractedAsset{}, fmt.Errorf("invalid package path: %q", packagePath)
+	}
+
+	keys, err := r.profileKeys(profileID)
+	if err != nil {
+		return ExtractedAsset{}, err
+	}
+	container, mountedPath, err := findPackage(containerDir, packagePath, keys)
+	if err != nil {
+		return ExtractedAsset{}, err
+	}
+
+	result := r.RunRetoc(ctx, RetocOperation{
+		Command:    "to-legacy",
+		InputPath:  containerDir,
+		OutputPath: outDir,
+		Include:    []string{packagePath},
+		ProfileID:  profileID,
+	})
+	extracted := ExtractedAsset{
+		PackagePath: packagePath,
+		Container:   container,
+		OperationID: result.OperationID,
+		Duration:    result.Duration,
+	}
+	if !result.Success {
+		if result.Error == "" {
+			result.Error = result.Message
+		}
+		return extracted, fmt.Errorf("failed to extract %s: %s", packagePath, result.Error)
+	}
+
+	base, err := unpackPath(outDir, mountedPath)
+	if err != nil {
+		return extracted, err
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	for _, ext := range legacyExtensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			extracted.Files = append(extracted.Files, base+ext)
+		}
+	}
+	if len(extracted.Files) == 0 {
+		return extracted, fmt.Errorf("retoc completed but wrote no files for %s under %s", packagePath, outDir)
+	}
+	return extracted, nil
+}
+
+// findPackage returns the first container under dir holding packagePath,
+// and the package's mounted file path in that container. Matching is
+// case-insensitive. Encrypted directory indices are decrypted with the
+// matching key from keys; containers none fits are not searched.
+func findPackage(dir, packagePath string, keys []config.AESKey) (container, mountedPath string, err error) {
+	paths, err := iostore.FindContainers(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if len(paths) == 0 {
+		return "", "", fmt.Errorf("no .utoc containers found in: %s", dir)
+	}
+
+	unsearched := 0
+	for _, p := range paths {
+		toc, err := readTocWithKeys(p, keys)
+		if err != nil || toc.DirectoryIndex == nil {
+			unsearched++
+			continue
+		}
+		for _, e := range toc.Entries() {
+			if strings.EqualFold(e.PackagePath, packagePath) {
+				return p, e.Path, nil
+			}
+		}
+	}
+
+	if unsearched > 0 {
+		return "", "", fmt.Errorf("package %s not found in %s (%d container(s) could not be searched)", packagePath, dir, unsearched)
+	}
+	return "", "", fmt.Errorf("package %s not found in %s", packagePath, dir)
+}
+
+// normalizePackagePath reduces an object path or file-style path to a long
+// package name: "/Game/Data/DT_Items.DT_Items" and "/Game/Data/DT_Items.uasset"
+// both become "/Game/Data/DT_Items".
+func normalizePackagePath(p string) string {
+	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" {
+		return ""
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	dir, name := path.Split(p)
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSuffix(dir+name, "/")
+}
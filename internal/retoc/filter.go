@@ -0,0 +1,190 @@
+package retoc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// packageFilter selects packages by long package name using include and
+// exclude globs such as "/Game/Data/**/DT_*". A package is selected when it
+// matches any include glob (or there are none) and no exclude glob.
+//
+// Glob syntax: "*" matches within one path segment, "**" matches across
+// segments ("/**/" also matches a single "/"), and "?" matches one
+// character other than "/". Globs without a leading "/" match at any depth.
+// Matching is case-insensitive, like the engine's own package names.
+type packageFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	// prefixes are the literal leading parts of the include globs, passed
+	// to retoc as --filter arguments when the filter cannot be applied
+	// natively.
+	prefixes []string
+}
+
+// newPackageFilter compiles the include and exclude globs. It returns nil
+// when both are empty.
+func newPackageFilter(include, exclude []string) (*packageFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	f := &packageFilter{}
+	for _, g := range include {
+		re, err := compileGlob(g)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, re)
+		f.prefixes = append(f.prefixes, globPrefix(g))
+	}
+	for _, g := range exclude {
+		re, err := compileGlob(g)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+// match reports whether packagePath is selected by the filter.
+func (f *packageFilter) match(packagePath string) bool {
+	if packagePath == "" {
+		return false
+	}
+	included := len(f.include) == 0
+	for _, re := range f.include {
+		if re.MatchString(packagePath) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(packagePath) {
+			return false
+		}
+	}
+	return true
+}
+
+// retocArgs returns the --filter arguments passed to retoc. retoc matches
+// its filter as a substring of the package path, so each include glob is
+// reduced to its literal prefix and the output is pruned afterwards with
+// pruneOutput. If any include glob has no literal prefix, retoc is left
+// unfiltered so that it does not drop packages that glob would select.
+func (f *packageFilter) retocArgs() []string {
+	var args []string
+	for _, p := range f.prefixes {
+		if p == "" || p == "/" {
+			return nil
+		}
+		args = append(args, "--filter", p)
+	}
+	return args
+}
+
+// compileGlob converts a package glob to an anchored regular expression.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	g := strings.ReplaceAll(strings.TrimSpace(glob), "\\", "/")
+	if g == "" {
+		return nil, fmt.Errorf("empty filter pattern")
+	}
+	if !strings.HasPrefix(g, "/") && !strings.HasPrefix(g, "**") {
+		g = "**/" + g
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(g); i++ {
+		c := g[i]
+		switch {
+		case c == '*' && strings.HasPrefix(g[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(g[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter pattern %q: %v", glob, err)
+	}
+	return re, nil
+}
+
+// globPrefix returns the part of glob before its first wildcard.
+func globPrefix(glob string) string {
+	g := strings.ReplaceAll(strings.TrimSpace(glob), "\\", "/")
+	if i := strings.IndexAny(g, "*?"); i >= 0 {
+		g = g[:i]
+	}
+	return g
+}
+
+// snapshotFiles returns the set of regular files under dir. A missing dir
+// yields an empty set.
+func snapshotFiles(dir string) map[string]bool {
+	files := make(map[string]bool)
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files[p] = true
+		}
+		return nil
+	})
+	return files
+}
+
+// pruneOutput removes files written under dir by a filtered retoc run whose
+// package does not match the filter. Files listed in existing were present
+// before the run and are never touched. It returns the number of files
+// removed.
+func (f *packageFilter) pruneOutput(dir string, existing map[string]bool) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || existing[p] {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if f.match(iostore.PackagePath(filepath.ToSlash(rel))) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// unpackPath returns where a chunk with the given mounted path is written
+// under outputDir: the path with its leading "../" mount segments removed.
+func unpackPath(outputDir, mountedPath string) (string, error) {
+	rel := strings.TrimLeft(path.Clean("/"+mountedPath), "/")
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("refusing to write outside output directory: %s", mountedPath)
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(rel)), nil
+}
@@ -0,0 +1,143 @@
+package retoc
+
+import (
+	"fmt"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// keyForContainer returns the key whose GUID matches the container's
+// encryption key GUID, or the game's default key if none does.
+func keyForContainer(keys []config.AESKey, toc *iostore.Toc) (config.AESKey, bool) {
+	guid, _ := config.NormalizeGUID(formatGUID(toc.Header.EncryptionKeyGUID))
+	var fallback config.AESKey
+	hasFallback := false
+	for _, k := range keys {
+		if k.GUID == guid {
+			return k, true
+		}
+		if k.GUID == "" {
+			fallback, hasFallback = k, true
+		}
+	}
+	return fallback, hasFallback
+}
+
+// applyKey decrypts toc's directory index with the matching key from keys.
+// It is a no-op for unencrypted containers.
+func applyKey(keys []config.AESKey, toc *iostore.Toc) error {
+	if !toc.IsEncrypted() {
+		return nil
+	}
+	key, ok := keyForContainer(keys, toc)
+	if !ok {
+		return fmt.Errorf("no AES key for %s (key GUID %s)", toc.Path, formatGUID(toc.Header.EncryptionKeyGUID))
+	}
+	raw, err := config.ParseAESKey(key.Key)
+	if err != nil {
+		return err
+	}
+	return toc.DecryptDirectoryIndex(raw)
+}
+
+// profileKeys returns the unlocked AES keys of the given profile, or none
+// if profileID is empty.
+func (r *RetocService) profileKeys(profileID string) ([]config.AESKey, error) {
+	if profileID == "" {
+		return nil, nil
+	}
+	keys, err := r.app.GameKeys(profileID)
+	if err != nil {
+		return nil, fmt.Errorf("AES key check failed: %w", err)
+	}
+	return keys, nil
+}
+
+// readTocWithKeys reads the .utoc file at path and, if it is encrypted and
+// keys are given, decrypts its directory index with the matching key.
+func readTocWithKeys(path string, keys []config.AESKey) (*iostore.Toc, error) {
+	toc, err := iostore.ReadToc(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		if err := applyKey(keys, toc); err != nil {
+			return nil, err
+		}
+	}
+	return toc, nil
+}
+
+// keyedInput is the input of one retoc.exe run and the key passed with it,
+// if any.
+type keyedInput struct {
+	path string
+	key  *config.AESKey
+}
+
+// keyedInputs checks keys against every encrypted container under
+// inputPath by decrypting its directory index, so a wrong key fails before
+// retoc.exe runs rather than partway through an unpack, and splits the
+// input into retoc.exe runs. retoc accepts one --aes-key per run, so an
+// input whose containers share a key, or have none, is one run with that
+// key or the game's default key; an input that mixes keys is run one
+// container at a time, grouped by key, with unencrypted containers last.
+func keyedInputs(inputPath string, keys []config.AESKey) ([]keyedInput, error) {
+	paths, err := iostore.FindContainers(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var guids []string
+	groups := make(map[string][]string)
+	used := make(map[string]config.AESKey)
+	var plain []string
+	for _, p := range paths {
+		toc, err := iostore.ReadToc(p)
+		if err != nil || !toc.IsEncrypted() {
+			plain = append(plain, p)
+			continue
+		}
+		if err := applyKey(keys, toc); err != nil {
+			return nil, err
+		}
+		key, _ := keyForContainer(keys, toc)
+		if _, ok := used[key.GUID]; !ok {
+			used[key.GUID] = key
+			guids = append(guids, key.GUID)
+		}
+		groups[key.GUID] = append(groups[key.GUID], p)
+	}
+
+	switch len(guids) {
+	case 0:
+		key := defaultKey(keys)
+		return []keyedInput{{path: inputPath, key: &key}}, nil
+	case 1:
+		key := used[guids[0]]
+		return []keyedInput{{path: inputPath, key: &key}}, nil
+	}
+	var inputs []keyedInput
+	for _, guid := range guids {
+		key := used[guid]
+		for _, p := range groups[guid] {
+			inputs = append(inputs, keyedInput{path: p, key: &key})
+		}
+	}
+	for _, p := range plain {
+		inputs = append(inputs, keyedInput{path: p})
+	}
+	return inputs, nil
+}
+
+// defaultKey returns the game's default key, the one without a GUID, or
+// the first key if every key has one.
+func defaultKey(keys []config.AESKey) config.AESKey {
+	for _, k := range keys {
+		if k.GUID == "" {
+			return k
+		}
+	}
+	return keys[0]
+}
@@ -0,0 +1,183 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+// DefaultNameTemplate is the template of the default "z" preset.
+const DefaultNameTemplate = "{prefix}{name}_{serial:04}{patchSuffix}"
+
+// NamingPreset is a built-in naming scheme for packed mod files.
+//
+// Templates may use these placeholders:
+//   - {name}: the mod name
+//   - {serial}, {serial:04}: the priority, optionally zero-padded to a width
+//   - {prefix}: the preset's Prefix
+//   - {patchSuffix}: the preset's PatchSuffix, or nothing when
+//     PackOptions.PatchSuffix is false
+type NamingPreset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Template    string `json:"template"`
+	Prefix      string `json:"prefix"`
+	PatchSuffix string `json:"patch_suffix"`
+}
+
+// NamingPresets lists the built-in presets. The first is the default.
+var NamingPresets = []NamingPreset{
+	{Name: "z", Description: "z_Name_0001_p (default)", Template: DefaultNameTemplate, Prefix: "z_", PatchSuffix: "_p"},
+	{Name: "zzz", Description: "zzz_Name_0001_P", Template: DefaultNameTemplate, Prefix: "zzz_", PatchSuffix: "_P"},
+	{Name: "tilde", Description: "~Name_0001_P", Template: DefaultNameTemplate, Prefix: "~", PatchSuffix: "_P"},
+	{Name: "plain", Description: "Name_0001_P, no prefix", Template: DefaultNameTemplate, PatchSuffix: "_P"},
+	{Name: "plain_nopatch", Description: "Name_0001, no prefix and no _P", Template: DefaultNameTemplate},
+}
+
+// packedExtensions are the files retoc writes for a to-zen operation, plus
+// the signature file some games require alongside the .pak.
+var packedExtensions = []string{".utoc", ".ucas", ".pak", ".sig"}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-zA-Z]+)(?::(\d+))?\}`)
+
+// ListNamingPresets returns the built-in naming presets.
+func (r *RetocService) ListNamingPresets(ctx context.Context) []NamingPreset {
+	return NamingPresets
+}
+
+// SetProfileNaming validates scheme and stores it as the naming scheme used
+// when packing for the given game profile.
+func (r *RetocService) SetProfileNaming(ctx context.Context, profileID string, scheme config.NamingScheme) error {
+	if _, err := resolveNaming(scheme.Preset, scheme.Template); err != nil {
+		return err
+	}
+	return r.app.SetNamingScheme(profileID, scheme)
+}
+
+// PreviewPackNames returns the file names a to-zen operation would produce,
+// without running anything, so the frontend can show them as the user
+// types.
+func (r *RetocService) PreviewPackNames(ctx context.Context, operation RetocOperation) ([]string, error) {
+	operation, err := r.applyProfile(operation)
+	if err != nil {
+		return nil, err
+	}
+	pack, err := operation.packOptions()
+	if err != nil {
+		return nil, err
+	}
+	base, err := r.packBaseName(operation.ProfileID, pack)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(packedExtensions))
+	for i, ext := range packedExtensions {
+		names[i] = base + ext
+	}
+	return names, nil
+}
+
+// packBaseName renders the output base name for pack. The naming scheme is
+// taken from pack if it names one, otherwise from the profile's scheme,
+// otherwise the default preset.
+func (r *RetocService) packBaseName(profileID string, pack PackOptions) (string, error) {
+	presetName, template := pack.NamingPreset, pack.NameTemplate
+	if presetName == "" && template == "" && profileID != "" {
+		scheme := r.app.GetNamingScheme(profileID)
+		presetName, template = scheme.Preset, scheme.Template
+	}
+	preset, err := resolveNaming(presetName, template)
+	if err != nil {
+		return "", err
+	}
+
+	patchSuffix := preset.PatchSuffix
+	if pack.PatchSuffix != nil && !*pack.PatchSuffix {
+		patchSuffix = ""
+	}
+	name := renderName(preset.Template, preset.Prefix, pack.ModName, pack.serial, patchSuffix)
+	if strings.ContainsAny(name, `<>:"/\|?*`) || strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("naming template produced an invalid file name: %q", name)
+	}
+	return name, nil
+}
+
+// resolveNaming returns the preset named presetName (the default if empty)
+// with its template replaced by template, if set.
+func resolveNaming(presetName, template string) (NamingPreset, error) {
+	preset := NamingPresets[0]
+	if presetName != "" {
+		found := false
+		for _, p := range NamingPresets {
+			if strings.EqualFold(p.Name, presetName) {
+				preset, found = p, true
+				break
+			}
+		}
+		if !found {
+			return NamingPreset{}, fmt.Errorf("unknown naming preset %q", presetName)
+		}
+	}
+	if template != "" {
+		if err := validateTemplate(template); err != nil {
+			return NamingPreset{}, err
+		}
+		preset.Template = template
+	}
+	return preset, nil
+}
+
+// validateTemplate checks that template uses only known placeholders and
+// includes the mod name.
+func validateTemplate(template string) error {
+	hasName := false
+	for _, m := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		switch m[1] {
+		case "name":
+			hasName = true
+		case "serial":
+			if m[2] != "" {
+				if width, _ := strconv.Atoi(m[2]); width < 1 || width > 9 {
+					return fmt.Errorf("invalid serial width in naming template: %s", m[0])
+				}
+			}
+		case "prefix", "patchSuffix":
+		default:
+			return fmt.Errorf("unknown placeholder in naming template: %s", m[0])
+		}
+		if m[1] != "serial" && m[2] != "" {
+			return fmt.Errorf("only {serial} takes a width: %s", m[0])
+		}
+	}
+	if !hasName {
+		return fmt.Errorf("naming template must contain {name}")
+	}
+	if strings.ContainsAny(placeholderPattern.ReplaceAllString(template, ""), `{}<>:"/\|?*`) {
+		return fmt.Errorf("naming template contains invalid characters: %q", template)
+	}
+	return nil
+}
+
+// renderName substitutes the placeholders of a validated template. serial
+// formats the serial to a width, 0 meaning unpadded.
+func renderName(template, prefix, name string, serial func(width int) string, patchSuffix string) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(ph string) string {
+		m := placeholderPattern.FindStringSubmatch(ph)
+		switch m[1] {
+		case "name":
+			return name
+		case "serial":
+			width, _ := strconv.Atoi(m[2])
+			return serial(width)
+		case "prefix":
+			return prefix
+		case "patchSuffix":
+			return patchSuffix
+		}
+		return ph
+	})
+}
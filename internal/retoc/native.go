@@ -0,0 +1,242 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+)
+
+// runNativeInspect answers the read-only "info" and "list" commands by
+// parsing the .utoc files under operation.InputPath in-process. It reports
+// false when no .utoc file is found (for example a legacy .pak-only
+// directory), in which case the caller falls back to retoc.exe. Encrypted
+// directory indices are decrypted with the matching key from keys.
+func (r *RetocService) runNativeInspect(operation RetocOperation, keys []config.AESKey, operationID string, startTime time.Time) (RetocResult, bool) {
+	paths, err := iostore.FindContainers(operation.InputPath)
+	if err != nil || len(paths) == 0 {
+		return RetocResult{}, false
+	}
+
+	r.app.Emit(EventStarted, RetocStarted{
+		OperationID: operationID,
+		Command:     operation.Command,
+	})
+	r.startProgress(operationID, operation.Command)
+
+	output := &outputCollector{}
+	var failures []string
+	for i, path := range paths {
+		toc, err := iostore.ReadToc(path)
+		if err == nil && len(keys) > 0 {
+			err = applyKey(keys, toc)
+		}
+		if err != nil {
+			failures = append(failures, err.Error())
+			r.emitOutputLine(operationID, StreamStderr, fmt.Sprintf("error: %v", err), output)
+			continue
+		}
+
+		var text string
+		if operation.Command == "info" {
+			text = formatContainerInfo(containerInfoFromToc(toc))
+		} else {
+			text = formatContainerList(toc.Path, containerEntriesFromToc(toc))
+		}
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			r.emitOutputLine(operationID, StreamStdout, line, output)
+		}
+
+		r.trackProgress(operationID, fmt.Sprintf("Reading %d/%d containers", i+1, len(paths)))
+	}
+
+	result := RetocResult{
+		Output:      output.String(),
+		Duration:    time.Since(startTime).String(),
+		OperationID: operationID,
+	}
+	if len(failures) == len(paths) {
+		r.finishProgress(operationID, PhaseFailed)
+		result.Success = false
+		result.Error = strings.Join(failures, "; ")
+		result.Message = "Retoc operation failed"
+		return result, true
+	}
+
+	r.finishProgress(operationID, PhaseCompleted)
+	result.Success = true
+	result.Message = "Retoc operation completed successfully"
+	if len(failures) > 0 {
+		result.Message += fmt.Sprintf(" (Warning: %d container(s) could not be read)", len(failures))
+	}
+	return result, true
+}
+
+// nativeChunk is one chunk selected for native extraction.
+type nativeChunk struct {
+	container *iostore.Container
+	index     int
+	path      string // Mounted path from the directory index
+}
+
+// runNativeUnpack extracts the chunks of the packages selected by filter
+// from the containers under operation.InputPath in-process. It reports
+// false, before writing anything, when no container is found or when any
+// selected chunk cannot be decoded natively (for example Oodle-compressed or
+// encrypted data without a key), in which case the caller falls back to
+// retoc.exe.
+func (r *RetocService) runNativeUnpack(ctx context.Context, operation RetocOperation, filter *packageFilter, keys []config.AESKey, operationID string, startTime time.Time) (RetocResult, bool) {
+	paths, err := iostore.FindContainers(operation.InputPath)
+	if err != nil || len(paths) == 0 {
+		return RetocResult{}, false
+	}
+
+	var containers []*iostore.Container
+	defer func() {
+		for _, c := range containers {
+			c.Close()
+		}
+	}()
+
+	var selected []nativeChunk
+	for _, path := range paths {
+		c, err := iostore.OpenContainer(path)
+		if err != nil {
+			return RetocResult{}, false
+		}
+		containers = append(containers, c)
+		if c.Toc.IsEncrypted() && len(keys) > 0 {
+			if key, ok := keyForContainer(keys, c.Toc); ok {
+				raw, err := config.ParseAESKey(key.Key)
+				if err != nil || c.SetKey(raw) != nil {
+					return RetocResult{}, false
+				}
+			}
+		}
+		for _, e := range c.Toc.Entries() {
+			if !filter.match(e.PackagePath) {
+				continue
+			}
+			if c.CanRead(e.TocEntryIndex) != nil {
+				return RetocResult{}, false
+			}
+			selected = append(selected, nativeChunk{c, e.TocEntryIndex, e.Path})
+		}
+	}
+
+	r.app.Emit(EventStarted, RetocStarted{
+		OperationID: operationID,
+		Command:     operation.Command,
+	})
+	r.startProgress(operationID, operation.Command)
+
+	output := &outputCollector{}
+	result := RetocResult{OperationID: operationID}
+	finish := func(phase string) RetocResult {
+		r.finishProgress(operationID, phase)
+		result.Output = output.String()
+		result.Duration = time.Since(startTime).String()
+		return result
+	}
+
+	for i, chunk := range selected {
+		if ctx.Err() != nil {
+			result.Message = "Operation cancelled by user"
+			result.Error = "cancelled"
+			return finish(PhaseCancelled), true
+		}
+
+		outPath, err := unpackPath(operation.OutputPath, chunk.path)
+		if err == nil {
+			err = writeChunk(chunk, outPath)
+		}
+		if err != nil {
+			r.emitOutputLine(operationID, StreamStderr, fmt.Sprintf("error: %s: %v", chunk.path, err), output)
+			result.Message = "Retoc operation failed"
+			result.Error = err.Error()
+			return finish(PhaseFailed), true
+		}
+
+		r.emitOutputLine(operationID, StreamStdout, fmt.Sprintf("Extracted %s", outPath), output)
+		r.trackProgress(operationID, fmt.Sprintf("Writing files %d/%d", i+1, len(selected)))
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Retoc operation completed successfully (%d file(s) extracted)", len(selected))
+	if len(selected) == 0 {
+		result.Message = "Retoc operation completed successfully (Warning: no packages matched the filter)"
+	}
+	return finish(PhaseCompleted), true
+}
+
+// writeChunk decodes chunk and writes it to outPath, creating parent
+// directories as needed.
+func writeChunk(chunk nativeChunk, outPath string) error {
+	data, err := chunk.container.ReadChunk(chunk.index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// formatContainerInfo renders container metadata in a form similar to
+// retoc's own "info" output.
+func formatContainerInfo(info ContainerInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Container: %s\n", info.Path)
+	fmt.Fprintf(&b, "  Version:        %s (%d)\n", info.Version, info.TocVersion)
+	fmt.Fprintf(&b, "  Container ID:   %s\n", info.ContainerID)
+	fmt.Fprintf(&b, "  Flags:          %s\n", info.Flags)
+	fmt.Fprintf(&b, "  Entries:        %d\n", info.EntryCount)
+	fmt.Fprintf(&b, "  Size:           %d bytes (%d compressed)\n", info.TotalSize, info.TotalCompressedSize)
+	fmt.Fprintf(&b, "  Block size:     %d\n", info.CompressionBlockSize)
+	fmt.Fprintf(&b, "  Compression:    %s\n", strings.Join(info.CompressionMethods, ", "))
+	fmt.Fprintf(&b, "  Partitions:     %d\n", info.PartitionCount)
+	switch {
+	case info.MountPoint != "":
+		fmt.Fprintf(&b, "  Mount point:    %s\n", info.MountPoint)
+		fmt.Fprintf(&b, "  Files:          %d\n", info.FileCount)
+	case info.Encrypted && info.Indexed:
+		fmt.Fprintf(&b, "  Mount point:    (encrypted)\n")
+	default:
+		fmt.Fprintf(&b, "  Mount point:    (no directory index)\n")
+	}
+	if info.Encrypted {
+		fmt.Fprintf(&b, "  Encryption key: %s\n", info.EncryptionKeyGUID)
+	}
+	return b.String()
+}
+
+// formatContainerList renders one line per chunk: path (or chunk ID for
+// unindexed chunks), chunk type and size.
+func formatContainerList(path string, entries []ContainerEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Container: %s\n", path)
+	for _, e := range entries {
+		name := e.FilePath
+		if name == "" {
+			name = e.ChunkID
+		}
+		fmt.Fprintf(&b, "  %s\t%s\t%d\n", name, e.ChunkType, e.Size)
+	}
+	return b.String()
+}
+
+// formatGUID renders a 16-byte FGuid as four 32-bit hex groups, the form
+// used by AES key listings for Unreal games.
+func formatGUID(g [16]byte) string {
+	var parts [4]string
+	for i := range parts {
+		v := uint32(g[i*4]) | uint32(g[i*4+1])<<8 | uint32(g[i*4+2])<<16 | uint32(g[i*4+3])<<24
+		parts[i] = fmt.Sprintf("%08X", v)
+	}
+	return strings.Join(parts[:], "")
+}
@@ -0,0 +1,222 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PackOptions configures a "to-zen" operation. The zero value packs with
+// the input folder's name, priority 1, the "_p" suffix and retoc's own
+// defaults for everything else.
+type PackOptions struct {
+	ModName       string `json:"mod_name"`       // Output base name; defaults to the input folder name
+	Priority      *int   `json:"priority"`       // Load-order serial, 0-9999; nil selects the default, 1
+	PatchSuffix   *bool  `json:"patch_suffix"`   // Include the scheme's patch suffix ("_p"); nil means true
+	MountPoint    string `json:"mount_point"`    // e.g. "../../../MyGame/"; defaults to retoc's own
+	Compression   string `json:"compression"`    // "Oodle", "Zlib" or "None"; defaults to retoc's own
+	EngineVersion string `json:"engine_version"` // e.g. "UE5_4"; overrides RetocOperation.UEVersion
+	NamingPreset  string `json:"naming_preset"`  // One of NamingPresets; defaults to the profile's scheme
+	NameTemplate  string `json:"name_template"`  // Overrides the preset's template, see NamingPreset
+
+	// legacySerial is the serial of a legacy option list that is not a
+	// number from 0 to 9999, formatted as the old renaming did. It
+	// replaces {serial} in the file name.
+	legacySerial string
+}
+
+// FieldError is a validation failure for one field of a request struct.
+// Field is the JSON name of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the [FieldError]s of an invalid request.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid pack options: " + strings.Join(parts, "; ")
+}
+
+// EngineVersions lists the values accepted for [PackOptions.EngineVersion]
+// and RetocOperation.UEVersion, oldest first.
+var EngineVersions = []string{"UE4_26", "UE4_27", "UE5_0", "UE5_1", "UE5_2", "UE5_3", "UE5_4", "UE5_5"}
+
+// compressionMethods lists the values accepted for PackOptions.Compression.
+var compressionMethods = []string{"None", "Zlib", "Oodle"}
+
+// maxPriority is the largest serial that fits the four-digit name field.
+const maxPriority = 9999
+
+// ValidatePackOptions checks opts and returns one [FieldError] per invalid
+// field, or an empty slice if opts is valid. The frontend uses it to show
+// errors next to the offending inputs before starting a pack.
+func (r *RetocService) ValidatePackOptions(ctx context.Context, opts PackOptions) []FieldError {
+	return opts.validate()
+}
+
+func (o PackOptions) validate() []FieldError {
+	errs := []FieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if o.ModName != "" {
+		switch {
+		case strings.ContainsAny(o.ModName, `<>:"/\|?*`):
+			add("mod_name", `must not contain any of < > : " / \ | ? *`)
+		case strings.IndexFunc(o.ModName, func(r rune) bool { return r < 0x20 }) >= 0:
+			add("mod_name", "must not contain control characters")
+		case strings.TrimRight(o.ModName, ". ") != o.ModName:
+			add("mod_name", "must not end with a dot or space")
+		}
+	}
+	if o.Priority != nil && (*o.Priority < 0 || *o.Priority > maxPriority) {
+		add("priority", "must be between 0 and %d, got %d", maxPriority, *o.Priority)
+	}
+	if o.MountPoint != "" {
+		mp := strings.ReplaceAll(o.MountPoint, "\\", "/")
+		if !strings.HasPrefix(mp, "../../../") || strings.Contains(strings.TrimPrefix(mp, "../../../"), "..") {
+			add("mount_point", `must start with "../../../" and not contain further ".." segments`)
+		}
+	}
+	if o.Compression != "" && canonical(o.Compression, compressionMethods) == "" {
+		add("compression", "must be one of %s", strings.Join(compressionMethods, ", "))
+	}
+	if o.EngineVersion != "" && canonical(o.EngineVersion, EngineVersions) == "" {
+		add("engine_version", "must be one of %s", strings.Join(EngineVersions, ", "))
+	}
+	if _, err := resolveNaming(o.NamingPreset, ""); err != nil {
+		add("naming_preset", "%v", err)
+	}
+	if o.NameTemplate != "" {
+		if err := validateTemplate(o.NameTemplate); err != nil {
+			add("name_template", "%v", err)
+		}
+	}
+	return errs
+}
+
+// normalized returns o with defaults applied and enum values in canonical
+// case. inputPath supplies the default mod name.
+func (o PackOptions) normalized(inputPath string) PackOptions {
+	if o.ModName == "" {
+		o.ModName = filepath.Base(inputPath)
+	}
+	if o.Priority == nil {
+		priority := 1
+		o.Priority = &priority
+	}
+	if o.PatchSuffix == nil {
+		patch := true
+		o.PatchSuffix = &patch
+	}
+	o.MountPoint = strings.ReplaceAll(o.MountPoint, "\\", "/")
+	if o.Compression != "" {
+		o.Compression = canonical(o.Compression, compressionMethods)
+	}
+	if o.EngineVersion != "" {
+		o.EngineVersion = canonical(o.EngineVersion, EngineVersions)
+	}
+	return o
+}
+
+// serial formats the normalized options' serial for a {serial} placeholder
+// of the given width; a legacy serial is used as it is.
+func (o PackOptions) serial(width int) string {
+	if o.legacySerial != "" {
+		return o.legacySerial
+	}
+	return fmt.Sprintf("%0*d", width, *o.Priority)
+}
+
+// packOptions returns the operation's pack options: Pack if set, otherwise
+// the legacy "--mod-name"/"--serialization" pairs from Options, which older
+// frontends still send. UEVersion fills in EngineVersion when unset. The
+// result is validated and normalized.
+func (operation RetocOperation) packOptions() (PackOptions, error) {
+	var opts PackOptions
+	if operation.Pack != nil {
+		opts = *operation.Pack
+	} else {
+		opts = parseLegacyOptions(operation.Options)
+	}
+	if opts.EngineVersion == "" {
+		opts.EngineVersion = operation.UEVersion
+	}
+	if errs := opts.validate(); len(errs) > 0 {
+		return PackOptions{}, &ValidationError{Errors: errs}
+	}
+	return opts.normalized(operation.InputPath), nil
+}
+
+// parseLegacyOptions converts the old "--mod-name NAME --serialization
+// NNNN" option list to PackOptions, naming the output exactly as the
+// renaming that used to read the list did. A serialization starting with a
+// number is that number modulo 10000, padded to four digits, so "0" is
+// 0000, "12345" is 2345 and "7abc" is 0007; one that does not is cut or
+// zero-padded to four characters, so "abc" is 0abc. An empty one selects
+// the default. Unknown options are ignored, as they always were.
+func parseLegacyOptions(options []string) PackOptions {
+	var opts PackOptions
+	for i := 0; i+1 < len(options); i++ {
+		value := options[i+1]
+		switch options[i] {
+		case "--mod-name":
+			opts.ModName = strings.TrimSpace(value)
+		case "--serialization":
+			if value == "" {
+				break
+			}
+			var n int
+			if _, err := fmt.Sscanf(value, "%d", &n); err != nil {
+				if len(value) > 4 {
+					value = value[:4]
+				}
+				opts.legacySerial = strings.Repeat("0", 4-len(value)) + value
+			} else if n %= maxPriority + 1; n >= 0 {
+				opts.Priority = &n
+			} else {
+				opts.legacySerial = fmt.Sprintf("%04d", n)
+			}
+		default:
+			continue
+		}
+		i++
+	}
+	return opts
+}
+
+// retocArgs returns the to-zen flags for the options that override
+// retoc's defaults.
+func (o PackOptions) retocArgs() []string {
+	var args []string
+	if o.EngineVersion != "" {
+		args = append(args, "--version", o.EngineVersion)
+	}
+	if o.MountPoint != "" {
+		args = append(args, "--mount-point", o.MountPoint)
+	}
+	if o.Compression != "" {
+		args = append(args, "--compression-format", o.Compression)
+	}
+	return args
+}
+
+// canonical returns the entry of allowed equal to s ignoring case, or "".
+func canonical(s string, allowed []string) string {
+	for _, a := range allowed {
+		if strings.EqualFold(s, a) {
+			return a
+		}
+	}
+	return ""
+}
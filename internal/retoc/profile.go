@@ -0,0 +1,90 @@
+package retoc
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// applyProfile fills in the fields of operation that its game profile
+// provides and the caller left empty: the engine version and, for commands
+// that read containers, the input path (the profile's Paks directory). A
+// "to-zen" operation that still has no engine version uses the one
+// detected from the game's files, if it is detected with high confidence:
+// the profile's install or Paks directory, or without a profile the Paks
+// directory the output or input is in, such as Content/Paks/~mods.
+func (r *RetocService) applyProfile(operation RetocOperation) (RetocOperation, error) {
+	needsVersion := func() bool {
+		return operation.UEVersion == "" && operation.Command == "to-zen" && (operation.Pack == nil || operation.Pack.EngineVersion == "")
+	}
+	if operation.ProfileID == "" {
+		if needsVersion() {
+			operation.UEVersion = detectPathVersion(operation.OutputPath, operation.InputPath)
+		}
+		return operation, nil
+	}
+	profile, err := r.app.GetProfile(operation.ProfileID)
+	if err != nil {
+		return operation, err
+	}
+	if operation.UEVersion == "" {
+		operation.UEVersion = profile.UEVersion
+	}
+	if needsVersion() {
+		operation.UEVersion = detectVersion(profile.InstallDir, profile.PaksDir)
+	}
+	if operation.InputPath == "" && operation.Command != "to-zen" {
+		operation.InputPath = profile.PaksDir
+	}
+	return operation, nil
+}
+
+// detectPathVersion returns the engine version detected with high
+// confidence from the first Paks directory that contains one of paths, or
+// "". Paths outside a game are not inspected.
+func detectPathVersion(paths ...string) string {
+	var dirs []string
+	for _, p := range paths {
+		if dir := paksAncestor(p); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return detectVersion(dirs...)
+}
+
+// paksAncestor returns the nearest directory named Paks that is path or
+// contains it, or "".
+func paksAncestor(path string) string {
+	if path == "" {
+		return ""
+	}
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	for {
+		if strings.EqualFold(filepath.Base(dir), "Paks") {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// detectVersion returns the engine version detected with high confidence
+// from the first of dirs it is found in, or "".
+func detectVersion(dirs ...string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if d, err := DetectEngineVersion(dir); err == nil {
+			if version, ok := d.Best(); ok {
+				return version
+			}
+		}
+	}
+	return ""
+}
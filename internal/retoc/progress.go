@@ -0,0 +1,364 @@
+package retoc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventProgress is emitted with a [RetocProgress] snapshot whenever a line of
+// retoc output changes an operation's progress.
+const EventProgress = "retoc:progress"
+
+// Progress phases reported in [RetocProgress.Phase]. The running phases are
+// inferred from keywords in retoc's output; the terminal phases are set when
+// the process exits.
+const (
+	PhaseStarting   = "starting"
+	PhaseReading    = "reading"
+	PhaseConverting = "converting"
+	PhaseWriting    = "writing"
+	PhaseCompleted  = "completed"
+	PhaseFailed     = "failed"
+	PhaseCancelled  = "cancelled"
+)
+
+// maxRetainedProgress bounds how many finished operations remain queryable
+// through GetProgress.
+const maxRetainedProgress = 64
+
+// RetocProgress is the typed progress model for a retoc operation, built from
+// the chunk, package and byte counters retoc prints. Counters that retoc has
+// not reported are zero. ETASeconds is -1 when no estimate is available.
+type RetocProgress struct {
+	OperationID string  `json:"operation_id"`
+	Command     string  `json:"command"`
+	Phase       string  `json:"phase"`
+	ItemsDone   int64   `json:"items_done"`
+	ItemsTotal  int64   `json:"items_total"`
+	ItemUnit    string  `json:"item_unit"` // e.g. "chunks", "packages"
+	BytesDone   int64   `json:"bytes_done"`
+	BytesTotal  int64   `json:"bytes_total"`
+	Percent     float64 `json:"percent"`
+	ETASeconds  float64 `json:"eta_seconds"`
+	Elapsed     string  `json:"elapsed"`
+	Done        bool    `json:"done"`
+}
+
+// progressTracker holds the mutable progress state of one operation.
+type progressTracker struct {
+	progress   RetocProgress
+	started    time.Time
+	phaseStart time.Time
+}
+
+// progressUpdate is the information parsed from a single output line.
+type progressUpdate struct {
+	phase      string
+	itemsDone  int64
+	itemsTotal int64
+	itemUnit   string
+	bytesDone  int64
+	bytesTotal int64
+	eta        time.Duration
+	hasItems   bool
+	hasBytes   bool
+	hasETA     bool
+}
+
+var (
+	// "123/4567 chunks", "[12/40]", "packages: 3 / 9", "40/80%". The counter
+	// must stand alone, so a path ("Content/12/34") or version ("4.27/5.1")
+	// is not taken for one.
+	itemsPattern = regexp.MustCompile(`(?i)(?:^|[\s\[(:=,])(\d[\d,]*)\s*/\s*(\d[\d,]*)(?:\s*%|\s+([a-z]+))?(?:$|[\s\]),;:!]|\.(?:\s|$))`)
+	// "1.5 GiB/3.2 GiB", "512KB / 2MB"
+	bytesPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*([KMGT]i?B|B)\s*/\s*(\d+(?:\.\d+)?)\s*([KMGT]i?B|B)`)
+	// "ETA 1m20s", "eta: 00:01:20", "(eta 12s)"
+	etaPattern = regexp.MustCompile(`(?i)\beta:?\s*([0-9hms:.]+)`)
+	// A preceding word naming the counted items, e.g. "Writing chunks 3/9".
+	unitBeforePattern = regexp.MustCompile(`(?i)\b(chunks?|packages?|files?|assets?|containers?)\b[^/]*$`)
+)
+
+// phaseKeywords map words in retoc's output to phases. They match whole
+// words, so "already" is not reading and "package" is not packing.
+var phaseKeywords = []struct {
+	pattern *regexp.Regexp
+	phase   string
+}{
+	{regexp.MustCompile(`\bread(s|ing)?\b`), PhaseReading},
+	{regexp.MustCompile(`\bload(s|ed|ing)?\b`), PhaseReading},
+	{regexp.MustCompile(`\bscan(s|ned|ning)?\b`), PhaseReading},
+	{regexp.MustCompile(`\bindex(es|ed|ing)?\b`), PhaseReading},
+	{regexp.MustCompile(`\b(write|writes|writing|written|wrote)\b`), PhaseWriting},
+	{regexp.MustCompile(`\bcompress(es|ed|ing)?\b`), PhaseWriting},
+	{regexp.MustCompile(`\bserializ(e|es|ed|ing)\b`), PhaseWriting},
+	{regexp.MustCompile(`\bconvert(s|ed|ing)?\b`), PhaseConverting},
+	{regexp.MustCompile(`\bpack(s|ed|ing)?\b`), PhaseConverting},
+	{regexp.MustCompile(`\bextract(s|ed|ing)?\b`), PhaseConverting},
+}
+
+// parseProgressLine extracts phase, counters and ETA from one line of retoc
+// output. It reports false when the line carries no progress information.
+func parseProgressLine(line string) (progressUpdate, bool) {
+	var update progressUpdate
+	lower := strings.ToLower(line)
+
+	for _, pk := range phaseKeywords {
+		if pk.pattern.MatchString(lower) {
+			update.phase = pk.phase
+			break
+		}
+	}
+
+	// Byte counters are matched first so their digits are not mistaken for
+	// item counters.
+	remaining := line
+	if m := bytesPattern.FindStringSubmatchIndex(line); m != nil {
+		done, okDone := parseByteSize(line[m[2]:m[3]], line[m[4]:m[5]])
+		total, okTotal := parseByteSize(line[m[6]:m[7]], line[m[8]:m[9]])
+		if okDone && okTotal {
+			update.bytesDone, update.bytesTotal, update.hasBytes = done, total, true
+			remaining = line[:m[0]] + line[m[1]:]
+		}
+	}
+
+	if m := itemsPattern.FindStringSubmatchIndex(remaining); m != nil {
+		done, errDone := strconv.ParseInt(strings.ReplaceAll(remaining[m[2]:m[3]], ",", ""), 10, 64)
+		total, errTotal := strconv.ParseInt(strings.ReplaceAll(remaining[m[4]:m[5]], ",", ""), 10, 64)
+		if errDone == nil && errTotal == nil && total > 0 && done <= total {
+			update.itemsDone, update.itemsTotal, update.hasItems = done, total, true
+			if m[6] >= 0 {
+				update.itemUnit = normalizeUnit(remaining[m[6]:m[7]])
+			}
+			if update.itemUnit == "" {
+				if u := unitBeforePattern.FindStringSubmatch(remaining[:m[0]]); u != nil {
+					update.itemUnit = normalizeUnit(u[1])
+				}
+			}
+		}
+	}
+
+	if m := etaPattern.FindStringSubmatch(line); m != nil {
+		if eta, ok := parseETA(m[1]); ok {
+			update.eta, update.hasETA = eta, true
+		}
+	}
+
+	return update, update.hasItems || update.hasBytes || update.hasETA || update.phase != ""
+}
+
+// normalizeUnit returns the plural form of a recognised item unit, or an
+// empty string for words that are not item units.
+func normalizeUnit(word string) string {
+	word = strings.ToLower(word)
+	switch strings.TrimSuffix(word, "s") {
+	case "chunk", "package", "file", "asset", "container":
+		return strings.TrimSuffix(word, "s") + "s"
+	}
+	return ""
+}
+
+func parseByteSize(value, unit string) (int64, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	multipliers := map[string]float64{
+		"b":   1,
+		"kb":  1e3,
+		"mb":  1e6,
+		"gb":  1e9,
+		"tb":  1e12,
+		"kib": 1 << 10,
+		"mib": 1 << 20,
+		"gib": 1 << 30,
+		"tib": 1 << 40,
+	}
+	m, ok := multipliers[strings.ToLower(unit)]
+	if !ok {
+		return 0, false
+	}
+	return int64(f * m), true
+}
+
+// parseETA accepts Go-style durations ("1m20s") and clock values ("01:20",
+// "00:01:20").
+func parseETA(s string) (time.Duration, bool) {
+	s = strings.TrimRight(s, ".")
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, false
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, true
+}
+
+// apply merges an update into the tracker and recomputes derived fields.
+func (t *progressTracker) apply(update progressUpdate, now time.Time) {
+	p := &t.progress
+
+	if update.phase != "" && update.phase != p.Phase {
+		p.Phase = update.phase
+		t.phaseStart = now
+	}
+	if update.hasItems {
+		p.ItemsDone, p.ItemsTotal = update.itemsDone, update.itemsTotal
+		if update.itemUnit != "" {
+			p.ItemUnit = update.itemUnit
+		}
+	}
+	if update.hasBytes {
+		p.BytesDone, p.BytesTotal = update.bytesDone, update.bytesTotal
+	}
+
+	switch {
+	case p.BytesTotal > 0:
+		p.Percent = 100 * float64(p.BytesDone) / float64(p.BytesTotal)
+	case p.ItemsTotal > 0:
+		p.Percent = 100 * float64(p.ItemsDone) / float64(p.ItemsTotal)
+	}
+
+	p.ETASeconds = -1
+	if update.hasETA {
+		p.ETASeconds = update.eta.Seconds()
+	} else if p.Percent > 0 && p.Percent < 100 {
+		elapsed := now.Sub(t.phaseStart).Seconds()
+		p.ETASeconds = elapsed * (100 - p.Percent) / p.Percent
+	}
+
+	p.Elapsed = now.Sub(t.started).String()
+}
+
+// startProgress begins tracking progress for an operation.
+func (r *RetocService) startProgress(operationID, command string) {
+	now := time.Now()
+	tracker := &progressTracker{
+		progress: RetocProgress{
+			OperationID: operationID,
+			Command:     command,
+			Phase:       PhaseStarting,
+			ETASeconds:  -1,
+		},
+		started:    now,
+		phaseStart: now,
+	}
+
+	r.progressMutex.Lock()
+	r.progress[operationID] = tracker
+	r.progressMutex.Unlock()
+
+	r.app.Emit(EventProgress, tracker.progress)
+}
+
+// trackProgress feeds one output line into the operation's progress and
+// emits a snapshot if the line carried progress information.
+func (r *RetocService) trackProgress(operationID, line string) {
+	update, ok := parseProgressLine(line)
+	if !ok {
+		return
+	}
+
+	r.progressMutex.Lock()
+	tracker, exists := r.progress[operationID]
+	if !exists || tracker.progress.Done {
+		r.progressMutex.Unlock()
+		return
+	}
+	tracker.apply(update, time.Now())
+	snapshot := tracker.progress
+	r.progressMutex.Unlock()
+
+	r.app.Emit(EventProgress, snapshot)
+}
+
+// finishProgress marks an operation's progress as terminal with the given
+// phase and keeps it queryable until it ages out of the retention window.
+func (r *RetocService) finishProgress(operationID, phase string) {
+	r.progressMutex.Lock()
+	tracker, exists := r.progress[operationID]
+	if !exists {
+		r.progressMutex.Unlock()
+		return
+	}
+	p := &tracker.progress
+	p.Phase = phase
+	p.Done = true
+	p.ETASeconds = 0
+	p.Elapsed = time.Since(tracker.started).String()
+	if phase == PhaseCompleted {
+		p.Percent = 100
+	}
+	snapshot := *p
+
+	r.finishedProgress = append(r.finishedProgress, operationID)
+	for len(r.finishedProgress) > maxRetainedProgress {
+		delete(r.progress, r.finishedProgress[0])
+		r.finishedProgress = r.finishedProgress[1:]
+	}
+	r.progressMutex.Unlock()
+
+	r.app.Emit(EventProgress, snapshot)
+}
+
+// GetProgress returns the latest progress snapshot for the given operation.
+// Finished operations remain queryable for a while after they complete.
+func (r *RetocService) GetProgress(ctx context.Context, operationID string) (RetocProgress, error) {
+	r.progressMutex.Lock()
+	defer r.progressMutex.Unlock()
+
+	tracker, exists := r.progress[operationID]
+	if !exists {
+		return RetocProgress{}, fmt.Errorf("no progress recorded for operation %s", operationID)
+	}
+	return tracker.progress, nil
+}
+
+// SubscribeProgress registers handler to receive every progress snapshot
+// emitted by RetocService. The returned function removes the subscription.
+func (r *RetocService) SubscribeProgress(handler func(RetocProgress)) func() {
+	return r.app.Subscribe(EventProgress, func(data any) {
+		if p, ok := data.(RetocProgress); ok {
+			handler(p)
+		}
+	})
+}
+
+// scanLinesOrCR is a bufio.SplitFunc that splits on '\n', "\r\n" or a bare
+// '\r', so that progress bars redrawn in place still arrive as lines.
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		advance = i + 1
+		if data[i] == '\r' {
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					advance++
+				}
+			} else if !atEOF {
+				// Need more data to know whether '\n' follows.
+				return 0, nil, nil
+			}
+		}
+		return advance, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -5,40 +5,81 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/JaceTheGrayOne/ARI-S/internal/app"
-	"github.com/google/uuid"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
 )
 
 // RetocService wraps the retoc.exe tool for Unreal Engine IoStore package
 // operations. It supports packing (legacy to zen), unpacking (zen to legacy),
-// and package inspection commands. Operations run asynchronously and can be
-// cancelled via operation ID or command type.
+// and package inspection commands. Operations are registered with the App's
+// operation registry as owner [OperationOwner] and can be cancelled via
+// operation ID or command type.
 //
 // RetocService is safe for concurrent use by multiple goroutines.
 type RetocService struct {
-	app               *app.App
-	depsDir           string // Path to extracted dependencies
-	runningOperations map[string]context.CancelFunc
-	// Track operations by command type so we can cancel specific operation types
-	operationsByCommand map[string]string // command -> operationID
-	operationsMutex     sync.Mutex
+	app     *app.App
+	depsDir string                // Path to extracted dependencies
+	runner  toolrunner.ToolRunner // nil selects toolrunner.ForApp
+
+	progress         map[string]*progressTracker // operationID -> progress
+	finishedProgress []string                    // finished operation IDs, oldest first
+	progressMutex    sync.Mutex
 }
 
 // RetocOperation describes a retoc command to execute. The Command field must
-// be one of: "to-zen", "to-legacy", "unpack", "info", or "list". The Options
-// field is used for mod naming and serialization when packing.
+// be one of: "to-zen", "to-legacy", "unpack", "info", or "list". Pack
+// configures "to-zen"; when it is nil the legacy Options list
+// ("--mod-name NAME --serialization NNNN") is used instead, and names the
+// output as it always did; see [parseLegacyOptions].
+//
+// Include and Exclude restrict "unpack" and "to-legacy" to the packages
+// whose long package name matches, e.g. "/Game/Data/**/DT_*". See
+// [packageFilter] for the glob syntax.
+//
+// DryRun plans the operation without running it: the result's Plan
+// holds the retoc invocation and, for "to-zen", the files it would write
+// and replace. A dry run always plans a retoc invocation, even for
+// operations that would be answered in-process.
+//
+// "to-zen" writes a manifest of its input, options and output next to the
+// output (see [PackChanges]). Packing the same input to the same output
+// again returns the last pack's files without running retoc when nothing
+// changed, unless Force is set. A dry run still returns its Plan, with the
+// Changes that show whether the real run would be skipped.
+//
+// ProfileID selects a game profile. Its engine version and Paks directory
+// fill in UEVersion and InputPath when they are empty; if the profile has
+// no engine version, "to-zen" uses the one [DetectEngineVersion] finds
+// with high confidence. Without a profile, "to-zen" with no engine version
+// uses the one detected from the Paks directory its output or input is in,
+// such as Content/Paks/~mods, and otherwise leaves the choice to retoc.
+// The profile's naming scheme names "to-zen" output. The
+// profile's unlocked AES keys are checked against the encrypted containers
+// in the input of the commands that read containers before anything runs,
+// and passed to retoc.exe as --aes-key. retoc takes one key per run, so an
+// input whose containers use different keys is run one container at a
+// time, each with its own key. "to-zen" uses no keys, so packing works
+// while the profile's vault is locked.
 type RetocOperation struct {
-	Command    string   `json:"command"`
-	InputPath  string   `json:"input_path"`
-	OutputPath string   `json:"output_path"`
-	UEVersion  string   `json:"ue_version"`
-	Options    []string `json:"options"`
+	Command    string       `json:"command"`
+	InputPath  string       `json:"input_path"`
+	OutputPath string       `json:"output_path"`
+	UEVersion  string       `json:"ue_version"`
+	Options    []string     `json:"options"` // Deprecated: use Pack
+	Pack       *PackOptions `json:"pack,omitempty"`
+	Include    []string     `json:"include"` // Package path globs to extract; empty means all
+	Exclude    []string     `json:"exclude"` // Package path globs to skip
+	ProfileID  string       `json:"profile_id"`
+	DryRun     bool         `json:"dry_run"`
+	Force      bool         `json:"force"` // Pack even if nothing changed
 }
 
 // RetocResult contains the outcome of a retoc operation. The OperationID can
@@ -51,24 +92,92 @@ type RetocResult struct {
 	Error       string `json:"error"`
 	Duration    string `json:"duration"`
 	OperationID string `json:"operation_id"` // ID for tracking/cancelling
+
+	Plan *toolrunner.Plan `json:"plan,omitempty"` // Set for dry runs
+
+	// For "to-zen": the packed files, whether they are the last pack's,
+	// returned because nothing changed, and what changed since that pack
+	// (nil for the first pack with a manifest)
+	OutputFiles []string     `json:"output_files,omitempty"`
+	Cached      bool         `json:"cached"`
+	Changes     *PackChanges `json:"changes,omitempty"`
 }
 
-// NewRetocService creates a new RetocService using the retoc.exe binary
+// OperationOwner is the owner of the operations RetocService registers
+// with the App.
+const OperationOwner = "retoc"
+
+// Event names emitted by RetocService. Payloads are [RetocStarted],
+// [RetocOutputLine] and [RetocFinished] respectively.
+const (
+	EventStarted  = "retoc:started"
+	EventOutput   = "retoc:output"
+	EventFinished = "retoc:finished"
+)
+
+// Output stream identifiers used in [RetocOutputLine.Stream].
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// RetocStarted is emitted when retoc is launched for an operation.
+type RetocStarted struct {
+	OperationID string `json:"operation_id"`
+	Command     string `json:"command"`
+}
+
+// RetocFinished is emitted when an operation ends, successfully or not.
+// Operation is the operation as run, with the fields its game profile
+// provides filled in, so running it again repeats it. OutputFiles lists
+// the packed files of a successful "to-zen".
+type RetocFinished struct {
+	Operation   RetocOperation `json:"operation"`
+	StartedAt   time.Time      `json:"started_at"`
+	Result      RetocResult    `json:"result"`
+	OutputFiles []string       `json:"output_files"`
+}
+
+// RetocOutputLine is a single line written by retoc.exe. Lines are emitted as
+// they are read, so stdout and stderr lines of one operation may interleave.
+type RetocOutputLine struct {
+	OperationID string `json:"operation_id"`
+	Stream      string `json:"stream"` // StreamStdout or StreamStderr
+	Line        string `json:"line"`
+}
+
+// NewRetocService creates a new RetocService using the retoc binary
 // located in the given depsDir. The depsDir should contain a "retoc"
-// subdirectory with retoc.exe and oo2core_9_win64.dll.
+// subdirectory with retoc.exe and oo2core_9_win64.dll. retoc is run with
+// the runner the App's preferences select; see [toolrunner.ForApp].
 func NewRetocService(a *app.App, depsDir string) *RetocService {
+	return NewRetocServiceWithRunner(a, depsDir, nil)
+}
+
+// NewRetocServiceWithRunner is like [NewRetocService] but always runs
+// retoc with runner. A nil runner follows the App's preferences.
+func NewRetocServiceWithRunner(a *app.App, depsDir string, runner toolrunner.ToolRunner) *RetocService {
 	return &RetocService{
-		app:                 a,
-		depsDir:             depsDir,
-		runningOperations:   make(map[string]context.CancelFunc),
-		operationsByCommand: make(map[string]string),
+		app:      a,
+		depsDir:  depsDir,
+		runner:   runner,
+		progress: make(map[string]*progressTracker),
+	}
+}
+
+// toolRunner returns the runner retoc is run with.
+func (r *RetocService) toolRunner() toolrunner.ToolRunner {
+	if r.runner != nil {
+		return r.runner
 	}
+	return toolrunner.ForApp(r.app)
 }
 
 // RunRetoc executes a retoc operation based on the given RetocOperation.
 // The operation runs asynchronously and can be cancelled via the returned
 // OperationID. For "to-zen" commands, the output files are automatically
-// renamed to follow the UE mod naming convention (z_modname_0001_p.*).
+// renamed using the operation's naming scheme (z_modname_0001_p.* unless the
+// pack options or the game profile select another preset).
 //
 // Supported commands:
 //   - "to-zen": Convert legacy assets to IoStore format
@@ -76,137 +185,236 @@ func NewRetocService(a *app.App, depsDir string) *RetocService {
 //   - "unpack": Extract IoStore package contents
 //   - "info": Display package metadata
 //   - "list": List package contents
-func (r *RetocService) RunRetoc(ctx context.Context, operation RetocOperation) RetocResult {
+//
+// "info" and "list" are answered in-process from the .utoc files when the
+// input contains any, without invoking retoc.exe. A filtered "unpack" is
+// also done in-process when every selected chunk is uncompressed or
+// Zlib-compressed and unencrypted; otherwise the filter is passed to
+// retoc.exe and files it writes for unselected packages are removed.
+func (r *RetocService) RunRetoc(ctx context.Context, operation RetocOperation) (result RetocResult) {
 	startTime := time.Now()
 
-	// Generate unique operation ID
-	operationID := uuid.New().String()
-
-	// Create a cancellable context
-	ctx, cancel := context.WithCancel(ctx)
-
-	// Store cancel function and track by command type
-	r.operationsMutex.Lock()
-	r.runningOperations[operationID] = cancel
-	r.operationsByCommand[operation.Command] = operationID
-	r.operationsMutex.Unlock()
-
-	// Ensure cleanup on exit
+	// Register the operation; its context is cancelled by CancelOperation
+	op := r.app.StartOperation(ctx, OperationOwner, operation.Command, operation.InputPath)
+	ctx = op.Context()
+	operationID := op.ID()
+	var outputFiles []string
 	defer func() {
-		r.operationsMutex.Lock()
-		delete(r.runningOperations, operationID)
-		// Only delete from operationsByCommand if this is still the current operation for this command
-		if r.operationsByCommand[operation.Command] == operationID {
-			delete(r.operationsByCommand, operation.Command)
-		}
-		r.operationsMutex.Unlock()
+		op.FinishResult(result.Success, result.Error)
+		r.app.Emit(EventFinished, RetocFinished{
+			Operation:   operation,
+			StartedAt:   startTime,
+			Result:      result,
+			OutputFiles: outputFiles,
+		})
 	}()
 
-	// Use the extracted dependencies directory
-	// Look for retoc.exe in the retoc subfolder
-	retocPath := filepath.Join(r.depsDir, "retoc", "retoc.exe")
-
-	// Check if retoc.exe exists
-	if _, err := os.Stat(retocPath); os.IsNotExist(err) {
+	operation, err := r.applyProfile(operation)
+	if err != nil {
 		return RetocResult{
 			Success:     false,
-			Error:       fmt.Sprintf("retoc.exe not found at: %s", retocPath),
+			Error:       err.Error(),
+			Duration:    time.Since(startTime).String(),
 			OperationID: operationID,
 		}
 	}
 
-	// Build command arguments
-	args := []string{}
-
-	switch operation.Command {
-	case "to-legacy":
-		args = append(args, "to-legacy", operation.InputPath, operation.OutputPath)
-	case "to-zen":
-		// Build: retoc.exe to-zen --version UE5_4 "inputdirectory" "outputdirectory\basename.utoc"
-		// retoc requires a .utoc filename as the output parameter, not just a directory
-		args = append(args, "to-zen")
-		if operation.UEVersion != "" {
-			args = append(args, "--version", operation.UEVersion)
-		}
-
-		// Get the base name from the input folder
-		inputBaseName := filepath.Base(operation.InputPath)
-		// Construct output path with .utoc extension (retoc will create .utoc, .ucas, and .pak)
-		outputFilePath := filepath.Join(operation.OutputPath, inputBaseName+".utoc")
-
-		args = append(args, operation.InputPath, outputFilePath)
-	case "unpack":
-		args = append(args, "unpack", operation.InputPath, operation.OutputPath)
-	case "info":
-		args = append(args, "info", operation.InputPath)
-	case "list":
-		args = append(args, "list", operation.InputPath)
-	default:
+	filter, err := newPackageFilter(operation.Include, operation.Exclude)
+	if err != nil {
 		return RetocResult{
 			Success:     false,
-			Error:       fmt.Sprintf("Unknown command: %s", operation.Command),
+			Error:       err.Error(),
+			Duration:    time.Since(startTime).String(),
 			OperationID: operationID,
 		}
 	}
+	filtered := filter != nil && (operation.Command == "unpack" || operation.Command == "to-legacy")
+
+	var pack PackOptions
+	if operation.Command == "to-zen" {
+		if pack, err = operation.packOptions(); err != nil {
+			return RetocResult{
+				Success:     false,
+				Error:       err.Error(),
+				Duration:    time.Since(startTime).String(),
+				OperationID: operationID,
+			}
+		}
+	}
 
-	// Note: We don't add operation.Options anymore since retoc doesn't use --priority
-
-	// Create command
-	cmd := exec.CommandContext(ctx, retocPath, args...)
+	// Check the profile's AES keys against the input's encrypted containers
+	// up front; a wrong key would otherwise fail deep inside retoc. Packing
+	// reads no containers, so it needs no keys and works with the vault
+	// locked.
+	var keys []config.AESKey
+	inputs := []keyedInput{{path: operation.InputPath}}
+	if operation.ProfileID != "" && readsContainers(operation.Command) {
+		keys, err = r.app.GameKeys(operation.ProfileID)
+		if err == nil && len(keys) > 0 {
+			inputs, err = keyedInputs(operation.InputPath, keys)
+		}
+		if err == nil && len(inputs) > 1 && operation.Command == "to-legacy" && strings.EqualFold(filepath.Ext(operation.OutputPath), ".pak") {
+			err = fmt.Errorf("the input uses more than one AES key, so it is converted one container at a time and needs an output directory, not a .pak file")
+		}
+		if err != nil {
+			return RetocResult{
+				Success:     false,
+				Error:       fmt.Sprintf("AES key check failed: %v", err),
+				Duration:    time.Since(startTime).String(),
+				OperationID: operationID,
+			}
+		}
+	}
 
-	// Set working directory to the directory containing retoc.exe
-	cmd.Dir = filepath.Dir(retocPath)
+	// Read-only commands are answered natively from the .utoc files when
+	// possible, so they work without retoc.exe and on any OS
+	if !operation.DryRun && (operation.Command == "info" || operation.Command == "list") {
+		if result, handled := r.runNativeInspect(operation, keys, operationID, startTime); handled {
+			return result
+		}
+	}
+	if !operation.DryRun && filtered && operation.Command == "unpack" {
+		if result, handled := r.runNativeUnpack(ctx, operation, filter, keys, operationID, startTime); handled {
+			return result
+		}
+	}
 
-	// Set up pipes for streaming output
-	stdout, err := cmd.StdoutPipe()
+	// Use the extracted dependencies directory
+	// Look for retoc in the retoc subfolder
+	runner := r.toolRunner()
+	retocPath, err := runner.Resolve(filepath.Join(r.depsDir, "retoc"), "retoc")
 	if err != nil {
 		return RetocResult{
 			Success:     false,
-			Error:       fmt.Sprintf("Failed to create stdout pipe: %v", err),
+			Error:       err.Error(),
+			Duration:    time.Since(startTime).String(),
 			OperationID: operationID,
 		}
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return RetocResult{
-			Success:     false,
-			Error:       fmt.Sprintf("Failed to create stderr pipe: %v", err),
-			OperationID: operationID,
+	// Build the command arguments of each run
+	var runs [][]string
+	for _, in := range inputs {
+		args, err := retocArgs(operation, in, pack, filter, filtered)
+		if err != nil {
+			return RetocResult{
+				Success:     false,
+				Error:       err.Error(),
+				Duration:    time.Since(startTime).String(),
+				OperationID: operationID,
+			}
+		}
+		runs = append(runs, args)
+	}
+
+	// Skip packing an input that has not changed since it was last packed
+	// to this output. The manifest is best-effort: if it cannot be read or
+	// the input hashed, the pack runs and retoc reports any real problem.
+	var cache *packCache
+	var changes *PackChanges
+	if operation.Command == "to-zen" {
+		if cache, err = r.checkPackCache(operation, pack, runs[0]); err != nil {
+			log.Printf("Pack manifest check failed, packing everything: %v", err)
+			cache = nil
+		} else if cache.previous != nil {
+			changes = &cache.changes
+		}
+		if cache != nil && cache.unchanged() && !operation.Force && !operation.DryRun {
+			outputFiles = cache.outputs()
+			return RetocResult{
+				Success:     true,
+				Message:     "Nothing changed since the last pack; returning its output",
+				Duration:    time.Since(startTime).String(),
+				OperationID: operationID,
+				OutputFiles: outputFiles,
+				Cached:      true,
+				Changes:     changes,
+			}
 		}
 	}
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
+	if operation.DryRun {
+		plan := runner.Plan(toolrunner.Command{Path: retocPath, Args: runs[0]})
+		if operation.Command == "to-zen" {
+			if plan.Outputs, plan.Overwrites, err = r.packPlan(operation); err != nil {
+				return RetocResult{
+					Success:     false,
+					Error:       err.Error(),
+					Duration:    time.Since(startTime).String(),
+					OperationID: operationID,
+				}
+			}
+		}
+		message := "Dry run: retoc was not run"
+		if len(runs) > 1 {
+			message += fmt.Sprintf("; the input uses more than one AES key, so retoc would run %d times, once per container, and the plan shows the first", len(runs))
+		}
+		if cache != nil && cache.unchanged() && !operation.Force {
+			message += "; nothing changed since the last pack, so running it would return that pack's output"
+		}
 		return RetocResult{
-			Success:     false,
-			Error:       fmt.Sprintf("Failed to start retoc: %v", err),
+			Success:     true,
+			Message:     message,
+			Duration:    time.Since(startTime).String(),
 			OperationID: operationID,
+			Plan:        &plan,
+			Changes:     changes,
 		}
 	}
 
+	// Remember what was already in the output directory so that pruning a
+	// filtered run never deletes files it did not write
+	var existingOutput map[string]bool
+	if filtered {
+		existingOutput = snapshotFiles(operation.OutputPath)
+	}
+
+	r.app.Emit(EventStarted, RetocStarted{
+		OperationID: operationID,
+		Command:     operation.Command,
+	})
+	r.startProgress(operationID, operation.Command)
+
 	// Stream output in real-time to the frontend
+	output := &outputCollector{}
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	go r.streamOutput(stdout, operationID, &wg)
-	go r.streamOutput(stderr, operationID, &wg)
+	go r.streamOutput(stdout, operationID, StreamStdout, output, &wg)
+	go r.streamOutput(stderr, operationID, StreamStderr, output, &wg)
+
+	// Run retoc in the directory containing it, once per run, stopping at
+	// the first that fails
+	for _, args := range runs {
+		err = runner.Run(ctx, toolrunner.Command{
+			Path:   retocPath,
+			Args:   args,
+			Stdout: stdoutWriter,
+			Stderr: stderrWriter,
+		})
+		if err != nil {
+			break
+		}
+	}
 
 	// Wait for streams to finish
+	stdoutWriter.Close()
+	stderrWriter.Close()
 	wg.Wait()
 
-	// Wait for command to complete
-	err = cmd.Wait()
-
 	duration := time.Since(startTime)
 
-	result := RetocResult{
+	result = RetocResult{
+		Output:      output.String(),
 		Duration:    duration.String(),
 		OperationID: operationID,
 	}
 
 	// Check if cancelled
 	if ctx.Err() == context.Canceled {
+		r.finishProgress(operationID, PhaseCancelled)
 		result.Success = false
 		result.Message = "Operation cancelled by user"
 		result.Error = "cancelled"
@@ -214,10 +422,12 @@ func (r *RetocService) RunRetoc(ctx context.Context, operation RetocOperation) R
 	}
 
 	if err != nil {
+		r.finishProgress(operationID, PhaseFailed)
 		result.Success = false
 		result.Error = err.Error()
 		result.Message = "Retoc operation failed"
 	} else {
+		r.finishProgress(operationID, PhaseCompleted)
 		result.Success = true
 		result.Message = "Retoc operation completed successfully"
 
@@ -227,123 +437,266 @@ func (r *RetocService) RunRetoc(ctx context.Context, operation RetocOperation) R
 			if renameErr != nil {
 				result.Message += fmt.Sprintf(" (Warning: File renaming failed: %v)", renameErr)
 			}
+			outputFiles = r.packedFiles(operation)
+			result.OutputFiles = outputFiles
+			result.Changes = changes
+			// The renamed files are only this input's pack if the rename
+			// succeeded; otherwise they are a previous pack's
+			if cache != nil && renameErr == nil {
+				if err := cache.save(outputFiles); err != nil {
+					result.Message += fmt.Sprintf(" (Warning: Writing the pack manifest failed: %v)", err)
+				}
+			}
+		}
+
+		// retoc's filter is coarser than our globs; drop anything extra
+		if filtered {
+			if _, pruneErr := filter.pruneOutput(operation.OutputPath, existingOutput); pruneErr != nil {
+				result.Message += fmt.Sprintf(" (Warning: Removing unmatched files failed: %v)", pruneErr)
+			}
 		}
 	}
 
 	return result
 }
 
-// renameOutputFiles renames the output files from a to-zen operation to follow
-// the Unreal Engine mod naming convention: z_modname_0001_p.* where the prefix
-// ensures load priority and the serialization number controls load order.
-func (r *RetocService) renameOutputFiles(operation RetocOperation) error {
-	// Get the input folder name (retoc uses this as the default output name)
-	inputFolderName := filepath.Base(operation.InputPath)
+// readsContainers reports whether command reads IoStore containers, and so
+// needs the game's AES keys for encrypted ones.
+func readsContainers(command string) bool {
+	switch command {
+	case "unpack", "to-legacy", "info", "list":
+		return true
+	}
+	return false
+}
 
-	// Get the mod name and serialization from the options, with defaults
-	modName := inputFolderName
-	serialization := "0001"
+// retocArgs returns the arguments of one retoc.exe run of operation on
+// in.path, passing in.key as --aes-key when set. filtered says whether the
+// run is restricted to the packages filter selects.
+func retocArgs(operation RetocOperation, in keyedInput, pack PackOptions, filter *packageFilter, filtered bool) ([]string, error) {
+	args := []string{}
+	if in.key != nil {
+		args = append(args, "--aes-key", in.key.Key)
+	}
 
-	// Parse options to get mod name and serialization if provided
-	for i, opt := range operation.Options {
-		if opt == "--mod-name" && i+1 < len(operation.Options) {
-			if operation.Options[i+1] != "" {
-				modName = operation.Options[i+1]
-			}
+	switch operation.Command {
+	case "to-legacy":
+		args = append(args, "to-legacy")
+		if filtered {
+			args = append(args, filter.retocArgs()...)
 		}
-		if opt == "--serialization" && i+1 < len(operation.Options) {
-			if operation.Options[i+1] != "" {
-				// Ensure serialization is exactly 4 digits (pad with zeros or truncate)
-				rawSerial := operation.Options[i+1]
-				// Parse as integer and format with zero-padding to exactly 4 digits
-				var val int
-				if _, err := fmt.Sscanf(rawSerial, "%d", &val); err == nil {
-					serialization = fmt.Sprintf("%04d", val%10000) // Limit to 4 digits max
-				} else {
-					// If not a valid number, truncate or pad the string
-					if len(rawSerial) >= 4 {
-						serialization = rawSerial[:4]
-					} else {
-						// Pad with leading zeros
-						serialization = fmt.Sprintf("%04s", rawSerial)
-						// fmt pads with spaces, so manually pad with zeros
-						for len(serialization) > 0 && serialization[0] == ' ' {
-							serialization = "0" + serialization[1:]
-						}
-					}
-				}
-			}
+		args = append(args, in.path, operation.OutputPath)
+	case "to-zen":
+		// Build: retoc.exe to-zen --version UE5_4 "inputdirectory" "outputdirectory\basename.utoc"
+		// retoc requires a .utoc filename as the output parameter, not just a directory
+		args = append(args, "to-zen")
+		args = append(args, pack.retocArgs()...)
+
+		// Get the base name from the input folder
+		inputBaseName := filepath.Base(in.path)
+		// Construct output path with .utoc extension (retoc will create .utoc, .ucas, and .pak)
+		outputFilePath := filepath.Join(operation.OutputPath, inputBaseName+".utoc")
+
+		args = append(args, in.path, outputFilePath)
+	case "unpack":
+		args = append(args, "unpack")
+		if filtered {
+			args = append(args, filter.retocArgs()...)
 		}
+		args = append(args, in.path, operation.OutputPath)
+	case "info":
+		args = append(args, "info", in.path)
+	case "list":
+		args = append(args, "list", in.path)
+	default:
+		return nil, fmt.Errorf("Unknown command: %s", operation.Command)
+	}
+	return args, nil
+}
+
+// renameOutputFiles renames the output files from a to-zen operation, which
+// retoc names after the input folder, using the operation's naming scheme
+// (z_modname_0001_p.* by default; see [NamingPresets]). A .sig file is
+// renamed along with the .utoc, .ucas and .pak when present.
+func (r *RetocService) renameOutputFiles(operation RetocOperation) error {
+	pack, err := operation.packOptions()
+	if err != nil {
+		return err
+	}
+	newName, err := r.packBaseName(operation.ProfileID, pack)
+	if err != nil {
+		return err
 	}
 
-	// Get the output directory (OutputPath should be just a directory for to-zen)
+	inputFolderName := filepath.Base(operation.InputPath)
 	outputDir := operation.OutputPath
 
-	// Define the old and new file names
-	oldFiles := []string{
-		filepath.Join(outputDir, inputFolderName+".utoc"),
-		filepath.Join(outputDir, inputFolderName+".ucas"),
-		filepath.Join(outputDir, inputFolderName+".pak"),
+	for _, ext := range packedExtensions {
+		oldFile := filepath.Join(outputDir, inputFolderName+ext)
+		newFile := filepath.Join(outputDir, newName+ext)
+		if _, err := os.Stat(oldFile); err == nil {
+			if err := os.Rename(oldFile, newFile); err != nil {
+				return fmt.Errorf("failed to rename %s to %s: %v", oldFile, newFile, err)
+			}
+		}
 	}
 
-	newFiles := []string{
-		filepath.Join(outputDir, fmt.Sprintf("z_%s_%s_p.utoc", modName, serialization)),
-		filepath.Join(outputDir, fmt.Sprintf("z_%s_%s_p.ucas", modName, serialization)),
-		filepath.Join(outputDir, fmt.Sprintf("z_%s_%s_p.pak", modName, serialization)),
+	return nil
+}
+
+// packPlan returns the files a to-zen operation writes, under the names
+// renameOutputFiles gives them, with its manifest, and the existing files
+// it replaces: those outputs, and retoc's own output for the input folder,
+// which it writes first.
+func (r *RetocService) packPlan(operation RetocOperation) (outputs, overwrites []string, err error) {
+	pack, err := operation.packOptions()
+	if err != nil {
+		return nil, nil, err
 	}
+	newName, err := r.packBaseName(operation.ProfileID, pack)
+	if err != nil {
+		return nil, nil, err
+	}
+	inputFolderName := filepath.Base(operation.InputPath)
 
-	// Rename each file
-	for i, oldFile := range oldFiles {
-		if _, err := os.Stat(oldFile); err == nil {
-			err := os.Rename(oldFile, newFiles[i])
-			if err != nil {
-				return fmt.Errorf("failed to rename %s to %s: %v", oldFile, newFiles[i], err)
+	for _, ext := range packedExtensions {
+		retocFile := filepath.Join(operation.OutputPath, inputFolderName+ext)
+		newFile := filepath.Join(operation.OutputPath, newName+ext)
+		_, retocErr := os.Stat(retocFile)
+		_, newErr := os.Stat(newFile)
+		if ext == ".sig" {
+			// retoc writes no .sig; one already there is renamed along
+			if retocErr == nil && newFile != retocFile && newErr == nil {
+				overwrites = append(overwrites, newFile)
 			}
+			continue
+		}
+		outputs = append(outputs, newFile)
+		if retocErr == nil && newFile != retocFile {
+			overwrites = append(overwrites, retocFile)
 		}
+		if newErr == nil {
+			overwrites = append(overwrites, newFile)
+		}
+	}
+	manifest := filepath.Join(operation.OutputPath, newName+manifestExtension)
+	outputs = append(outputs, manifest)
+	if _, err := os.Stat(manifest); err == nil {
+		overwrites = append(overwrites, manifest)
 	}
+	return outputs, overwrites, nil
+}
 
+// packedFiles returns the paths of the files a successful to-zen operation
+// left in its output directory, under the name renameOutputFiles gave them
+// or, if renaming failed, the input folder's name.
+func (r *RetocService) packedFiles(operation RetocOperation) []string {
+	names := []string{filepath.Base(operation.InputPath)}
+	if pack, err := operation.packOptions(); err == nil {
+		if newName, err := r.packBaseName(operation.ProfileID, pack); err == nil {
+			names = append([]string{newName}, names...)
+		}
+	}
+	for _, name := range names {
+		var files []string
+		for _, ext := range packedExtensions {
+			path := filepath.Join(operation.OutputPath, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+			}
+		}
+		if len(files) > 0 {
+			return files
+		}
+	}
 	return nil
 }
 
-func (r *RetocService) streamOutput(reader io.Reader, operationID string, wg *sync.WaitGroup) {
+// streamOutput reads lines from reader until EOF, appending each to output
+// and emitting it as an [EventOutput] event tagged with the operation ID
+// and stream name. Each line is also fed to the operation's progress tracker.
+func (r *RetocService) streamOutput(reader io.Reader, operationID, stream string, output *outputCollector, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanLinesOrCR)
 	for scanner.Scan() {
 		line := scanner.Text()
-		// TODO: Emit event to frontend with the output line
-		// For now, just print to stdout
-		fmt.Printf("[retoc:%s] %s\n", operationID, line)
+		r.emitOutputLine(operationID, stream, line, output)
+		r.trackProgress(operationID, line)
 	}
+	// Keep draining after an overlong line so the tool never blocks
+	io.Copy(io.Discard, reader)
 }
 
-// CancelOperation attempts to cancel the operation with the given ID. If the
-// operation is not found or has already completed, an error is returned.
-func (r *RetocService) CancelOperation(ctx context.Context, operationID string) error {
-	r.operationsMutex.Lock()
-	cancel, exists := r.runningOperations[operationID]
-	r.operationsMutex.Unlock()
+// emitOutputLine appends line to output and emits it as an [EventOutput]
+// event.
+func (r *RetocService) emitOutputLine(operationID, stream, line string, output *outputCollector) {
+	output.add(line)
+	r.app.Emit(EventOutput, RetocOutputLine{
+		OperationID: operationID,
+		Stream:      stream,
+		Line:        line,
+	})
+}
+
+// SubscribeOutput registers handler to receive every output line from every
+// retoc operation, in the same form the frontend receives them. Callers that
+// only care about one operation should filter on OperationID. The returned
+// function removes the subscription.
+func (r *RetocService) SubscribeOutput(handler func(RetocOutputLine)) func() {
+	return r.app.Subscribe(EventOutput, func(data any) {
+		if line, ok := data.(RetocOutputLine); ok {
+			handler(line)
+		}
+	})
+}
+
+// outputCollector accumulates output lines from concurrent stream readers
+// in arrival order.
+type outputCollector struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (c *outputCollector) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf.WriteString(line)
+	c.buf.WriteByte('\n')
+}
+
+func (c *outputCollector) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
 
-	if !exists {
+// CancelOperation attempts to cancel the retoc operation with the given ID.
+// If the operation is not found or has already completed, an error is
+// returned.
+func (r *RetocService) CancelOperation(ctx context.Context, operationID string) error {
+	op, err := r.app.GetOperation(operationID)
+	if err != nil || op.Owner != OperationOwner {
 		return fmt.Errorf("operation %s not found or already completed", operationID)
 	}
-
-	// Trigger cancellation
-	cancel()
-	return nil
+	return r.app.CancelOperation(operationID)
 }
 
-// CancelOperationByCommand attempts to cancel the currently running operation
-// of the given command type (e.g., "to-zen", "unpack"). If no operation of
-// that type is running, an error is returned.
+// CancelOperationByCommand cancels every running operation of the given
+// command type (e.g., "to-zen", "unpack"). If no operation of that type is
+// running, an error is returned.
 func (r *RetocService) CancelOperationByCommand(ctx context.Context, command string) error {
-	r.operationsMutex.Lock()
-	operationID := r.operationsByCommand[command]
-	r.operationsMutex.Unlock()
-
-	if operationID == "" {
+	cancelled := 0
+	for _, op := range r.app.ListOperations() {
+		if op.Owner == OperationOwner && op.Kind == command && !op.State.Finished() {
+			if r.app.CancelOperation(op.ID) == nil {
+				cancelled++
+			}
+		}
+	}
+	if cancelled == 0 {
 		return fmt.Errorf("no %s operation currently running", command)
 	}
-
-	return r.CancelOperation(ctx, operationID)
+	return nil
 }
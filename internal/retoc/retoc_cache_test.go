@@ -0,0 +1,157 @@
+package retoc
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+)
+
+// newCacheTest returns a service whose retoc is a fake writing the .utoc
+// named on its command line and its siblings, and a mod folder to pack.
+func newCacheTest(t *testing.T) (*RetocService, *toolrunnertest.FakeRunner, RetocOperation) {
+	t.Helper()
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		utoc := cmd.Args[len(cmd.Args)-1]
+		for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+			os.WriteFile(strings.TrimSuffix(utoc, ".utoc")+ext, []byte(strings.Join(cmd.Args, " ")), 0644)
+		}
+		return nil
+	})
+	input := filepath.Join(t.TempDir(), "MyMod")
+	os.MkdirAll(filepath.Join(input, "Content"), 0755)
+	os.WriteFile(filepath.Join(input, "Content", "A.uasset"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(input, "Content", "B.uasset"), []byte("b"), 0644)
+
+	service := NewRetocServiceWithRunner(app.NewApp(), t.TempDir(), fake)
+	return service, fake, RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: t.TempDir(),
+		Pack:       &PackOptions{ModName: "MyMod", EngineVersion: "UE5_3"},
+	}
+}
+
+func TestRetocCache_Unchanged_ReturnsLastOutput(t *testing.T) {
+	service, fake, operation := newCacheTest(t)
+	ctx := context.Background()
+
+	first := service.RunRetoc(ctx, operation)
+	if !first.Success || first.Cached || first.Changes != nil || len(first.OutputFiles) != 3 {
+		t.Fatalf("Expected a first pack with three files, got %+v", first)
+	}
+	if _, err := os.Stat(filepath.Join(operation.OutputPath, "z_MyMod_0001_p.manifest.json")); err != nil {
+		t.Fatalf("Expected a manifest next to the output: %v", err)
+	}
+
+	second := service.RunRetoc(ctx, operation)
+	if !second.Success || !second.Cached {
+		t.Fatalf("Expected the second pack to be cached, got %+v", second)
+	}
+	if !slices.Equal(second.OutputFiles, first.OutputFiles) {
+		t.Errorf("Expected the cached output %v, got %v", first.OutputFiles, second.OutputFiles)
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Errorf("Expected retoc to run once, ran %d times", n)
+	}
+
+	operation.Force = true
+	if forced := service.RunRetoc(ctx, operation); !forced.Success || forced.Cached || len(fake.Calls()) != 2 {
+		t.Errorf("Expected Force to pack again, got %+v", forced)
+	}
+}
+
+func TestRetocCache_InputChanged_ReportsFilesAndRepacks(t *testing.T) {
+	service, fake, operation := newCacheTest(t)
+	ctx := context.Background()
+	service.RunRetoc(ctx, operation)
+
+	content := filepath.Join(operation.InputPath, "Content")
+	os.WriteFile(filepath.Join(content, "A.uasset"), []byte("changed"), 0644)
+	os.Remove(filepath.Join(content, "B.uasset"))
+	os.WriteFile(filepath.Join(content, "C.uasset"), []byte("c"), 0644)
+
+	result := service.RunRetoc(ctx, operation)
+	if !result.Success || result.Cached || result.Changes == nil {
+		t.Fatalf("Expected a repack reporting changes, got %+v", result)
+	}
+	want := PackChanges{Added: []string{"Content/C.uasset"}, Modified: []string{"Content/A.uasset"}, Removed: []string{"Content/B.uasset"}}
+	got := *result.Changes
+	if !slices.Equal(got.Added, want.Added) || !slices.Equal(got.Modified, want.Modified) || !slices.Equal(got.Removed, want.Removed) || got.Options || len(got.Outputs) != 0 {
+		t.Errorf("Expected changes %+v, got %+v", want, got)
+	}
+	if n := len(fake.Calls()); n != 2 {
+		t.Errorf("Expected retoc to run twice, ran %d times", n)
+	}
+
+	// The repack's manifest is current again
+	if again := service.RunRetoc(ctx, operation); !again.Cached {
+		t.Errorf("Expected the repacked input to be cached, got %+v", again)
+	}
+}
+
+func TestRetocCache_OptionsOrOutputChanged_Repacks(t *testing.T) {
+	service, _, operation := newCacheTest(t)
+	ctx := context.Background()
+	first := service.RunRetoc(ctx, operation)
+
+	operation.Pack.Compression = "Zlib"
+	result := service.RunRetoc(ctx, operation)
+	if result.Cached || result.Changes == nil || !result.Changes.Options {
+		t.Errorf("Expected an options change, got %+v", result)
+	}
+
+	os.Remove(first.OutputFiles[0])
+	result = service.RunRetoc(ctx, operation)
+	if result.Cached || result.Changes == nil || !slices.Equal(result.Changes.Outputs, first.OutputFiles[:1]) {
+		t.Errorf("Expected the deleted output to be reported, got %+v", result.Changes)
+	}
+}
+
+func TestRetocCache_DryRunOfUnchangedInput_ReturnsPlan(t *testing.T) {
+	service, fake, operation := newCacheTest(t)
+	ctx := context.Background()
+	service.RunRetoc(ctx, operation)
+
+	operation.DryRun = true
+	result := service.RunRetoc(ctx, operation)
+	if !result.Success || result.Cached || result.Plan == nil {
+		t.Fatalf("Expected a plan rather than the cached output, got %+v", result)
+	}
+	if result.Changes == nil || !result.Changes.None() {
+		t.Errorf("Expected changes showing nothing changed, got %+v", result.Changes)
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Errorf("Expected the dry run not to run retoc, ran %d times", n)
+	}
+}
+
+func TestRetocCache_RenameFailed_KeepsManifest(t *testing.T) {
+	service, _, operation := newCacheTest(t)
+	ctx := context.Background()
+	service.RunRetoc(ctx, operation)
+	manifestPath := filepath.Join(operation.OutputPath, "z_MyMod_0001_p.manifest.json")
+	before, _ := os.ReadFile(manifestPath)
+
+	// A directory in the way of the renamed .utoc makes the rename fail
+	utoc := filepath.Join(operation.OutputPath, "z_MyMod_0001_p.utoc")
+	os.Remove(utoc)
+	os.MkdirAll(filepath.Join(utoc, "blocker"), 0755)
+	os.WriteFile(filepath.Join(operation.InputPath, "Content", "A.uasset"), []byte("changed"), 0644)
+
+	result := service.RunRetoc(ctx, operation)
+	if !strings.Contains(result.Message, "renaming failed") {
+		t.Fatalf("Expected the rename to fail, got %+v", result)
+	}
+	if after, _ := os.ReadFile(manifestPath); string(after) != string(before) {
+		t.Error("Expected the manifest not to record the new input as packed")
+	}
+}
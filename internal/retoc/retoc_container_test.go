@@ -0,0 +1,93 @@
+package retoc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func TestRetocContainer_InspectContainer_ReturnsMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	utocPath := writeTestContainer(t, tempDir)
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	info, err := service.InspectContainer(context.Background(), utocPath, "")
+	if err != nil {
+		t.Fatalf("InspectContainer failed: %v", err)
+	}
+
+	if info.Name != "pakchunk0-Windows" {
+		t.Errorf("Expected name 'pakchunk0-Windows', got '%s'", info.Name)
+	}
+	if info.Version != "PerfectHashWithOverflow" {
+		t.Errorf("Expected version 'PerfectHashWithOverflow', got '%s'", info.Version)
+	}
+	if info.MountPoint != "../../../" {
+		t.Errorf("Expected mount point '../../../', got '%s'", info.MountPoint)
+	}
+	if info.Encrypted || info.Signed {
+		t.Error("Expected container to be neither encrypted nor signed")
+	}
+	if !info.Indexed {
+		t.Error("Expected container to be indexed")
+	}
+	if info.EntryCount != 2 || info.FileCount != 2 {
+		t.Errorf("Expected 2 entries and 2 files, got %d and %d", info.EntryCount, info.FileCount)
+	}
+	if info.TotalSize != 10 {
+		t.Errorf("Expected total size 10, got %d", info.TotalSize)
+	}
+}
+
+func TestRetocContainer_InspectContainer_RejectsNonUtoc(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	if _, err := service.InspectContainer(context.Background(), "pakchunk0-Windows.pak", ""); err == nil {
+		t.Error("Expected error for non-.utoc path")
+	}
+}
+
+func TestRetocContainer_ListContainer_ReturnsTypedEntries(t *testing.T) {
+	tempDir := t.TempDir()
+	files := []iostoretest.File{
+		{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")},
+		{Path: "MyGame/Content/Data/DT_Items.ubulk", Data: []byte("bulkdata"), ChunkType: iostore.ChunkTypeBulkData},
+	}
+	if _, err := iostoretest.WriteContainer(tempDir, "z_Items_0001_P", files, iostoretest.Options{}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	entries, err := service.ListContainer(context.Background(), tempDir, "")
+	if err != nil {
+		t.Fatalf("ListContainer failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	bulk := entries[1]
+	if bulk.PackagePath != "/Game/Data/DT_Items" {
+		t.Errorf("Expected package path '/Game/Data/DT_Items', got '%s'", bulk.PackagePath)
+	}
+	if bulk.ChunkType != "BulkData" {
+		t.Errorf("Expected chunk type 'BulkData', got '%s'", bulk.ChunkType)
+	}
+	if bulk.Size != 8 || bulk.CompressedSize != 8 {
+		t.Errorf("Expected size 8/8, got %d/%d", bulk.Size, bulk.CompressedSize)
+	}
+}
+
+func TestRetocContainer_ListContainer_EmptyDirectory_ReturnsError(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	if _, err := service.ListContainer(context.Background(), t.TempDir(), ""); err == nil {
+		t.Error("Expected error when no containers are present")
+	}
+}
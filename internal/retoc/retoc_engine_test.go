@@ -0,0 +1,197 @@
+package retoc
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+)
+
+// writeGamePaks creates <dir>/MyGame/Content/Paks holding a container with
+// the given TOC version and returns the Paks directory.
+func writeGamePaks(t *testing.T, dir string, version iostore.TocVersion) string {
+	t.Helper()
+	paks := filepath.Join(dir, "MyGame", "Content", "Paks")
+	files := []iostoretest.File{{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")}}
+	if _, err := iostoretest.WriteContainer(paks, "pakchunk0-Windows", files, iostoretest.Options{Version: version}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+	return paks
+}
+
+// writePak writes a .pak file ending in a version 8+ FPakInfo footer.
+func writePak(t *testing.T, path string, version uint32) {
+	t.Helper()
+	footer := make([]byte, 16+1+4+4+8+8+20+5*32) // Key GUID, encrypted flag, info, method names
+	binary.LittleEndian.PutUint32(footer[17:], pakMagic)
+	binary.LittleEndian.PutUint32(footer[21:], version)
+	if err := os.WriteFile(path, append([]byte("pak data"), footer...), 0644); err != nil {
+		t.Fatalf("Failed to write pak: %v", err)
+	}
+}
+
+func versions(candidates []EngineCandidate) []string {
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Version + ":" + string(c.Confidence)
+	}
+	return names
+}
+
+func TestEngineVersion_TocVersion_NarrowsCandidates(t *testing.T) {
+	tempDir := t.TempDir()
+	writeGamePaks(t, tempDir, iostore.TocVersionPerfectHashWithOverflow)
+
+	d, err := DetectEngineVersion(tempDir)
+	if err != nil {
+		t.Fatalf("DetectEngineVersion failed: %v", err)
+	}
+
+	want := []string{"UE5_2:medium", "UE5_1:medium", "UE5_0:medium"}
+	if got := versions(d.Candidates); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if d.TocVersion != "PerfectHashWithOverflow" {
+		t.Errorf("Unexpected TOC version %q", d.TocVersion)
+	}
+	if _, ok := d.Best(); ok {
+		t.Error("Expected no high-confidence version")
+	}
+}
+
+func TestEngineVersion_PakFooter_RulesOutOldEngines(t *testing.T) {
+	paks := writeGamePaks(t, t.TempDir(), iostore.TocVersionDirectoryIndex)
+
+	writePak(t, filepath.Join(paks, "pakchunk0-Windows.pak"), 11)
+	d, err := DetectEngineVersion(paks)
+	if err != nil {
+		t.Fatalf("DetectEngineVersion failed: %v", err)
+	}
+	if version, ok := d.Best(); !ok || version != "UE4_26" || d.PakVersion != 11 {
+		t.Errorf("Expected UE4_26 with pak version 11, got %+v", d)
+	}
+
+	writePak(t, filepath.Join(paks, "pakchunk0-Windows.pak"), 8)
+	d, _ = DetectEngineVersion(paks)
+	if len(d.Candidates) != 0 || d.PakVersion != 8 {
+		t.Errorf("Expected pak version 8 to rule out every candidate, got %+v", d)
+	}
+}
+
+func TestEngineVersion_VersionInfo_PicksMinorVersion(t *testing.T) {
+	build := utf16.Encode([]rune("ProductVersion\x005.1.1-23901901+++UE5+Release-5.1\x00"))
+	info := make([]byte, len(build)*2)
+	for i, u := range build {
+		binary.LittleEndian.PutUint16(info[i*2:], u)
+	}
+	display, version, confidence := parseVersionInfo(info)
+	if display != "5.1.1-23901901+++UE5+Release-5.1" || version != "UE5_1" || confidence != ConfidenceHigh {
+		t.Errorf("Unexpected build version: %q %q %q", display, version, confidence)
+	}
+
+	fixed := make([]byte, 64)
+	binary.LittleEndian.PutUint32(fixed[40:], fixedFileInfoSignature)
+	binary.LittleEndian.PutUint32(fixed[48:], 5<<16|2) // FileVersionMS
+	binary.LittleEndian.PutUint32(fixed[52:], 1<<16)   // FileVersionLS
+	display, version, confidence = parseVersionInfo(fixed)
+	if display != "5.2.1.0" || version != "UE5_2" || confidence != ConfidenceMedium {
+		t.Errorf("Unexpected file version: %q %q %q", display, version, confidence)
+	}
+
+	// Combined with a TOC version that allows it, the executable decides.
+	got := versions(rankCandidates(tocEngineVersions[iostore.TocVersionPerfectHashWithOverflow], true, version, confidence))
+	if want := []string{"UE5_2:high", "UE5_1:low", "UE5_0:low"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestEngineVersion_NoGameFiles_ReturnsError(t *testing.T) {
+	if _, err := DetectEngineVersion(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without game files")
+	}
+}
+
+func TestEngineVersion_Profile_DefaultsPackVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock retoc.exe is a shell script")
+	}
+
+	tempDir := t.TempDir()
+	retocDir := filepath.Join(tempDir, "deps", "retoc")
+	os.MkdirAll(retocDir, 0755)
+	if err := os.WriteFile(filepath.Join(retocDir, "retoc.exe"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock retoc.exe: %v", err)
+	}
+
+	a := newConfiguredApp(t)
+	installDir := filepath.Join(tempDir, "Game")
+	writeGamePaks(t, installDir, iostore.TocVersionPartitionSize)
+	profile, err := a.SaveProfile(config.GameProfile{Name: "My Game", InstallDir: installDir})
+	if err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	service := NewRetocService(a, filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  filepath.Join(tempDir, "MyMod"),
+		OutputPath: tempDir,
+		ProfileID:  profile.ID,
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected to-zen to succeed, got error: %s", result.Error)
+	}
+	if !strings.HasPrefix(result.Output, "to-zen --version UE4_27 ") {
+		t.Errorf("Expected the detected version to be passed, got: %s", result.Output)
+	}
+}
+
+func TestEngineVersion_NoProfile_DetectsFromOutputPaks(t *testing.T) {
+	tempDir := t.TempDir()
+	paks := writeGamePaks(t, filepath.Join(tempDir, "Game"), iostore.TocVersionPartitionSize)
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		return nil
+	})
+	service := NewRetocServiceWithRunner(app.NewApp(), filepath.Join(tempDir, "deps"), fake)
+
+	for output, want := range map[string]string{
+		filepath.Join(paks, "~mods"):    "UE4_27",
+		filepath.Join(tempDir, "build"): "",
+	} {
+		os.MkdirAll(output, 0755)
+		result := service.RunRetoc(context.Background(), RetocOperation{
+			Command:    "to-zen",
+			InputPath:  filepath.Join(tempDir, "MyMod"),
+			OutputPath: output,
+			Force:      true,
+		})
+		if !result.Success {
+			t.Fatalf("Expected to-zen to succeed, got error: %s", result.Error)
+		}
+		calls := fake.Calls()
+		args := calls[len(calls)-1].Args
+		version := ""
+		if i := slices.Index(args, "--version"); i >= 0 {
+			version = args[i+1]
+		}
+		if version != want {
+			t.Errorf("Output %s: expected version %q, got %v", output, want, args)
+		}
+	}
+}
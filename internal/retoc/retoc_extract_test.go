@@ -0,0 +1,89 @@
+package retoc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+func TestRetocExtract_NormalizePackagePath(t *testing.T) {
+	tests := map[string]string{
+		"/Game/Data/DT_Items":          "/Game/Data/DT_Items",
+		"/Game/Data/DT_Items.DT_Items": "/Game/Data/DT_Items",
+		"Game/Data/DT_Items.uasset":    "/Game/Data/DT_Items",
+		`\Game\Data\DT_Items`:          "/Game/Data/DT_Items",
+		"  ":                           "",
+	}
+	for in, want := range tests {
+		if got := normalizePackagePath(in); got != want {
+			t.Errorf("normalizePackagePath(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestRetocExtract_MissingPackage_FailsBeforeRetoc(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestContainer(t, tempDir)
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	_, err := service.ExtractAsset(context.Background(), tempDir, "/Game/Data/DT_Missing", filepath.Join(tempDir, "out"), "")
+	if err == nil {
+		t.Fatal("Expected error for missing package")
+	}
+	if !strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "retoc.exe") {
+		t.Errorf("Expected package-not-found error, got: %v", err)
+	}
+}
+
+func TestRetocExtract_Success_ReturnsOnlyPackageFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock retoc.exe is a shell script")
+	}
+
+	tempDir := t.TempDir()
+	writeTestContainer(t, tempDir)
+	outDir := filepath.Join(tempDir, "out")
+
+	// Mock retoc.exe writes more than was asked for, as retoc's substring
+	// filter can
+	retocDir := filepath.Join(tempDir, "deps", "retoc")
+	os.MkdirAll(retocDir, 0755)
+	script := `#!/bin/sh
+for last; do :; done
+mkdir -p "$last/MyGame/Content/Data" "$last/MyGame/Content/Maps"
+touch "$last/MyGame/Content/Data/DT_Items.uasset" "$last/MyGame/Content/Data/DT_Items.uexp"
+touch "$last/MyGame/Content/Data/DT_ItemsOld.uasset" "$last/MyGame/Content/Maps/Arena.umap"
+echo "$@"
+`
+	if err := os.WriteFile(filepath.Join(retocDir, "retoc.exe"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create mock retoc.exe: %v", err)
+	}
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	asset, err := service.ExtractAsset(context.Background(), tempDir, "/Game/Data/DT_Items.DT_Items", outDir, "")
+	if err != nil {
+		t.Fatalf("ExtractAsset failed: %v", err)
+	}
+
+	dataDir := filepath.Join(outDir, "MyGame", "Content", "Data")
+	want := []string{filepath.Join(dataDir, "DT_Items.uasset"), filepath.Join(dataDir, "DT_Items.uexp")}
+	if strings.Join(asset.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v, got %v", want, asset.Files)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "DT_ItemsOld.uasset")); !os.IsNotExist(err) {
+		t.Error("Expected DT_ItemsOld.uasset to be pruned")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "MyGame", "Content", "Maps", "Arena.umap")); !os.IsNotExist(err) {
+		t.Error("Expected Arena.umap to be pruned")
+	}
+	if !strings.HasSuffix(asset.Container, "pakchunk0-Windows.utoc") {
+		t.Errorf("Expected container pakchunk0-Windows.utoc, got %s", asset.Container)
+	}
+}
@@ -0,0 +1,197 @@
+package retoc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func TestRetocFilter_Match_Globs(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"/Game/Data/**/DT_*", "/Game/Data/DT_Items", true},
+		{"/Game/Data/**/DT_*", "/Game/Data/Loot/Tables/DT_Drops", true},
+		{"/Game/Data/**/DT_*", "/Game/Maps/DT_Items", false},
+		{"/Game/Data/*", "/Game/Data/Loot/DT_Drops", false},
+		{"/game/data/dt_items", "/Game/Data/DT_Items", true},
+		{"DT_*", "/Game/Data/DT_Items", true},
+		{"/Game/Maps/Arena?", "/Game/Maps/Arena2", true},
+		{"/Game/**", "/Engine/Foo", false},
+	}
+
+	for _, tt := range tests {
+		f, err := newPackageFilter([]string{tt.glob}, nil)
+		if err != nil {
+			t.Fatalf("newPackageFilter(%q) failed: %v", tt.glob, err)
+		}
+		if got := f.match(tt.path); got != tt.want {
+			t.Errorf("%q matching %q: expected %v, got %v", tt.glob, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestRetocFilter_Exclude_OverridesInclude(t *testing.T) {
+	f, err := newPackageFilter([]string{"/Game/Data/**"}, []string{"**/DT_Debug*"})
+	if err != nil {
+		t.Fatalf("newPackageFilter failed: %v", err)
+	}
+
+	if !f.match("/Game/Data/DT_Items") {
+		t.Error("Expected DT_Items to be included")
+	}
+	if f.match("/Game/Data/DT_DebugItems") {
+		t.Error("Expected DT_DebugItems to be excluded")
+	}
+}
+
+func TestRetocFilter_RetocArgs_UsesLiteralPrefixes(t *testing.T) {
+	f, _ := newPackageFilter([]string{"/Game/Data/**/DT_*", "/Game/Maps/Arena"}, nil)
+	want := []string{"--filter", "/Game/Data/", "--filter", "/Game/Maps/Arena"}
+	if got := f.retocArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// A glob with no literal prefix cannot be narrowed, so retoc must see
+	// every package
+	f, _ = newPackageFilter([]string{"/Game/Data/*", "**/DT_*"}, nil)
+	if got := f.retocArgs(); got != nil {
+		t.Errorf("Expected no filter args, got %v", got)
+	}
+}
+
+func TestRetocFilter_PruneOutput_KeepsPreexistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "notes.txt")
+	os.WriteFile(keep, []byte("mine"), 0644)
+	existing := snapshotFiles(dir)
+
+	matched := filepath.Join(dir, "MyGame", "Content", "Data", "DT_Items.uasset")
+	unmatched := filepath.Join(dir, "MyGame", "Content", "Maps", "Arena.umap")
+	for _, p := range []string{matched, unmatched} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+	}
+
+	f, _ := newPackageFilter([]string{"/Game/Data/**"}, nil)
+	removed, err := f.pruneOutput(dir, existing)
+	if err != nil {
+		t.Fatalf("pruneOutput failed: %v", err)
+	}
+
+	if removed != 1 {
+		t.Errorf("Expected 1 file removed, got %d", removed)
+	}
+	for _, p := range []string{keep, matched} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("Expected %s to be kept", p)
+		}
+	}
+	if _, err := os.Stat(unmatched); !os.IsNotExist(err) {
+		t.Error("Expected unmatched file to be removed")
+	}
+}
+
+func TestRetocFilter_PruneOutput_KeepsMultiPartExtensions(t *testing.T) {
+	dir := t.TempDir()
+	content := filepath.Join(dir, "MyGame", "Content", "Maps")
+	os.MkdirAll(content, 0755)
+	for _, name := range []string{"Arena.umap", "Arena.uexp", "Arena.m.ubulk", "Other.m.ubulk"} {
+		os.WriteFile(filepath.Join(content, name), []byte("x"), 0644)
+	}
+
+	f, _ := newPackageFilter([]string{"/Game/Maps/Arena"}, nil)
+	removed, err := f.pruneOutput(dir, nil)
+	if err != nil {
+		t.Fatalf("pruneOutput failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected only Other.m.ubulk removed, got %d removed", removed)
+	}
+	if _, err := os.Stat(filepath.Join(content, "Arena.m.ubulk")); err != nil {
+		t.Error("Expected the selected package's .m.ubulk to be kept")
+	}
+}
+
+func TestRetocFilter_Unpack_ExtractsMatchingNatively(t *testing.T) {
+	tempDir := t.TempDir()
+	paksDir := filepath.Join(tempDir, "Paks")
+	outDir := filepath.Join(tempDir, "out")
+	files := []iostoretest.File{
+		{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")},
+		{Path: "MyGame/Content/Data/DT_Items.ubulk", Data: []byte("bulk")},
+		{Path: "MyGame/Content/Maps/Arena.umap", Data: []byte("arena")},
+	}
+	if _, err := iostoretest.WriteContainer(paksDir, "pakchunk0-Windows", files, iostoretest.Options{Compression: "Zlib"}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	// depsDir deliberately has no retoc.exe
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "unpack",
+		InputPath:  paksDir,
+		OutputPath: outDir,
+		Include:    []string{"/Game/Data/**/DT_*"},
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected native unpack to succeed, got error: %s", result.Error)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "MyGame", "Content", "Data", "DT_Items.uasset"))
+	if err != nil || string(data) != "items" {
+		t.Errorf("Expected DT_Items.uasset with 'items', got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "MyGame", "Content", "Data", "DT_Items.ubulk")); err != nil {
+		t.Error("Expected DT_Items.ubulk to be extracted")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "MyGame", "Content", "Maps", "Arena.umap")); !os.IsNotExist(err) {
+		t.Error("Expected Arena.umap not to be extracted")
+	}
+}
+
+func TestRetocFilter_Unpack_UnsupportedCompression_FallsBackToRetocExe(t *testing.T) {
+	tempDir := t.TempDir()
+	files := []iostoretest.File{{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")}}
+	if _, err := iostoretest.WriteContainer(tempDir, "pakchunk0-Windows", files, iostoretest.Options{Compression: "Oodle"}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "unpack",
+		InputPath:  tempDir,
+		OutputPath: filepath.Join(tempDir, "out"),
+		Include:    []string{"/Game/Data/**"},
+	})
+
+	if result.Success {
+		t.Error("Expected fallback to fail without retoc.exe")
+	}
+	if !strings.Contains(result.Error, "not found at:") {
+		t.Errorf("Expected missing retoc.exe error, got: %s", result.Error)
+	}
+}
+
+func TestRetocFilter_InvalidGlob_ReturnsError(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command: "unpack",
+		Include: []string{"  "},
+	})
+
+	if result.Success || !strings.Contains(result.Error, "empty filter pattern") {
+		t.Errorf("Expected empty pattern error, got: %+v", result)
+	}
+}
@@ -0,0 +1,84 @@
+package retoc
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+)
+
+func writeTestContainer(t *testing.T, dir string) string {
+	t.Helper()
+	files := []iostoretest.File{
+		{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")},
+		{Path: "MyGame/Content/Maps/Arena.umap", Data: []byte("arena")},
+	}
+	utocPath, err := iostoretest.WriteContainer(dir, "pakchunk0-Windows", files, iostoretest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to write test container: %v", err)
+	}
+	return utocPath
+}
+
+func TestRetocInspect_Info_AnsweredWithoutRetocExe(t *testing.T) {
+	tempDir := t.TempDir()
+	paksDir := filepath.Join(tempDir, "Paks")
+	writeTestContainer(t, paksDir)
+
+	// depsDir deliberately has no retoc.exe
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:   "info",
+		InputPath: paksDir,
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected native info to succeed, got error: %s", result.Error)
+	}
+	for _, expected := range []string{"PerfectHashWithOverflow", "Indexed", "Mount point:    ../../../"} {
+		if !strings.Contains(result.Output, expected) {
+			t.Errorf("Expected info output to contain '%s', got:\n%s", expected, result.Output)
+		}
+	}
+}
+
+func TestRetocInspect_List_ListsFilePaths(t *testing.T) {
+	tempDir := t.TempDir()
+	utocPath := writeTestContainer(t, tempDir)
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:   "list",
+		InputPath: utocPath,
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected native list to succeed, got error: %s", result.Error)
+	}
+	if !strings.Contains(result.Output, "../../../MyGame/Content/Data/DT_Items.uasset") {
+		t.Errorf("Expected list output to contain DT_Items path, got:\n%s", result.Output)
+	}
+}
+
+func TestRetocInspect_NoContainers_FallsBackToRetocExe(t *testing.T) {
+	tempDir := t.TempDir()
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:   "info",
+		InputPath: tempDir,
+	})
+
+	if result.Success {
+		t.Error("Expected fallback to fail without retoc.exe")
+	}
+	if !strings.Contains(result.Error, "not found at:") {
+		t.Errorf("Expected missing retoc.exe error, got: %s", result.Error)
+	}
+}
@@ -0,0 +1,340 @@
+package retoc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+	"github.com/JaceTheGrayOne/ARI-S/internal/iostore/iostoretest"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+)
+
+var (
+	testAESKey    = bytes.Repeat([]byte{0x42}, 32)
+	testAESKeyHex = "0x" + strings.Repeat("42", 32)
+)
+
+// newConfiguredApp returns an App with a loaded configuration that lives
+// in a temp directory.
+func newConfiguredApp(t *testing.T) *app.App {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("AppData", configDir)
+
+	a := app.NewApp()
+	a.LoadConfiguration()
+	return a
+}
+
+// newKeyedApp returns a configured App with a "MyGame" profile whose keys
+// are saved and unlocked.
+func newKeyedApp(t *testing.T, keys []config.AESKey) *app.App {
+	t.Helper()
+	a := newConfiguredApp(t)
+	if _, err := a.SaveProfile(config.GameProfile{ID: "MyGame", Name: "MyGame"}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	if err := a.SaveGameKeys("MyGame", keys, "hunter2"); err != nil {
+		t.Fatalf("SaveGameKeys failed: %v", err)
+	}
+	return a
+}
+
+func writeEncryptedContainer(t *testing.T, dir string) string {
+	t.Helper()
+	files := []iostoretest.File{
+		{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")},
+		{Path: "MyGame/Content/Maps/Arena.umap", Data: []byte("arena")},
+	}
+	utocPath, err := iostoretest.WriteContainer(dir, "pakchunk0-Windows", files, iostoretest.Options{AESKey: testAESKey})
+	if err != nil {
+		t.Fatalf("Failed to write encrypted container: %v", err)
+	}
+	return utocPath
+}
+
+func TestRetocKeys_List_DecryptsIndexWithGameKey(t *testing.T) {
+	tempDir := t.TempDir()
+	writeEncryptedContainer(t, tempDir)
+
+	service := NewRetocService(newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}}), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:   "list",
+		InputPath: tempDir,
+		ProfileID: "MyGame",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected list to succeed, got error: %s", result.Error)
+	}
+	if !strings.Contains(result.Output, "DT_Items.uasset") {
+		t.Errorf("Expected decrypted file list, got:\n%s", result.Output)
+	}
+}
+
+func TestRetocKeys_WrongKey_FailsBeforeRetoc(t *testing.T) {
+	tempDir := t.TempDir()
+	writeEncryptedContainer(t, tempDir)
+
+	wrongKey := "0x" + strings.Repeat("24", 32)
+	service := NewRetocService(newKeyedApp(t, []config.AESKey{{Key: wrongKey}}), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-legacy",
+		InputPath:  tempDir,
+		OutputPath: filepath.Join(tempDir, "out"),
+		ProfileID:  "MyGame",
+	})
+
+	if result.Success {
+		t.Fatal("Expected wrong key to fail")
+	}
+	if !strings.Contains(result.Error, "wrong AES key") {
+		t.Errorf("Expected wrong key error, got: %s", result.Error)
+	}
+}
+
+func TestRetocKeys_GUIDKeyed_SelectsMatchingKey(t *testing.T) {
+	tempDir := t.TempDir()
+	guid := [16]byte{0xD4, 0xC3, 0xB2, 0xA1, 15: 1}
+	files := []iostoretest.File{{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")}}
+	if _, err := iostoretest.WriteContainer(tempDir, "pakchunk1-Windows", files, iostoretest.Options{AESKey: testAESKey, KeyGUID: guid}); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	service := NewRetocService(newKeyedApp(t, []config.AESKey{
+		{Key: "0x" + strings.Repeat("24", 32)},
+		{GUID: "A1B2C3D4000000000000000001000000", Key: testAESKeyHex},
+	}), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "unpack",
+		InputPath:  tempDir,
+		OutputPath: filepath.Join(tempDir, "out"),
+		Include:    []string{"/Game/**"},
+		ProfileID:  "MyGame",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected native unpack with GUID key to succeed, got error: %s", result.Error)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "out", "MyGame", "Content", "Data", "DT_Items.uasset"))
+	if err != nil || string(data) != "items" {
+		t.Errorf("Expected decrypted DT_Items.uasset, got %q (%v)", data, err)
+	}
+}
+
+func TestRetocKeys_LockedVault_ReturnsError(t *testing.T) {
+	tempDir := t.TempDir()
+	newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}})
+
+	// A fresh App sees the stored vault but has not unlocked it
+	locked := app.NewApp()
+	locked.LoadConfiguration()
+	service := NewRetocService(locked, filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:   "info",
+		InputPath: tempDir,
+		ProfileID: "MyGame",
+	})
+
+	if result.Success || !strings.Contains(result.Error, "locked") {
+		t.Errorf("Expected locked vault error, got: %+v", result)
+	}
+
+	if err := locked.UnlockGameKeys("MyGame", "wrong"); err == nil {
+		t.Error("Expected unlock with wrong passphrase to fail")
+	}
+	if err := locked.UnlockGameKeys("MyGame", "hunter2"); err != nil {
+		t.Errorf("Expected unlock to succeed, got %v", err)
+	}
+}
+
+func TestRetocKeys_PassesAESKeyToRetoc(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock retoc.exe is a shell script")
+	}
+
+	tempDir := t.TempDir()
+	paksDir := filepath.Join(tempDir, "Paks")
+	writeEncryptedContainer(t, paksDir)
+
+	retocDir := filepath.Join(tempDir, "deps", "retoc")
+	os.MkdirAll(retocDir, 0755)
+	if err := os.WriteFile(filepath.Join(retocDir, "retoc.exe"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock retoc.exe: %v", err)
+	}
+
+	service := NewRetocService(newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}}), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-legacy",
+		InputPath:  paksDir,
+		OutputPath: filepath.Join(tempDir, "out"),
+		ProfileID:  "MyGame",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected to-legacy to succeed, got error: %s", result.Error)
+	}
+	if !strings.HasPrefix(result.Output, "--aes-key "+testAESKeyHex+" to-legacy") {
+		t.Errorf("Expected --aes-key before the subcommand, got: %s", result.Output)
+	}
+}
+
+func TestRetocKeys_ListAndInspectContainer_DecryptWithProfileKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	utocPath := writeEncryptedContainer(t, tempDir)
+	service := NewRetocService(newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}}), filepath.Join(tempDir, "deps"))
+	ctx := context.Background()
+
+	entries, err := service.ListContainer(ctx, tempDir, "MyGame")
+	if err != nil {
+		t.Fatalf("ListContainer failed: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		found = found || e.PackagePath == "/Game/Data/DT_Items"
+	}
+	if !found {
+		t.Errorf("Expected decrypted package paths, got %+v", entries)
+	}
+
+	info, err := service.InspectContainer(ctx, utocPath, "MyGame")
+	if err != nil {
+		t.Fatalf("InspectContainer failed: %v", err)
+	}
+	if info.MountPoint == "" || info.FileCount != 2 {
+		t.Errorf("Expected a mount point and 2 files, got %+v", info)
+	}
+
+	if entries, _ := service.ListContainer(ctx, tempDir, ""); len(entries) > 0 && entries[0].PackagePath != "" {
+		t.Errorf("Expected no package paths without the profile, got %+v", entries)
+	}
+}
+
+func TestRetocKeys_ExtractAsset_FindsPackageInEncryptedContainer(t *testing.T) {
+	tempDir := t.TempDir()
+	writeEncryptedContainer(t, tempDir)
+	outDir := filepath.Join(tempDir, "out")
+	a := newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}})
+
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		dir := filepath.Join(outDir, "MyGame", "Content", "Data")
+		os.MkdirAll(dir, 0755)
+		return os.WriteFile(filepath.Join(dir, "DT_Items.uasset"), []byte("items"), 0644)
+	})
+	service := NewRetocServiceWithRunner(a, filepath.Join(tempDir, "deps"), fake)
+
+	asset, err := service.ExtractAsset(context.Background(), tempDir, "/Game/Data/DT_Items", outDir, "MyGame")
+	if err != nil {
+		t.Fatalf("ExtractAsset failed: %v", err)
+	}
+	if len(asset.Files) != 1 || !strings.HasSuffix(asset.Container, "pakchunk0-Windows.utoc") {
+		t.Errorf("Expected one file from pakchunk0-Windows.utoc, got %+v", asset)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || !slices.Contains(calls[0].Args, "--aes-key") {
+		t.Errorf("Expected retoc to get the profile's key, got %+v", calls)
+	}
+
+	if _, err := service.ExtractAsset(context.Background(), tempDir, "/Game/Data/DT_Items", outDir, ""); err == nil || !strings.Contains(err.Error(), "could not be searched") {
+		t.Errorf("Expected the encrypted container to be unsearchable without keys, got %v", err)
+	}
+}
+
+func TestRetocKeys_ContainersWithDifferentGUIDs_RunOncePerKey(t *testing.T) {
+	tempDir := t.TempDir()
+	paksDir := filepath.Join(tempDir, "Paks")
+	otherKey := bytes.Repeat([]byte{0x24}, 32)
+	otherKeyHex := "0x" + strings.Repeat("24", 32)
+	files := []iostoretest.File{{Path: "MyGame/Content/Data/DT_Items.uasset", Data: []byte("items")}}
+	for _, c := range []struct {
+		name string
+		key  []byte
+		guid [16]byte
+	}{
+		{"pakchunk1-Windows", testAESKey, [16]byte{0: 1}},
+		{"pakchunk2-Windows", otherKey, [16]byte{0: 2}},
+	} {
+		if _, err := iostoretest.WriteContainer(paksDir, c.name, files, iostoretest.Options{AESKey: c.key, KeyGUID: c.guid}); err != nil {
+			t.Fatalf("Failed to write container: %v", err)
+		}
+	}
+
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		return nil
+	})
+	service := NewRetocServiceWithRunner(newKeyedApp(t, []config.AESKey{
+		{GUID: "00000001000000000000000000000000", Key: testAESKeyHex},
+		{GUID: "00000002000000000000000000000000", Key: otherKeyHex},
+	}), filepath.Join(tempDir, "deps"), fake)
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-legacy",
+		InputPath:  paksDir,
+		OutputPath: filepath.Join(tempDir, "out"),
+		ProfileID:  "MyGame",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected to-legacy to succeed, got error: %s", result.Error)
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected one retoc run per key, got %+v", calls)
+	}
+	for i, want := range []struct{ key, input string }{
+		{testAESKeyHex, "pakchunk1-Windows.utoc"},
+		{otherKeyHex, "pakchunk2-Windows.utoc"},
+	} {
+		args := calls[i].Args
+		if len(args) < 4 || args[0] != "--aes-key" || args[1] != want.key || filepath.Base(args[len(args)-2]) != want.input {
+			t.Errorf("Run %d: expected %s with its key, got %v", i, want.input, args)
+		}
+	}
+}
+
+func TestRetocKeys_PackWithLockedVault_RunsWithoutKey(t *testing.T) {
+	tempDir := t.TempDir()
+	newKeyedApp(t, []config.AESKey{{Key: testAESKeyHex}})
+
+	// A fresh App sees the stored vault but has not unlocked it
+	locked := app.NewApp()
+	locked.LoadConfiguration()
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		return nil
+	})
+	service := NewRetocServiceWithRunner(locked, filepath.Join(tempDir, "deps"), fake)
+
+	input := filepath.Join(tempDir, "MyMod")
+	os.MkdirAll(input, 0755)
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: filepath.Join(tempDir, "out"),
+		UEVersion:  "UE5_3",
+		ProfileID:  "MyGame",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected to-zen to succeed with the vault locked, got error: %s", result.Error)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || slices.Contains(calls[0].Args, "--aes-key") {
+		t.Errorf("Expected one retoc run without --aes-key, got %+v", calls)
+	}
+}
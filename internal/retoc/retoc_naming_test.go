@@ -0,0 +1,104 @@
+package retoc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/config"
+)
+
+func TestRetocNaming_Presets_RenderExpectedNames(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+	noPatch := false
+
+	tests := []struct {
+		pack PackOptions
+		want string
+	}{
+		{PackOptions{}, "z_AwesomeMod_0042_p"},
+		{PackOptions{NamingPreset: "zzz"}, "zzz_AwesomeMod_0042_P"},
+		{PackOptions{NamingPreset: "tilde"}, "~AwesomeMod_0042_P"},
+		{PackOptions{NamingPreset: "plain"}, "AwesomeMod_0042_P"},
+		{PackOptions{NamingPreset: "plain_nopatch"}, "AwesomeMod_0042"},
+		{PackOptions{NamingPreset: "zzz", PatchSuffix: &noPatch}, "zzz_AwesomeMod_0042"},
+		{PackOptions{NameTemplate: "{prefix}{serial:3}-{name}{patchSuffix}"}, "z_042-AwesomeMod_p"},
+		{PackOptions{NameTemplate: "{name}"}, "AwesomeMod"},
+	}
+
+	for _, tt := range tests {
+		tt.pack.ModName = "AwesomeMod"
+		tt.pack.Priority = priority(42)
+		names, err := service.PreviewPackNames(context.Background(), RetocOperation{Command: "to-zen", Pack: &tt.pack})
+		if err != nil {
+			t.Fatalf("PreviewPackNames(%+v) failed: %v", tt.pack, err)
+		}
+		want := []string{tt.want + ".utoc", tt.want + ".ucas", tt.want + ".pak", tt.want + ".sig"}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("Expected %v, got %v", want, names)
+		}
+	}
+}
+
+func TestRetocNaming_InvalidTemplates_Rejected(t *testing.T) {
+	for _, template := range []string{
+		"{prefix}_{serial:04}", // no {name}
+		"{name}_{version}",     // unknown placeholder
+		"{name}_{serial:0}",    // bad width
+		"{name:4}",             // width on non-serial placeholder
+		"{name}/{serial}",      // path separator
+		"{name}_{serial",       // unbalanced brace
+	} {
+		if err := validateTemplate(template); err == nil {
+			t.Errorf("Expected template %q to be rejected", template)
+		}
+	}
+
+	errs := PackOptions{NamingPreset: "zz"}.validate()
+	if len(errs) != 1 || errs[0].Field != "naming_preset" {
+		t.Errorf("Expected naming_preset error, got %+v", errs)
+	}
+}
+
+func TestRetocNaming_ProfileScheme_AppliedToAllOutputFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	a := newConfiguredApp(t)
+	service := NewRetocService(a, filepath.Join(tempDir, "deps"))
+
+	if err := service.SetProfileNaming(context.Background(), "MyGame", config.NamingScheme{Preset: "zzz"}); err == nil {
+		t.Error("Expected an unknown profile to be rejected")
+	}
+	if _, err := a.SaveProfile(config.GameProfile{ID: "MyGame", Name: "MyGame"}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	if err := service.SetProfileNaming(context.Background(), "MyGame", config.NamingScheme{Preset: "unknown"}); err == nil {
+		t.Error("Expected unknown preset to be rejected")
+	}
+	if err := service.SetProfileNaming(context.Background(), "MyGame", config.NamingScheme{Preset: "zzz"}); err != nil {
+		t.Fatalf("SetProfileNaming failed: %v", err)
+	}
+
+	for _, ext := range packedExtensions {
+		os.WriteFile(filepath.Join(tempDir, "MyModFolder"+ext), []byte("mock"), 0644)
+	}
+
+	err := service.renameOutputFiles(RetocOperation{
+		Command:    "to-zen",
+		InputPath:  filepath.Join(tempDir, "MyModFolder"),
+		OutputPath: tempDir,
+		ProfileID:  "MyGame",
+		Pack:       &PackOptions{ModName: "AwesomeMod", Priority: priority(3)},
+	})
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	for _, ext := range packedExtensions {
+		if _, err := os.Stat(filepath.Join(tempDir, "zzz_AwesomeMod_0003_P"+ext)); err != nil {
+			t.Errorf("Expected zzz_AwesomeMod_0003_P%s to exist", ext)
+		}
+	}
+}
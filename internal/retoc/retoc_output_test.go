@@ -0,0 +1,75 @@
+package retoc
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+func TestRetocOutput_StreamOutput_CollectsAndEmitsLines(t *testing.T) {
+	appInstance := app.NewApp()
+	service := NewRetocService(appInstance, t.TempDir())
+
+	var mu sync.Mutex
+	var received []RetocOutputLine
+	unsubscribe := service.SubscribeOutput(func(line RetocOutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, line)
+	})
+	defer unsubscribe()
+
+	output := &outputCollector{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	service.streamOutput(strings.NewReader("Packing chunks\nDone\n"), "op-1", StreamStdout, output, &wg)
+	wg.Wait()
+
+	if got := output.String(); got != "Packing chunks\nDone\n" {
+		t.Errorf("Expected collected output to contain both lines, got %q", got)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 output events, got %d", len(received))
+	}
+
+	for _, line := range received {
+		if line.OperationID != "op-1" {
+			t.Errorf("Expected operation ID 'op-1', got '%s'", line.OperationID)
+		}
+		if line.Stream != StreamStdout {
+			t.Errorf("Expected stream '%s', got '%s'", StreamStdout, line.Stream)
+		}
+	}
+
+	if received[0].Line != "Packing chunks" {
+		t.Errorf("Expected first line 'Packing chunks', got '%s'", received[0].Line)
+	}
+}
+
+func TestRetocOutput_Unsubscribe_StopsDelivery(t *testing.T) {
+	appInstance := app.NewApp()
+	service := NewRetocService(appInstance, t.TempDir())
+
+	count := 0
+	unsubscribe := service.SubscribeOutput(func(line RetocOutputLine) {
+		count++
+	})
+	unsubscribe()
+
+	output := &outputCollector{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	service.streamOutput(strings.NewReader("error: bad input\n"), "op-2", StreamStderr, output, &wg)
+	wg.Wait()
+
+	if count != 0 {
+		t.Errorf("Expected no events after unsubscribe, got %d", count)
+	}
+
+	if !strings.Contains(output.String(), "error: bad input") {
+		t.Errorf("Expected stderr line to be collected, got %q", output.String())
+	}
+}
@@ -87,7 +87,7 @@ func TestRetocPack_MissingRetocExe_ReturnsError(t *testing.T) {
 		t.Error("Expected error message about missing retoc.exe")
 	}
 
-	expectedErrSubstring := "retoc.exe not found"
+	expectedErrSubstring := "not found at:"
 	if !strings.Contains(result.Error, expectedErrSubstring) {
 		t.Errorf("Expected error containing '%s', got: %s", expectedErrSubstring, result.Error)
 	}
@@ -0,0 +1,193 @@
+package retoc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+// priority returns a pointer to n, for PackOptions.Priority.
+func priority(n int) *int {
+	return &n
+}
+
+func TestRetocPackOptions_Validate_ReportsEachField(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	errs := service.ValidatePackOptions(context.Background(), PackOptions{
+		ModName:       "Bad:Name",
+		Priority:      priority(10000),
+		MountPoint:    "C:/Game/",
+		Compression:   "LZMA",
+		EngineVersion: "UE3",
+	})
+
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"mod_name", "priority", "mount_point", "compression", "engine_version"} {
+		if !fields[field] {
+			t.Errorf("Expected an error for field %s, got %+v", field, errs)
+		}
+	}
+}
+
+func TestRetocPackOptions_Validate_AcceptsValidOptions(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	errs := service.ValidatePackOptions(context.Background(), PackOptions{
+		ModName:       "AwesomeMod",
+		Priority:      priority(42),
+		MountPoint:    "../../../MyGame/",
+		Compression:   "oodle",
+		EngineVersion: "ue5_4",
+	})
+
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %+v", errs)
+	}
+}
+
+func TestRetocPackOptions_LegacyOptions_StillParsed(t *testing.T) {
+	operation := RetocOperation{
+		Command:   "to-zen",
+		InputPath: filepath.Join("mods", "MyModFolder"),
+		UEVersion: "UE5_3",
+		Options:   []string{"--mod-name", "AwesomeMod", "--serialization", "42"},
+	}
+
+	opts, err := operation.packOptions()
+	if err != nil {
+		t.Fatalf("packOptions failed: %v", err)
+	}
+	if opts.ModName != "AwesomeMod" || *opts.Priority != 42 || opts.EngineVersion != "UE5_3" || !*opts.PatchSuffix {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	// Named exactly as the old renaming named them
+	service := NewRetocService(app.NewApp(), t.TempDir())
+	for serialization, want := range map[string]string{
+		"":       "z_AwesomeMod_0001_p",
+		"0":      "z_AwesomeMod_0000_p",
+		"0000":   "z_AwesomeMod_0000_p",
+		"10000":  "z_AwesomeMod_0000_p",
+		"12345":  "z_AwesomeMod_2345_p",
+		"7abc":   "z_AwesomeMod_0007_p",
+		"abc":    "z_AwesomeMod_0abc_p",
+		"abcdef": "z_AwesomeMod_abcd_p",
+		"-3":     "z_AwesomeMod_-003_p",
+	} {
+		operation.Options = []string{"--mod-name", "AwesomeMod", "--serialization", serialization}
+		names, err := service.PreviewPackNames(context.Background(), operation)
+		if err != nil || len(names) == 0 || names[0] != want+".utoc" {
+			t.Errorf("Serialization %q: expected %s.utoc, got %v (%v)", serialization, want, names, err)
+		}
+	}
+}
+
+func TestRetocPackOptions_PriorityZero_NamesSerial0000(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+	if errs := service.ValidatePackOptions(context.Background(), PackOptions{Priority: priority(0)}); len(errs) != 0 {
+		t.Errorf("Expected priority 0 to be valid, got %+v", errs)
+	}
+
+	names, err := service.PreviewPackNames(context.Background(), RetocOperation{
+		Command:   "to-zen",
+		InputPath: filepath.Join("mods", "MyMod"),
+		Pack:      &PackOptions{Priority: priority(0)},
+	})
+	if err != nil || len(names) == 0 || names[0] != "z_MyMod_0000_p.utoc" {
+		t.Errorf("Expected z_MyMod_0000_p.utoc, got %v (%v)", names, err)
+	}
+}
+
+func TestRetocPackOptions_Defaults_UseInputFolderName(t *testing.T) {
+	opts, err := RetocOperation{InputPath: filepath.Join("mods", "MyModFolder"), Pack: &PackOptions{}}.packOptions()
+	if err != nil {
+		t.Fatalf("packOptions failed: %v", err)
+	}
+	if opts.ModName != "MyModFolder" || *opts.Priority != 1 || !*opts.PatchSuffix {
+		t.Errorf("Unexpected defaults: %+v", opts)
+	}
+}
+
+func TestRetocPackOptions_InvalidOptions_FailBeforeRetoc(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  filepath.Join(tempDir, "MyMod"),
+		OutputPath: tempDir,
+		Pack:       &PackOptions{Priority: priority(-1)},
+	})
+
+	if result.Success {
+		t.Fatal("Expected invalid options to fail")
+	}
+	if !strings.Contains(result.Error, "priority:") || strings.Contains(result.Error, "retoc.exe") {
+		t.Errorf("Expected priority field error, got: %s", result.Error)
+	}
+}
+
+func TestRetocPackOptions_FileRenaming_WithoutPatchSuffix(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+		os.WriteFile(filepath.Join(tempDir, "MyModFolder"+ext), []byte("mock"), 0644)
+	}
+
+	patch := false
+	err := service.renameOutputFiles(RetocOperation{
+		Command:    "to-zen",
+		InputPath:  filepath.Join(tempDir, "MyModFolder"),
+		OutputPath: tempDir,
+		Pack:       &PackOptions{ModName: "AwesomeMod", Priority: priority(7), PatchSuffix: &patch},
+	})
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+		if _, err := os.Stat(filepath.Join(tempDir, "z_AwesomeMod_0007"+ext)); err != nil {
+			t.Errorf("Expected z_AwesomeMod_0007%s to exist", ext)
+		}
+	}
+}
+
+func TestRetocPackOptions_PassesFlagsToRetoc(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock retoc.exe is a shell script")
+	}
+
+	tempDir := t.TempDir()
+	retocDir := filepath.Join(tempDir, "deps", "retoc")
+	os.MkdirAll(retocDir, 0755)
+	if err := os.WriteFile(filepath.Join(retocDir, "retoc.exe"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock retoc.exe: %v", err)
+	}
+
+	service := NewRetocService(app.NewApp(), filepath.Join(tempDir, "deps"))
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  filepath.Join(tempDir, "MyMod"),
+		OutputPath: tempDir,
+		UEVersion:  "UE5_1",
+		Pack:       &PackOptions{EngineVersion: "ue5_4", Compression: "zlib"},
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected to-zen to succeed, got error: %s", result.Error)
+	}
+	if !strings.HasPrefix(result.Output, "to-zen --version UE5_4 --compression-format Zlib ") {
+		t.Errorf("Unexpected retoc arguments: %s", result.Output)
+	}
+}
@@ -0,0 +1,157 @@
+package retoc
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+func TestRetocProgress_ParseLine_ExtractsCounters(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		phase      string
+		itemsDone  int64
+		itemsTotal int64
+		unit       string
+		bytesDone  int64
+		bytesTotal int64
+	}{
+		{"chunks after counter", "Writing 120/480 chunks", PhaseWriting, 120, 480, "chunks", 0, 0},
+		{"packages before counter", "Converting packages [3/9]", PhaseConverting, 3, 9, "packages", 0, 0},
+		{"thousands separators", "Reading 1,024/2,048 files", PhaseReading, 1024, 2048, "files", 0, 0},
+		{"bytes and items", "Extracting 5/10 chunks 512 MiB/1 GiB", PhaseConverting, 5, 10, "chunks", 512 << 20, 1 << 30},
+		{"percent unit", "Packing 40/80%", PhaseConverting, 40, 80, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, ok := parseProgressLine(tt.line)
+			if !ok {
+				t.Fatalf("Expected line %q to carry progress", tt.line)
+			}
+			if update.phase != tt.phase {
+				t.Errorf("Expected phase '%s', got '%s'", tt.phase, update.phase)
+			}
+			if update.itemsDone != tt.itemsDone || update.itemsTotal != tt.itemsTotal {
+				t.Errorf("Expected items %d/%d, got %d/%d", tt.itemsDone, tt.itemsTotal, update.itemsDone, update.itemsTotal)
+			}
+			if update.itemUnit != tt.unit {
+				t.Errorf("Expected unit '%s', got '%s'", tt.unit, update.itemUnit)
+			}
+			if update.bytesDone != tt.bytesDone || update.bytesTotal != tt.bytesTotal {
+				t.Errorf("Expected bytes %d/%d, got %d/%d", tt.bytesDone, tt.bytesTotal, update.bytesDone, update.bytesTotal)
+			}
+		})
+	}
+}
+
+func TestRetocProgress_ParseLine_ExplicitETA(t *testing.T) {
+	update, ok := parseProgressLine("Writing 10/20 chunks (eta 00:01:30)")
+	if !ok || !update.hasETA {
+		t.Fatal("Expected ETA to be parsed")
+	}
+	if update.eta.Seconds() != 90 {
+		t.Errorf("Expected ETA of 90s, got %v", update.eta)
+	}
+}
+
+func TestRetocProgress_ParseLine_IgnoresPlainText(t *testing.T) {
+	if _, ok := parseProgressLine("Done."); ok {
+		t.Error("Expected plain text line to carry no progress")
+	}
+}
+
+func TestRetocProgress_ParseLine_IgnoresPathsVersionsAndPartWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		phase string
+	}{
+		{"Package /Game/Content/12/34/Foo already mounted", ""},
+		{"Using thread pool for 4.27/5.1", ""},
+		{"Output written on 2024/01/15", PhaseWriting},
+	}
+	for _, tt := range tests {
+		update, _ := parseProgressLine(tt.line)
+		if update.hasItems {
+			t.Errorf("%q: expected no item counter, got %d/%d", tt.line, update.itemsDone, update.itemsTotal)
+		}
+		if update.phase != tt.phase {
+			t.Errorf("%q: expected phase '%s', got '%s'", tt.line, tt.phase, update.phase)
+		}
+	}
+}
+
+func TestRetocProgress_Tracking_QueryableByOperationID(t *testing.T) {
+	appInstance := app.NewApp()
+	service := NewRetocService(appInstance, t.TempDir())
+
+	var mu sync.Mutex
+	var snapshots []RetocProgress
+	unsubscribe := service.SubscribeProgress(func(p RetocProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		snapshots = append(snapshots, p)
+	})
+	defer unsubscribe()
+
+	service.startProgress("op-1", "to-legacy")
+	service.trackProgress("op-1", "Extracting 25/100 packages")
+
+	progress, err := service.GetProgress(context.Background(), "op-1")
+	if err != nil {
+		t.Fatalf("Expected progress for op-1, got error: %v", err)
+	}
+	if progress.ItemsDone != 25 || progress.ItemsTotal != 100 {
+		t.Errorf("Expected 25/100 items, got %d/%d", progress.ItemsDone, progress.ItemsTotal)
+	}
+	if progress.Percent != 25 {
+		t.Errorf("Expected 25%%, got %v", progress.Percent)
+	}
+
+	service.finishProgress("op-1", PhaseCompleted)
+
+	progress, err = service.GetProgress(context.Background(), "op-1")
+	if err != nil {
+		t.Fatalf("Expected finished progress to remain queryable, got error: %v", err)
+	}
+	if !progress.Done || progress.Phase != PhaseCompleted {
+		t.Errorf("Expected completed progress, got %+v", progress)
+	}
+
+	if len(snapshots) != 3 {
+		t.Errorf("Expected 3 progress events (start, update, finish), got %d", len(snapshots))
+	}
+}
+
+func TestRetocProgress_UnknownOperation_ReturnsError(t *testing.T) {
+	service := NewRetocService(app.NewApp(), t.TempDir())
+
+	if _, err := service.GetProgress(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for unknown operation")
+	}
+}
+
+func TestRetocProgress_ScanLinesOrCR_SplitsCarriageReturns(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("1/3\r2/3\r3/3\r\nDone\n"))
+	scanner.Split(scanLinesOrCR)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	expected := []string{"1/3", "2/3", "3/3", "Done"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %q", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected '%s', got '%s'", i, expected[i], lines[i])
+		}
+	}
+}
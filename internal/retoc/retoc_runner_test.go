@@ -0,0 +1,140 @@
+package retoc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+)
+
+func TestRetocRunner_FakeRetoc_PacksAndRenamesOutput(t *testing.T) {
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		// retoc writes the .utoc named on its command line and its siblings
+		utoc := cmd.Args[len(cmd.Args)-1]
+		for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+			os.WriteFile(strings.TrimSuffix(utoc, ".utoc")+ext, []byte(ext), 0644)
+		}
+		fmt.Fprintln(stdout, "Packed 3 files")
+		fmt.Fprintln(stderr, "warning: none")
+		return nil
+	})
+	depsDir, input, output := t.TempDir(), filepath.Join(t.TempDir(), "MyMod"), t.TempDir()
+	service := NewRetocServiceWithRunner(app.NewApp(), depsDir, fake)
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: output,
+		Pack:       &PackOptions{ModName: "MyMod", EngineVersion: "UE5_3"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if !strings.Contains(result.Output, "Packed 3 files\n") || !strings.Contains(result.Output, "warning: none\n") {
+		t.Errorf("Expected both streams in the output, got %q", result.Output)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].Path != filepath.Join(depsDir, "retoc", "retoc") {
+		t.Fatalf("Expected one run of retoc from depsDir, got %+v", calls)
+	}
+	if !slices.Contains(calls[0].Args, "to-zen") || !slices.Contains(calls[0].Args, "UE5_3") {
+		t.Errorf("Expected to-zen arguments, got %v", calls[0].Args)
+	}
+	if _, err := os.Stat(filepath.Join(output, "z_MyMod_0001_p.utoc")); err != nil {
+		t.Errorf("Expected the output to be renamed: %v", err)
+	}
+}
+
+func TestRetocRunner_FakeRetoc_ExitStatusFails(t *testing.T) {
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		fmt.Fprintln(stderr, "error: bad container")
+		return &toolrunner.ExitError{Code: 2}
+	})
+	service := NewRetocServiceWithRunner(app.NewApp(), t.TempDir(), fake)
+
+	result := service.RunRetoc(context.Background(), RetocOperation{Command: "unpack", InputPath: t.TempDir(), OutputPath: t.TempDir()})
+	if result.Success || result.Error != "exit status 2" {
+		t.Errorf("Expected exit status 2, got %+v", result)
+	}
+	if progress, err := service.GetProgress(context.Background(), result.OperationID); err != nil || progress.Phase != PhaseFailed {
+		t.Errorf("Expected the failed phase, got %+v, %v", progress, err)
+	}
+}
+
+func TestRetocRunner_FakeRetoc_Cancelled(t *testing.T) {
+	fake := toolrunnertest.New()
+	started := make(chan struct{})
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	service := NewRetocServiceWithRunner(app.NewApp(), t.TempDir(), fake)
+
+	go func() {
+		<-started
+		service.CancelOperationByCommand(context.Background(), "unpack")
+	}()
+	result := service.RunRetoc(context.Background(), RetocOperation{Command: "unpack", InputPath: t.TempDir(), OutputPath: t.TempDir()})
+	if result.Success || result.Error != "cancelled" {
+		t.Errorf("Expected the operation to be cancelled, got %+v", result)
+	}
+}
+
+func TestRetocRunner_DryRun_PlansWithoutRunning(t *testing.T) {
+	fake := toolrunnertest.New()
+	fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		t.Error("Expected retoc not to run")
+		return nil
+	})
+	depsDir, input, output := t.TempDir(), filepath.Join(t.TempDir(), "MyMod"), t.TempDir()
+	os.WriteFile(filepath.Join(output, "z_MyMod_0001_p.pak"), []byte("live"), 0644)
+	os.WriteFile(filepath.Join(output, "MyMod.utoc"), []byte("stale"), 0644)
+	service := NewRetocServiceWithRunner(app.NewApp(), depsDir, fake)
+
+	result := service.RunRetoc(context.Background(), RetocOperation{
+		Command:    "to-zen",
+		InputPath:  input,
+		OutputPath: output,
+		Pack:       &PackOptions{ModName: "MyMod", EngineVersion: "UE5_3"},
+		DryRun:     true,
+	})
+	if !result.Success || result.Plan == nil {
+		t.Fatalf("Expected a plan, got %+v", result)
+	}
+	plan := result.Plan
+	retocPath := filepath.Join(depsDir, "retoc", "retoc")
+	if plan.Binary != retocPath || plan.Dir != filepath.Dir(retocPath) {
+		t.Errorf("Expected retoc from depsDir, got %+v", plan)
+	}
+	if len(plan.Argv) < 3 || plan.Argv[0] != retocPath || plan.Argv[1] != "to-zen" || plan.Argv[len(plan.Argv)-1] != filepath.Join(output, "MyMod.utoc") {
+		t.Errorf("Unexpected argv %v", plan.Argv)
+	}
+	wantOutputs := []string{
+		filepath.Join(output, "z_MyMod_0001_p.utoc"),
+		filepath.Join(output, "z_MyMod_0001_p.ucas"),
+		filepath.Join(output, "z_MyMod_0001_p.pak"),
+		filepath.Join(output, "z_MyMod_0001_p.manifest.json"),
+	}
+	if !slices.Equal(plan.Outputs, wantOutputs) {
+		t.Errorf("Expected outputs %v, got %v", wantOutputs, plan.Outputs)
+	}
+	wantOverwrites := []string{filepath.Join(output, "MyMod.utoc"), filepath.Join(output, "z_MyMod_0001_p.pak")}
+	if !slices.Equal(plan.Overwrites, wantOverwrites) {
+		t.Errorf("Expected overwrites %v, got %v", wantOverwrites, plan.Overwrites)
+	}
+	if data, _ := os.ReadFile(filepath.Join(output, "z_MyMod_0001_p.pak")); string(data) != "live" {
+		t.Error("Expected the live file to be left alone")
+	}
+}
@@ -115,13 +115,10 @@ func TestRetocUnpack_CancelByCommand_TargetsCorrectOperation(t *testing.T) {
 	appInstance := app.NewApp()
 	service := NewRetocService(appInstance, depsDir)
 
-	// Manually add a tracked operation
-	ctx, cancel := context.WithCancel(context.Background())
-	operationID := "test-operation-123"
-	service.operationsMutex.Lock()
-	service.runningOperations[operationID] = cancel
-	service.operationsByCommand["to-legacy"] = operationID
-	service.operationsMutex.Unlock()
+	// Two operations of the same command must both be reachable
+	first := appInstance.StartOperation(context.Background(), OperationOwner, "to-legacy", "")
+	second := appInstance.StartOperation(context.Background(), OperationOwner, "to-legacy", "")
+	other := appInstance.StartOperation(context.Background(), OperationOwner, "unpack", "")
 
 	err := service.CancelOperationByCommand(context.Background(), "to-legacy")
 
@@ -129,12 +126,16 @@ func TestRetocUnpack_CancelByCommand_TargetsCorrectOperation(t *testing.T) {
 		t.Errorf("Expected no error cancelling by command, got: %v", err)
 	}
 
-	// Verify context was cancelled
-	select {
-	case <-ctx.Done():
-		t.Log("Context cancelled successfully")
-	case <-time.After(1 * time.Second):
-		t.Error("Context was not cancelled")
+	// Verify both contexts were cancelled and the other command's was not
+	for _, op := range []*app.OperationHandle{first, second} {
+		select {
+		case <-op.Context().Done():
+		case <-time.After(1 * time.Second):
+			t.Errorf("Context of operation %s was not cancelled", op.ID())
+		}
+	}
+	if other.Context().Err() != nil {
+		t.Error("Expected the unpack operation to keep running")
 	}
 }
 
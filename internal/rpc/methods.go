@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+)
+
+// exposed lists the methods callable over RPC, by service. They are called
+// as "<Service>.<Method>", e.g. "RetocService.RunRetoc", with the same
+// parameters the frontend binding takes, minus the context.
+var exposed = map[string][]string{
+	"App": {
+		"GetPreference", "SetPreference",
+		"GetLastUsedPath", "SetLastUsedPath",
+		"GetInitialConfig",
+		"ListProfiles", "GetProfile", "SaveProfile",
+		"ListOperations", "GetOperation", "CancelOperation",
+	},
+	"RetocService": {
+		"RunRetoc", "CancelOperation", "GetProgress",
+		"ListContainer", "InspectContainer", "DetectEngineVersion",
+		"PreviewPackNames", "ValidatePackOptions",
+	},
+	"UAssetService": {
+		"ExportUAssets", "ImportUAssets", "RunUAsset",
+	},
+	"JobQueue": {
+		"Submit", "List", "Get", "Cancel",
+		"Pause", "Resume", "IsPaused", "ClearFinished",
+		"GetConcurrency", "SetConcurrency",
+	},
+	"HistoryService": {
+		"Query", "Get", "Rerun",
+	},
+	"WatchService": {
+		"Start", "Stop", "List",
+	},
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeServerError    = -32000 // The method returned an error
+)
+
+// request is a JSON-RPC 2.0 request. A request without an ID is a
+// notification and gets no response.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC 2.0 response, and with Method set and no ID, a
+// notification sent to WebSocket clients.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// method is a bound service method callable with JSON parameters.
+type method struct {
+	fn      reflect.Value
+	params  []reflect.Type // Excluding a leading context.Context
+	withCtx bool
+}
+
+// bindMethods returns the exposed methods of services, keyed by
+// "<Service>.<Method>".
+func bindMethods(services map[string]any) map[string]*method {
+	methods := make(map[string]*method)
+	for service, names := range exposed {
+		v := reflect.ValueOf(services[service])
+		if !v.IsValid() || v.IsNil() {
+			continue
+		}
+		for _, name := range names {
+			fn := v.MethodByName(name)
+			if !fn.IsValid() {
+				panic(fmt.Sprintf("rpc: %s has no method %s", service, name))
+			}
+			m := &method{fn: fn}
+			t := fn.Type()
+			for i := 0; i < t.NumIn(); i++ {
+				if i == 0 && t.In(0) == contextType {
+					m.withCtx = true
+					continue
+				}
+				m.params = append(m.params, t.In(i))
+			}
+			methods[service+"."+name] = m
+		}
+	}
+	return methods
+}
+
+// call decodes params, which may be an array of positional parameters or,
+// for a method taking one parameter, that parameter itself, and calls the
+// method. A trailing error result is returned as the error.
+func (m *method) call(ctx context.Context, params json.RawMessage) (any, error) {
+	var raw []json.RawMessage
+	trimmed := bytes.TrimSpace(params)
+	switch {
+	case len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")):
+	case trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	case len(m.params) == 1:
+		raw = []json.RawMessage{trimmed}
+	default:
+		return nil, &rpcError{Code: codeInvalidParams, Message: "params must be an array"}
+	}
+	if len(raw) != len(m.params) {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("expected %d parameter(s), got %d", len(m.params), len(raw))}
+	}
+
+	args := make([]reflect.Value, 0, len(raw)+1)
+	if m.withCtx {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	for i, t := range m.params {
+		arg := reflect.New(t)
+		if err := json.Unmarshal(raw[i], arg.Interface()); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("parameter %d: %v", i+1, err)}
+		}
+		args = append(args, arg.Elem())
+	}
+
+	out := m.fn.Call(args)
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out[0].Interface(), nil
+}
+
+// handle runs one request and returns its response, or nil for a
+// notification.
+func (s *Server) handle(ctx context.Context, req request) *response {
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	result, err := s.dispatch(ctx, req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: codeServerError, Message: err.Error()}
+		}
+		resp.Error = rerr
+		return resp
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	resp.Result = result
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: `expected "jsonrpc": "2.0" and a method`}
+	}
+	m, ok := s.methods[req.Method]
+	if !ok {
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	if req.Method == "App.SetPreference" {
+		// Scripts must not be able to reconfigure the server they talk to,
+		// or choose the programs tools are run with.
+		var params []string
+		if json.Unmarshal(req.Params, &params) == nil && len(params) > 0 && protectedPreference(params[0]) {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("preference %q cannot be set over RPC", params[0])}
+		}
+	}
+	return m.call(ctx, req.Params)
+}
+
+// protectedPreference reports whether key cannot be set over RPC.
+func protectedPreference(key string) bool {
+	return strings.HasPrefix(key, "rpc_") || strings.HasPrefix(key, toolrunner.PrefRunner)
+}
+
+// handleMessage runs a single request or a batch and returns the encoded
+// response, or nil if there is nothing to send back.
+func (s *Server) handleMessage(ctx context.Context, data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []request
+		if err := json.Unmarshal(data, &batch); err != nil || len(batch) == 0 {
+			return encode(errorResponse(codeInvalidRequest, "invalid batch"))
+		}
+		var responses []*response
+		for _, req := range batch {
+			if resp := s.handle(ctx, req); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return encode(responses)
+	}
+
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return encode(errorResponse(codeParseError, err.Error()))
+	}
+	if resp := s.handle(ctx, req); resp != nil {
+		return encode(resp)
+	}
+	return nil
+}
+
+func errorResponse(code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: code, Message: message}}
+}
+
+func encode(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(errorResponse(codeInternalError, err.Error()))
+	}
+	return data
+}
@@ -0,0 +1,285 @@
+// Package rpc exposes the bound service methods of a running ARI-S instance
+// over a localhost-only JSON-RPC 2.0 endpoint, so that Python and editor
+// scripts can drive it. Requests are POSTed to /rpc or sent over a WebSocket
+// at /ws, which also streams progress notifications. Every request must
+// carry the token stored in the data directory. The server is disabled by
+// default; see [PrefEnabled].
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/history"
+	"github.com/JaceTheGrayOne/ARI-S/internal/jobs"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+	"github.com/JaceTheGrayOne/ARI-S/internal/watch"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Preference keys that configure the server. They cannot be changed
+// through the RPC endpoint itself.
+const (
+	PrefEnabled = "rpc_enabled" // "true" starts the server with the app
+	PrefPort    = "rpc_port"    // TCP port on 127.0.0.1; defaults to DefaultPort
+)
+
+// DefaultPort is the port the server listens on unless PrefPort is set.
+const DefaultPort = 48620
+
+// TokenFileName is the file in the data directory holding the token that
+// clients send as "Authorization: Bearer <token>". It is created with
+// owner-only permissions the first time the server starts.
+const TokenFileName = "rpc-token"
+
+// Status describes the state of the automation server.
+type Status struct {
+	Enabled   bool   `json:"enabled"`
+	Running   bool   `json:"running"`
+	Address   string `json:"address"`    // e.g. "127.0.0.1:48620" while running
+	TokenPath string `json:"token_path"` // File scripts read the token from
+	Error     string `json:"error"`      // Why the server failed to start, if it did
+}
+
+// Server serves the JSON-RPC endpoint. It is registered as a Wails service
+// so that it starts and stops with the app and the frontend can toggle it.
+type Server struct {
+	app     *app.App
+	methods map[string]*method
+
+	mu       sync.Mutex
+	listener net.Listener
+	http     *http.Server
+	token    string
+	lastErr  error
+	clients  map[*wsClient]struct{}
+	unsubs   []func()
+}
+
+// NewServer creates a Server exposing the methods listed in exposed of the
+// given services. The server is not started.
+func NewServer(a *app.App, r *retoc.RetocService, u *uasset.UAssetService, q *jobs.JobQueue, h *history.HistoryService, w *watch.WatchService) *Server {
+	return &Server{
+		app: a,
+		methods: bindMethods(map[string]any{
+			"App":            a,
+			"RetocService":   r,
+			"UAssetService":  u,
+			"JobQueue":       q,
+			"HistoryService": h,
+			"WatchService":   w,
+		}),
+		clients: make(map[*wsClient]struct{}),
+	}
+}
+
+// ServiceStartup starts the server if it is enabled. A failure to start is
+// logged and reported by GetStatus rather than stopping the app.
+func (s *Server) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	if s.app.GetPreference(PrefEnabled) == "true" {
+		if err := s.Start(); err != nil {
+			log.Printf("Automation API failed to start: %v", err)
+		}
+	}
+	return nil
+}
+
+// ServiceShutdown stops the server.
+func (s *Server) ServiceShutdown() error {
+	return s.Stop()
+}
+
+// GetStatus returns the state of the server.
+func (s *Server) GetStatus(ctx context.Context) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Enabled:   s.app.GetPreference(PrefEnabled) == "true",
+		Running:   s.listener != nil,
+		TokenPath: s.tokenPath(),
+	}
+	if s.listener != nil {
+		status.Address = s.listener.Addr().String()
+	}
+	if s.lastErr != nil {
+		status.Error = s.lastErr.Error()
+	}
+	return status
+}
+
+// SetEnabled stores whether the server runs with the app and starts or
+// stops it accordingly.
+func (s *Server) SetEnabled(ctx context.Context, enabled bool) (Status, error) {
+	s.app.SetPreference(PrefEnabled, strconv.FormatBool(enabled))
+	var err error
+	if enabled {
+		err = s.Start()
+	} else {
+		err = s.Stop()
+	}
+	return s.GetStatus(ctx), err
+}
+
+// RegenerateToken replaces the token, invalidating the one clients hold.
+// Open WebSocket connections stay open.
+func (s *Server) RegenerateToken(ctx context.Context) (Status, error) {
+	s.mu.Lock()
+	token, err := s.writeToken()
+	if err == nil {
+		s.token = token
+	}
+	s.mu.Unlock()
+	return s.GetStatus(ctx), err
+}
+
+// Start listens on 127.0.0.1 at the configured port. It does nothing if
+// the server is already running.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return nil
+	}
+
+	s.lastErr = s.start()
+	return s.lastErr
+}
+
+func (s *Server) start() error {
+	port := DefaultPort
+	if value := s.app.GetPreference(PrefPort); value != "" {
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 0 || p > 65535 {
+			return fmt.Errorf("invalid %s preference %q", PrefPort, value)
+		}
+		port = p
+	}
+
+	token, err := s.readToken()
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", s.serveHTTP)
+	mux.Handle("/ws", s.wsHandler())
+	s.token = token
+	s.listener = listener
+	s.http = &http.Server{Handler: s.guard(mux), ReadHeaderTimeout: 10 * time.Second}
+	s.subscribe()
+
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Automation API stopped: %v", err)
+		}
+	}(s.http)
+	log.Printf("Automation API listening on %s", listener.Addr())
+	return nil
+}
+
+// Stop closes the listener and all connections. Running operations started
+// over RPC are cancelled with their connections.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+
+	for _, unsub := range s.unsubs {
+		unsub()
+	}
+	s.unsubs = nil
+	for c := range s.clients {
+		c.close()
+	}
+	err := s.http.Close()
+	s.listener, s.http, s.lastErr = nil, nil, nil
+	return err
+}
+
+// guard rejects requests that are not addressed to the loopback interface
+// by name, which defeats DNS rebinding, or that lack the token.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if host != "127.0.0.1" && host != "localhost" && host != "::1" {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether r carries the token, as a bearer token or,
+// for WebSocket clients that cannot set headers, a "token" query parameter.
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	s.mu.Lock()
+	want := s.token
+	s.mu.Unlock()
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+func (s *Server) tokenPath() string {
+	return filepath.Join(s.app.DataDir(), TokenFileName)
+}
+
+// readToken returns the stored token, creating one if there is none.
+func (s *Server) readToken() (string, error) {
+	data, err := os.ReadFile(s.tokenPath())
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return s.writeToken()
+}
+
+// writeToken stores a new random token and returns it.
+func (s *Server) writeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(s.tokenPath()), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(s.tokenPath(), []byte(token), 0600); err != nil {
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,268 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"golang.org/x/net/websocket"
+)
+
+// startServer starts a Server for an App whose data directory is temporary,
+// on a free port, and returns it with its address and token.
+func startServer(t *testing.T) (*Server, *app.App, string, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+	a := app.NewApp()
+	if err := a.LoadConfiguration(); err != nil {
+		t.Fatalf("LoadConfiguration failed: %v", err)
+	}
+	a.SetPreference(PrefPort, "0")
+
+	s := NewServer(a, nil, nil, nil, nil, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	status := s.GetStatus(context.Background())
+	if !status.Running || status.Address == "" {
+		t.Fatalf("Expected a running server, got %+v", status)
+	}
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	return s, a, status.Address, token
+}
+
+// post sends body to /rpc and returns the status code and decoded response.
+func post(t *testing.T, addr, token, body string) (int, response) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/rpc", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out response
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+func TestServer_MissingOrWrongToken_Unauthorized(t *testing.T) {
+	_, _, addr, _ := startServer(t)
+	body := `{"jsonrpc":"2.0","id":1,"method":"App.GetPreference","params":["theme"]}`
+
+	for _, token := range []string{"", "not-the-token"} {
+		if code, _ := post(t, addr, token, body); code != http.StatusUnauthorized {
+			t.Errorf("Token %q: expected 401, got %d", token, code)
+		}
+	}
+}
+
+func TestServer_GetPreference_ReturnsValue(t *testing.T) {
+	_, a, addr, token := startServer(t)
+	a.SetPreference("theme", "dark")
+
+	code, resp := post(t, addr, token, `{"jsonrpc":"2.0","id":1,"method":"App.GetPreference","params":["theme"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.Error != nil || resp.Result != "dark" {
+		t.Errorf("Expected result \"dark\", got %+v", resp)
+	}
+	if string(resp.ID) != "1" {
+		t.Errorf("Expected id 1, got %s", resp.ID)
+	}
+}
+
+func TestServer_UnknownMethodAndBadParams_ReturnErrors(t *testing.T) {
+	_, _, addr, token := startServer(t)
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"App.Quit"}`, codeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":1,"method":"App.GetPreference","params":[1]}`, codeInvalidParams},
+		{`{"jsonrpc":"2.0","id":1,"method":"App.GetPreference","params":["a","b"]}`, codeInvalidParams},
+		{`{"jsonrpc":"2.0","id":1,"method":"App.GetPreference"`, codeParseError},
+		{`{"id":1,"method":"App.GetPreference"}`, codeInvalidRequest},
+	}
+	for _, tt := range tests {
+		_, resp := post(t, addr, token, tt.body)
+		if resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: expected error code %d, got %+v", tt.body, tt.code, resp.Error)
+		}
+	}
+}
+
+func TestServer_SetRPCPreference_Rejected(t *testing.T) {
+	_, a, addr, token := startServer(t)
+
+	_, resp := post(t, addr, token, `{"jsonrpc":"2.0","id":1,"method":"App.SetPreference","params":["rpc_port","1"]}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Errorf("Expected an invalid params error, got %+v", resp)
+	}
+	if got := a.GetPreference(PrefPort); got != "0" {
+		t.Errorf("Expected %s to stay \"0\", got %q", PrefPort, got)
+	}
+
+	_, resp = post(t, addr, token, `{"jsonrpc":"2.0","id":2,"method":"App.SetPreference","params":["tool_runner_wine","/tmp/evil"]}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Errorf("Expected an invalid params error for the tool runner, got %+v", resp)
+	}
+}
+
+func TestServer_ConcurrentPreferenceWrites_AreSafe(t *testing.T) {
+	_, a, addr, token := startServer(t)
+
+	// Each request is handled on its own goroutine; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, body := range []string{
+				fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"App.SetPreference","params":["theme","t%d"]}`, i, i),
+				`{"jsonrpc":"2.0","id":1,"method":"App.ListProfiles"}`,
+			} {
+				req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/rpc", strings.NewReader(body))
+				req.Header.Set("Authorization", "Bearer "+token)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Errorf("POST failed: %v", err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if theme := a.GetPreference("theme"); !strings.HasPrefix(theme, "t") {
+		t.Errorf("Expected one of the written themes, got %q", theme)
+	}
+}
+
+func TestServer_ForeignHost_Forbidden(t *testing.T) {
+	_, _, addr, token := startServer(t)
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/rpc", strings.NewReader(`{}`))
+	req.Host = "evil.example.com"
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_WebSocket_ReceivesResponsesAndProgress(t *testing.T) {
+	_, a, addr, token := startServer(t)
+	config, err := websocket.NewConfig("ws://"+addr+"/ws?token="+token, "http://localhost/")
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The response also shows the client is registered for notifications
+	if err := websocket.Message.Send(conn, `{"jsonrpc":"2.0","id":"a","method":"App.GetPreference","params":["missing"]}`); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	var resp response
+	if err := websocket.JSON.Receive(conn, &resp); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if string(resp.ID) != `"a"` || resp.Error != nil {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+
+	a.Emit(retoc.EventProgress, retoc.RetocProgress{OperationID: "op-1", Phase: "Packing", Percent: 50})
+	var note struct {
+		Method string              `json:"method"`
+		Params retoc.RetocProgress `json:"params"`
+	}
+	if err := websocket.JSON.Receive(conn, &note); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if note.Method != retoc.EventProgress || note.Params.OperationID != "op-1" || note.Params.Percent != 50 {
+		t.Errorf("Unexpected notification %+v", note)
+	}
+}
+
+func TestServer_WebSocket_ForeignOrigin_Rejected(t *testing.T) {
+	_, _, addr, token := startServer(t)
+	if _, err := websocket.Dial("ws://"+addr+"/ws?token="+token, "", "https://evil.example.com"); err == nil {
+		t.Error("Expected the handshake to fail for a foreign origin")
+	}
+}
+
+func TestServer_WebSocket_StalledClient_DisconnectedWithoutBlockingEmit(t *testing.T) {
+	s, a, addr, token := startServer(t)
+	conn, err := websocket.Dial("ws://"+addr+"/ws?token="+token, "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the client to register")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The client never reads, so its socket and then its queue fill up
+	line := strings.Repeat("x", 64<<10)
+	start := time.Now()
+	for i := 0; i < 2*sendQueueSize+1000; i++ {
+		a.Emit(retoc.EventOutput, line)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Emit not to wait on the client, took %v", elapsed)
+	}
+
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline.Add(5 * time.Second)) {
+			t.Fatal("Expected the stalled client to be disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
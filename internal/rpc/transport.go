@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/history"
+	"github.com/JaceTheGrayOne/ARI-S/internal/jobs"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/watch"
+	"golang.org/x/net/websocket"
+)
+
+// maxRequestSize bounds the body of a request or WebSocket message.
+const maxRequestSize = 1 << 20
+
+// sendQueueSize is how many messages may wait for a WebSocket client before
+// it is considered stalled, and writeTimeout how long a single write may
+// take.
+const (
+	sendQueueSize = 256
+	writeTimeout  = 10 * time.Second
+)
+
+// notifications are the events forwarded to WebSocket clients as JSON-RPC
+// notifications whose method is the event name.
+var notifications = []string{
+	retoc.EventStarted, retoc.EventProgress, retoc.EventOutput,
+	app.EventOperation, jobs.EventJob, history.EventRecorded,
+	watch.EventWatch,
+}
+
+// serveHTTP handles a request or batch POSTed to /rpc. The request context
+// is cancelled if the client disconnects, which cancels a running operation.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resp := s.handleMessage(r.Context(), data)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// wsClient is a WebSocket connection. Responses and notifications are
+// queued and written in order by the client's own writer, so a client that
+// stops reading never blocks an event or another client.
+type wsClient struct {
+	conn  *websocket.Conn
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newWSClient(conn *websocket.Conn) *wsClient {
+	return &wsClient{conn: conn, queue: make(chan []byte, sendQueueSize), done: make(chan struct{})}
+}
+
+// writeLoop writes queued messages until the client is closed or a write
+// fails, then closes the connection.
+func (c *wsClient) writeLoop() {
+	defer c.conn.Close()
+	for {
+		select {
+		case data := <-c.queue:
+			// close cuts the deadline short, so check done after setting it
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+			if err := websocket.Message.Send(c.conn, string(data)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// send queues a response, waiting for room unless the client is closed.
+func (c *wsClient) send(data []byte) {
+	select {
+	case c.queue <- data:
+	case <-c.done:
+	}
+}
+
+// notify queues a notification without waiting. A client whose queue is
+// full is not keeping up, so it is disconnected rather than sent a partial
+// stream of events.
+func (c *wsClient) notify(data []byte) {
+	select {
+	case c.queue <- data:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+// close stops the writer, which closes the connection. A write in progress
+// is abandoned, since the client may never read it.
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.SetWriteDeadline(time.Now())
+	})
+}
+
+// wsHandler serves /ws. Each message is a request or batch, handled
+// concurrently so that a long operation does not block GetProgress or
+// CancelOperation on the same connection.
+func (s *Server) wsHandler() http.Handler {
+	return websocket.Server{
+		Handshake: checkOrigin,
+		Handler: func(conn *websocket.Conn) {
+			conn.MaxPayloadBytes = maxRequestSize
+			c := newWSClient(conn)
+			go c.writeLoop()
+			s.mu.Lock()
+			s.clients[c] = struct{}{}
+			s.mu.Unlock()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer func() {
+				cancel()
+				s.mu.Lock()
+				delete(s.clients, c)
+				s.mu.Unlock()
+				c.close()
+			}()
+
+			for {
+				var data []byte
+				if err := websocket.Message.Receive(conn, &data); err != nil {
+					return
+				}
+				go func() {
+					if resp := s.handleMessage(ctx, data); resp != nil {
+						c.send(resp)
+					}
+				}()
+			}
+		},
+	}
+}
+
+// checkOrigin accepts clients that send no Origin, such as scripts, and
+// pages served from localhost, but not other web pages.
+func checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	switch u.Hostname() {
+	case "127.0.0.1", "localhost", "::1":
+		config.Origin = u
+		return nil
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// subscribe forwards notifications to WebSocket clients. It is called with
+// s.mu held while starting.
+func (s *Server) subscribe() {
+	for _, name := range notifications {
+		s.unsubs = append(s.unsubs, s.app.Subscribe(name, func(data any) {
+			s.broadcast(encode(&response{JSONRPC: "2.0", Method: name, Params: data}))
+		}))
+	}
+}
+
+// broadcast queues a notification for every client. It runs inside Emit,
+// so it never waits on a connection.
+func (s *Server) broadcast(data []byte) {
+	s.mu.Lock()
+	clients := make([]*wsClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.notify(data)
+	}
+}
@@ -0,0 +1,70 @@
+package toolrunner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// ExecRunner runs tools as native processes. On Windows a tool's binary is
+// tool.exe; elsewhere it is tool, or tool.exe if there is no native build,
+// which runs when the system can execute it directly (a script, or Wine
+// registered through binfmt_misc).
+type ExecRunner struct{}
+
+// Resolve implements [ToolRunner].
+func (ExecRunner) Resolve(dir, tool string) (string, error) {
+	names := []string{tool, tool + ".exe"}
+	if runtime.GOOS == "windows" {
+		names = names[1:]
+	}
+	return resolve(dir, names)
+}
+
+// Run implements [ToolRunner].
+func (e ExecRunner) Run(ctx context.Context, cmd Command) error {
+	return execute(ctx, e.Plan(cmd), cmd)
+}
+
+// Plan implements [ToolRunner].
+func (ExecRunner) Plan(cmd Command) Plan {
+	return Plan{
+		Binary: cmd.Path,
+		Argv:   append([]string{cmd.Path}, cmd.Args...),
+		Dir:    cmd.dir(),
+		Env:    slices.Clone(cmd.Env),
+	}
+}
+
+// resolve returns the first of names that exists in dir.
+func resolve(dir string, names []string) (string, error) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", &NotFoundError{Path: filepath.Join(dir, names[0])}
+}
+
+// execute runs p, sending output where cmd asks, and waits for it.
+func execute(ctx context.Context, p Plan, cmd Command) error {
+	c := exec.CommandContext(ctx, p.Binary, p.Argv[1:]...)
+	c.Dir = p.Dir
+	if len(p.Env) > 0 {
+		c.Env = append(os.Environ(), p.Env...)
+	}
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return &ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+// Package toolrunner runs the external tools ARI-S bundles, retoc and
+// UAssetBridge, so that the services invoking them do not depend on how
+// they are run. [ExecRunner] starts them directly, [WineRunner] starts the
+// Windows builds under Wine, and package toolrunnertest provides an
+// in-memory fake for tests.
+package toolrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+)
+
+// ToolRunner resolves and runs a tool's binary.
+//
+// Implementations are safe for concurrent use by multiple goroutines.
+type ToolRunner interface {
+	// Resolve returns the path of the binary of the named tool, such as
+	// "retoc", in dir. It returns a [*NotFoundError] if there is none.
+	Resolve(dir, tool string) (string, error)
+
+	// Run runs cmd and waits for it to exit and for its output to be
+	// written. Cancelling ctx kills it. A non-zero exit status is returned
+	// as an [*ExitError].
+	Run(ctx context.Context, cmd Command) error
+
+	// Plan returns how Run would run cmd, without running anything.
+	Plan(cmd Command) Plan
+}
+
+// Command is one run of a tool.
+type Command struct {
+	Path string   // As returned by Resolve
+	Args []string // Not including the program name
+	Dir  string   // Working directory; empty means Path's directory
+	Env  []string // "KEY=value" entries added to the current environment
+
+	// Stdout and Stderr receive the tool's output as it is written; nil
+	// discards it. If they are the same writer, at most one goroutine
+	// writes to it at a time.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (c Command) dir() string {
+	if c.Dir == "" {
+		return filepath.Dir(c.Path)
+	}
+	return c.Dir
+}
+
+// Plan is how a runner would run a [Command]: the program it starts, with
+// its full argument vector, working directory and added environment. For
+// dry runs, services add the files the run would write.
+type Plan struct {
+	Binary     string   `json:"binary"`
+	Argv       []string `json:"argv"` // Starting with Binary
+	Dir        string   `json:"dir"`
+	Env        []string `json:"env,omitempty"`
+	Outputs    []string `json:"outputs,omitempty"`    // Files written, under their final names
+	Overwrites []string `json:"overwrites,omitempty"` // Existing files replaced
+}
+
+// NotFoundError is returned by Resolve when a tool has no binary.
+type NotFoundError struct {
+	Path string // The binary looked for first
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found at: %s", filepath.Base(e.Path), e.Path)
+}
+
+// ExitError reports a tool that exited with a non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Preferences selecting the runner [ForApp] returns.
+const (
+	PrefRunner     = "tool_runner"             // RunnerNative (the default) or RunnerWine
+	PrefWine       = "tool_runner_wine"        // Wine binary; empty means "wine"
+	PrefWinePrefix = "tool_runner_wine_prefix" // WINEPREFIX; empty means Wine's default
+)
+
+// Values of [PrefRunner].
+const (
+	RunnerNative = "native"
+	RunnerWine   = "wine"
+)
+
+// ForApp returns the runner the App's preferences select. Wine is only
+// used outside Windows.
+func ForApp(a *app.App) ToolRunner {
+	if runtime.GOOS != "windows" && a.GetPreference(PrefRunner) == RunnerWine {
+		return &WineRunner{
+			Wine:   a.GetPreference(PrefWine),
+			Prefix: a.GetPreference(PrefWinePrefix),
+		}
+	}
+	return ExecRunner{}
+}
@@ -0,0 +1,106 @@
+package toolrunner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeScript writes a shell script named name to dir.
+func writeScript(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestExecRunner_Resolve_PrefersNativeBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows only resolves tool.exe")
+	}
+	dir := t.TempDir()
+	var runner ExecRunner
+
+	var notFound *NotFoundError
+	if _, err := runner.Resolve(dir, "retoc"); !errors.As(err, &notFound) || notFound.Path != filepath.Join(dir, "retoc") {
+		t.Errorf("Expected a NotFoundError for %s, got %v", filepath.Join(dir, "retoc"), err)
+	}
+
+	exe := writeScript(t, dir, "retoc.exe", "")
+	if path, err := runner.Resolve(dir, "retoc"); err != nil || path != exe {
+		t.Errorf("Expected the fallback %s, got %q, %v", exe, path, err)
+	}
+	native := writeScript(t, dir, "retoc", "")
+	if path, err := runner.Resolve(dir, "retoc"); err != nil || path != native {
+		t.Errorf("Expected the native %s, got %q, %v", native, path, err)
+	}
+}
+
+func TestExecRunner_Run_StreamsOutputAndExitStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock tool is a shell script")
+	}
+	dir := t.TempDir()
+	path := writeScript(t, dir, "tool", `echo "out $1 $TOOL_VAR $(pwd)"; echo err >&2; exit 3`+"\n")
+
+	var stdout, stderr bytes.Buffer
+	err := ExecRunner{}.Run(context.Background(), Command{
+		Path:   path,
+		Args:   []string{"arg"},
+		Env:    []string{"TOOL_VAR=set"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("Expected exit status 3, got %v", err)
+	}
+	resolvedDir, _ := filepath.EvalSymlinks(dir)
+	if got := strings.TrimSpace(stdout.String()); got != "out arg set "+resolvedDir && got != "out arg set "+dir {
+		t.Errorf("Unexpected stdout %q", got)
+	}
+	if got := strings.TrimSpace(stderr.String()); got != "err" {
+		t.Errorf("Expected stderr \"err\", got %q", got)
+	}
+}
+
+func TestWineRunner_Run_PassesExeAndPrefixToWine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mock wine is a shell script")
+	}
+	dir := t.TempDir()
+	wine := writeScript(t, t.TempDir(), "wine", `echo "$WINEPREFIX $WINEDEBUG $@"`+"\n")
+	runner := &WineRunner{Wine: wine, Prefix: "/prefix"}
+
+	writeScript(t, dir, "retoc", "") // Native builds are ignored
+	if _, err := runner.Resolve(dir, "retoc"); err == nil {
+		t.Fatal("Expected only retoc.exe to resolve")
+	}
+	exe := writeScript(t, dir, "retoc.exe", "")
+	path, err := runner.Resolve(dir, "retoc")
+	if err != nil || path != exe {
+		t.Fatalf("Expected %s, got %q, %v", exe, path, err)
+	}
+
+	plan := runner.Plan(Command{Path: path, Args: []string{"info"}})
+	if plan.Binary != wine || len(plan.Argv) != 3 || plan.Argv[1] != exe || plan.Dir != dir || !slices.Contains(plan.Env, "WINEPREFIX=/prefix") {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+
+	var stdout bytes.Buffer
+	if err := runner.Run(context.Background(), Command{Path: path, Args: []string{"info", "C:/x"}, Stdout: &stdout}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got, want := strings.TrimSpace(stdout.String()), "/prefix -all "+exe+" info C:/x"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,97 @@
+// Package toolrunnertest provides an in-memory [toolrunner.ToolRunner] so
+// that services running tools can be tested without the tools.
+package toolrunnertest
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+)
+
+// Tool stands in for a tool's binary. It writes the tool's output to
+// stdout and stderr and returns what [toolrunner.ToolRunner.Run] returns,
+// typically nil or a [*toolrunner.ExitError]. It should return promptly
+// once ctx is cancelled.
+type Tool func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error
+
+// FakeRunner runs registered [Tool] functions in place of binaries. The
+// binary of a tool is dir/tool whatever the OS; tools that have not been
+// registered are not found.
+//
+// FakeRunner is safe for concurrent use by multiple goroutines.
+type FakeRunner struct {
+	mu    sync.Mutex
+	tools map[string]Tool
+	calls []toolrunner.Command
+}
+
+// New returns a FakeRunner with no tools.
+func New() *FakeRunner {
+	return &FakeRunner{tools: make(map[string]Tool)}
+}
+
+// Handle registers fn as the tool named tool, such as "retoc".
+func (f *FakeRunner) Handle(tool string, fn Tool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tools[tool] = fn
+}
+
+// Calls returns the commands run so far, oldest first.
+func (f *FakeRunner) Calls() []toolrunner.Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// Resolve implements [toolrunner.ToolRunner].
+func (f *FakeRunner) Resolve(dir, tool string) (string, error) {
+	path := filepath.Join(dir, tool)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.tools[tool] == nil {
+		return "", &toolrunner.NotFoundError{Path: path}
+	}
+	return path, nil
+}
+
+// Run implements [toolrunner.ToolRunner].
+func (f *FakeRunner) Run(ctx context.Context, cmd toolrunner.Command) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, cmd)
+	fn := f.tools[filepath.Base(cmd.Path)]
+	f.mu.Unlock()
+	if fn == nil {
+		return &toolrunner.NotFoundError{Path: cmd.Path}
+	}
+
+	if err := fn(ctx, cmd, output(cmd.Stdout), output(cmd.Stderr)); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Plan implements [toolrunner.ToolRunner].
+func (f *FakeRunner) Plan(cmd toolrunner.Command) toolrunner.Plan {
+	dir := cmd.Dir
+	if dir == "" {
+		dir = filepath.Dir(cmd.Path)
+	}
+	return toolrunner.Plan{
+		Binary: cmd.Path,
+		Argv:   append([]string{cmd.Path}, cmd.Args...),
+		Dir:    dir,
+		Env:    slices.Clone(cmd.Env),
+	}
+}
+
+func output(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
@@ -0,0 +1,45 @@
+package toolrunner
+
+import (
+	"context"
+	"os/exec"
+)
+
+// WineRunner runs the Windows builds of tools under Wine, for Linux users
+// without native builds. A tool's binary is always tool.exe.
+type WineRunner struct {
+	Wine   string // Wine binary; empty means "wine" on the PATH
+	Prefix string // WINEPREFIX; empty means Wine's default
+}
+
+// Resolve implements [ToolRunner].
+func (w *WineRunner) Resolve(dir, tool string) (string, error) {
+	return resolve(dir, []string{tool + ".exe"})
+}
+
+// Run implements [ToolRunner].
+func (w *WineRunner) Run(ctx context.Context, cmd Command) error {
+	return execute(ctx, w.Plan(cmd), cmd)
+}
+
+// Plan implements [ToolRunner]. Wine's own debug output is turned off so
+// that only the tool's output is seen.
+func (w *WineRunner) Plan(cmd Command) Plan {
+	wine := w.Wine
+	if wine == "" {
+		wine = "wine"
+	}
+	if path, err := exec.LookPath(wine); err == nil {
+		wine = path
+	}
+	env := []string{"WINEDEBUG=-all"}
+	if w.Prefix != "" {
+		env = append(env, "WINEPREFIX="+w.Prefix)
+	}
+	return Plan{
+		Binary: wine,
+		Argv:   append([]string{wine, cmd.Path}, cmd.Args...),
+		Dir:    cmd.dir(),
+		Env:    append(env, cmd.Env...),
+	}
+}
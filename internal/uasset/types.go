@@ -1,5 +1,19 @@
 package uasset
 
+import (
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+)
+
+// OperationOwner is the owner of the operations UAssetService registers
+// with the App.
+const OperationOwner = "uasset"
+
+// EventFinished is emitted with a [UAssetFinished] when an export or import
+// ends, successfully or not.
+const EventFinished = "uasset:finished"
+
 // UAssetResult contains the outcome of a UAsset export or import operation.
 // The FilesProcessed count is extracted from the bridge output if available.
 // This type is shared between IPC and Native implementations.
@@ -10,4 +24,28 @@ type UAssetResult struct {
 	Error          string `json:"error"`
 	Duration       string `json:"duration"`
 	FilesProcessed int    `json:"files_processed"`
+	OperationID    string `json:"operation_id"` // ID for tracking/cancelling through the App
+
+	Plan *toolrunner.Plan `json:"plan,omitempty"` // Set for dry runs
+}
+
+// UAssetRequest describes an export or import for [UAssetService.RunUAsset].
+// ProfileID selects a game profile whose mappings file is used when
+// MappingsPath is empty. DryRun plans the bridge invocation and the files
+// it would write without running it.
+type UAssetRequest struct {
+	Command      string `json:"command"` // "export" or "import"
+	FolderPath   string `json:"folder_path"`
+	MappingsPath string `json:"mappings_path"`
+	ProfileID    string `json:"profile_id"`
+	DryRun       bool   `json:"dry_run"`
+}
+
+// UAssetFinished describes an export or import that ended. Request is the
+// request as run, with the mappings file its game profile provides filled
+// in, so running it again repeats it.
+type UAssetFinished struct {
+	Request   UAssetRequest `json:"request"`
+	StartedAt time.Time     `json:"started_at"`
+	Result    UAssetResult  `json:"result"`
 }
@@ -4,35 +4,58 @@
 package uasset
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
 )
 
 // UAssetService provides UAsset serialization operations using an external
 // UAssetBridge.exe process via IPC. It supports exporting .uasset/.uexp files
 // to JSON and importing JSON back to binary format.
 //
+// Exports and imports are registered with the App's operation registry as
+// owner [OperationOwner], so they can be listed and cancelled through
+// [app.App.ListOperations] and [app.App.CancelOperation].
+//
 // UAssetService is safe for concurrent use by multiple goroutines.
 type UAssetService struct {
 	app     *app.App
-	depsDir string // Path to extracted dependencies
+	depsDir string                // Path to extracted dependencies
+	runner  toolrunner.ToolRunner // nil selects toolrunner.ForApp
 }
 
-// NewUAssetService creates a new UAssetService using the UAssetBridge.exe
+// NewUAssetService creates a new UAssetService using the UAssetBridge
 // binary located in the given depsDir. The depsDir should contain a "UAssetAPI"
 // subdirectory with UAssetBridge.exe and all required .NET runtime DLLs.
+// The bridge is run with the runner the App's preferences select; see
+// [toolrunner.ForApp].
 func NewUAssetService(a *app.App, depsDir string) *UAssetService {
+	return NewUAssetServiceWithRunner(a, depsDir, nil)
+}
+
+// NewUAssetServiceWithRunner is like [NewUAssetService] but always runs
+// the bridge with runner. A nil runner follows the App's preferences.
+func NewUAssetServiceWithRunner(a *app.App, depsDir string, runner toolrunner.ToolRunner) *UAssetService {
 	return &UAssetService{
 		app:     a,
 		depsDir: depsDir,
+		runner:  runner,
+	}
+}
+
+// toolRunner returns the runner the bridge is run with.
+func (u *UAssetService) toolRunner() toolrunner.ToolRunner {
+	if u.runner != nil {
+		return u.runner
 	}
+	return toolrunner.ForApp(u.app)
 }
 
 // ExportUAssets converts all .uasset/.uexp files in folderPath to JSON format.
@@ -40,7 +63,7 @@ func NewUAssetService(a *app.App, depsDir string) *UAssetService {
 // unversioned property resolution. The operation spawns UAssetBridge.exe as
 // a subprocess and waits for it to complete.
 func (u *UAssetService) ExportUAssets(ctx context.Context, folderPath, mappingsPath string) UAssetResult {
-	return u.runUAssetOperation(ctx, "export", folderPath, mappingsPath)
+	return u.runUAssetOperation(ctx, UAssetRequest{Command: "export", FolderPath: folderPath, MappingsPath: mappingsPath})
 }
 
 // ImportUAssets converts all .json files in folderPath back to .uasset/.uexp
@@ -48,21 +71,57 @@ func (u *UAssetService) ExportUAssets(ctx context.Context, folderPath, mappingsP
 // for unversioned property serialization. The operation spawns UAssetBridge.exe
 // as a subprocess and waits for it to complete.
 func (u *UAssetService) ImportUAssets(ctx context.Context, folderPath, mappingsPath string) UAssetResult {
-	return u.runUAssetOperation(ctx, "import", folderPath, mappingsPath)
+	return u.runUAssetOperation(ctx, UAssetRequest{Command: "import", FolderPath: folderPath, MappingsPath: mappingsPath})
 }
 
-func (u *UAssetService) runUAssetOperation(ctx context.Context, command, folderPath, mappingsPath string) UAssetResult {
+// RunUAsset runs the export or import described by req. The mappings file
+// is taken from the request's game profile unless req sets one.
+func (u *UAssetService) RunUAsset(ctx context.Context, req UAssetRequest) UAssetResult {
+	return u.runUAssetOperation(ctx, req)
+}
+
+// runUAssetOperation runs UAssetBridge.exe for req as an operation
+// registered with the App, so that it can be listed and cancelled like any
+// other. [EventFinished] is emitted when it ends, also when req is invalid,
+// so that every call is recorded.
+func (u *UAssetService) runUAssetOperation(ctx context.Context, req UAssetRequest) (result UAssetResult) {
+	op := u.app.StartOperation(ctx, OperationOwner, req.Command, req.FolderPath)
 	startTime := time.Now()
+	defer func() {
+		result.OperationID = op.ID()
+		op.FinishResult(result.Success, result.Error)
+		u.app.Emit(EventFinished, UAssetFinished{Request: req, StartedAt: startTime, Result: result})
+	}()
+	ctx = op.Context()
+
+	if req.Command != "export" && req.Command != "import" {
+		return UAssetResult{
+			Success: false,
+			Error:   fmt.Sprintf("Unknown UAsset command: %q", req.Command),
+		}
+	}
+	if req.ProfileID != "" {
+		profile, err := u.app.GetProfile(req.ProfileID)
+		if err != nil {
+			return UAssetResult{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		if req.MappingsPath == "" {
+			req.MappingsPath = profile.MappingsPath
+		}
+	}
+	command, folderPath, mappingsPath := req.Command, req.FolderPath, req.MappingsPath
 
 	// Use the extracted dependencies directory
-	// Look for UAssetBridge.exe in the UAssetAPI subfolder
-	bridgePath := filepath.Join(u.depsDir, "UAssetAPI", "UAssetBridge.exe")
-
-	// Check if UAssetBridge.exe exists
-	if _, err := os.Stat(bridgePath); os.IsNotExist(err) {
+	// Look for UAssetBridge in the UAssetAPI subfolder
+	runner := u.toolRunner()
+	bridgePath, err := runner.Resolve(filepath.Join(u.depsDir, "UAssetAPI"), "UAssetBridge")
+	if err != nil {
 		return UAssetResult{
 			Success: false,
-			Error:   fmt.Sprintf("UAssetBridge.exe not found at: %s", bridgePath),
+			Error:   err.Error(),
 		}
 	}
 
@@ -89,23 +148,43 @@ func (u *UAssetService) runUAssetOperation(ctx context.Context, command, folderP
 		args = append(args, mappingsPath)
 	}
 
-	// Create command
-	cmd := exec.CommandContext(ctx, bridgePath, args...)
-
-	// Set working directory to the directory containing uasset_bridge.exe
-	cmd.Dir = filepath.Dir(bridgePath)
+	if req.DryRun {
+		plan := runner.Plan(toolrunner.Command{Path: bridgePath, Args: args})
+		if plan.Outputs, plan.Overwrites, err = convertedFiles(command, folderPath); err != nil {
+			return UAssetResult{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return UAssetResult{
+			Success:  true,
+			Message:  fmt.Sprintf("Dry run: UAsset %s was not run", command),
+			Duration: time.Since(startTime).String(),
+			Plan:     &plan,
+		}
+	}
 
-	// Capture output
-	output, err := cmd.CombinedOutput()
+	// Run the bridge in the directory containing it, capturing output
+	var output bytes.Buffer
+	err = runner.Run(ctx, toolrunner.Command{
+		Path:   bridgePath,
+		Args:   args,
+		Stdout: &output,
+		Stderr: &output,
+	})
 
 	duration := time.Since(startTime)
 
-	result := UAssetResult{
+	result = UAssetResult{
 		Duration: duration.String(),
-		Output:   string(output),
+		Output:   output.String(),
 	}
 
-	if err != nil {
+	if ctx.Err() == context.Canceled {
+		result.Success = false
+		result.Error = "cancelled"
+		result.Message = fmt.Sprintf("UAsset %s operation cancelled by user", command)
+	} else if err != nil {
 		result.Success = false
 		result.Error = err.Error()
 		result.Message = fmt.Sprintf("UAsset %s operation failed", command)
@@ -114,12 +193,41 @@ func (u *UAssetService) runUAssetOperation(ctx context.Context, command, folderP
 		result.Message = fmt.Sprintf("UAsset %s operation completed successfully", command)
 
 		// Try to extract file count from output
-		result.FilesProcessed = u.extractFileCount(string(output))
+		result.FilesProcessed = u.extractFileCount(output.String())
 	}
 
 	return result
 }
 
+// convertedFiles returns the files the bridge writes for command in
+// folderPath, and those of them that already exist. The bridge converts in
+// place: each .uasset is exported to a .json of the same name, and each
+// .json imported to a .uasset and .uexp.
+func convertedFiles(command, folderPath string) (outputs, overwrites []string, err error) {
+	from, to := ".uasset", []string{".json"}
+	if command == "import" {
+		from, to = ".json", []string{".uasset", ".uexp"}
+	}
+	err = filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), from) {
+			return nil
+		}
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		for _, ext := range to {
+			output := base + ext
+			outputs = append(outputs, output)
+			if _, err := os.Stat(output); err == nil {
+				overwrites = append(overwrites, output)
+			}
+		}
+		return nil
+	})
+	return outputs, overwrites, err
+}
+
 // extractFileCount attempts to extract the number of files processed from output
 func (u *UAssetService) extractFileCount(output string) int {
 	// Look for patterns like "Processed X files" or "X files processed"
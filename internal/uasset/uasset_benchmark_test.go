@@ -1,5 +1,9 @@
-//go:build cgo
-// +build cgo
+//go:build cgo && uassetnative
+// +build cgo,uassetnative
+
+// These benchmarks compare the bridge with the experimental NativeAOT
+// service, which is not part of this package; build them with the
+// uassetnative tag alongside it.
 
 package uasset
 
@@ -3,12 +3,16 @@ package uasset
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
 )
 
 func TestUAssetExport_Success_CreatesJSONFiles(t *testing.T) {
@@ -101,7 +105,7 @@ func TestUAssetExport_MissingBridge_ReturnsError(t *testing.T) {
 		t.Error("Expected operation to fail with missing bridge")
 	}
 
-	expectedErr := "UAssetBridge.exe not found"
+	expectedErr := "not found at:"
 	if !strings.Contains(result.Error, expectedErr) {
 		t.Errorf("Expected error containing '%s', got: %s", expectedErr, result.Error)
 	}
@@ -165,9 +169,9 @@ func TestUAssetExport_CountFiles_Accurate(t *testing.T) {
 		t.Fatalf("Failed to create txt file: %v", err)
 	}
 
-	app := NewApp()
+	appInstance := app.NewApp()
 	depsDir := filepath.Join(tempDir, "deps")
-	service := NewUAssetService(app, depsDir)
+	service := NewUAssetService(appInstance, depsDir)
 
 	ctx := context.Background()
 	uassetCount, uexpCount, err := service.CountUAssetFiles(ctx, testFolder)
@@ -184,3 +188,82 @@ func TestUAssetExport_CountFiles_Accurate(t *testing.T) {
 		t.Errorf("Expected 3 .uexp files, got %d", uexpCount)
 	}
 }
+
+func TestUAssetExport_FakeBridge_CountsProcessedFiles(t *testing.T) {
+	fake := toolrunnertest.New()
+	fake.Handle("UAssetBridge", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		fmt.Fprintln(stdout, "Export complete: 4 processed files")
+		return nil
+	})
+	depsDir, folder := t.TempDir(), t.TempDir()
+	mappings := filepath.Join(t.TempDir(), "Game.usmap")
+	os.WriteFile(mappings, []byte("usmap"), 0644)
+	service := NewUAssetServiceWithRunner(app.NewApp(), depsDir, fake)
+
+	result := service.ExportUAssets(context.Background(), folder, mappings)
+	if !result.Success || result.FilesProcessed != 4 {
+		t.Errorf("Expected 4 files processed, got %+v", result)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].Path != filepath.Join(depsDir, "UAssetAPI", "UAssetBridge") {
+		t.Fatalf("Expected one run of the bridge from depsDir, got %+v", calls)
+	}
+	if want := []string{"export", folder, mappings}; !slices.Equal(calls[0].Args, want) {
+		t.Errorf("Expected arguments %v, got %v", want, calls[0].Args)
+	}
+}
+
+func TestUAssetExport_DryRun_PlansOutputs(t *testing.T) {
+	fake := toolrunnertest.New()
+	fake.Handle("UAssetBridge", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		t.Error("Expected the bridge not to run")
+		return nil
+	})
+	depsDir, folder := t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(folder, "Data"), 0755)
+	for _, name := range []string{"A.uasset", "A.uexp", "A.json", "Data/B.uasset", "Data/B.uexp"} {
+		os.WriteFile(filepath.Join(folder, name), []byte("x"), 0644)
+	}
+	service := NewUAssetServiceWithRunner(app.NewApp(), depsDir, fake)
+
+	result := service.RunUAsset(context.Background(), UAssetRequest{Command: "export", FolderPath: folder, DryRun: true})
+	if !result.Success || result.Plan == nil {
+		t.Fatalf("Expected a plan, got %+v", result)
+	}
+	if want := []string{filepath.Join(depsDir, "UAssetAPI", "UAssetBridge"), "export", folder}; !slices.Equal(result.Plan.Argv, want) {
+		t.Errorf("Expected argv %v, got %v", want, result.Plan.Argv)
+	}
+	wantOutputs := []string{filepath.Join(folder, "A.json"), filepath.Join(folder, "Data", "B.json")}
+	if !slices.Equal(result.Plan.Outputs, wantOutputs) {
+		t.Errorf("Expected outputs %v, got %v", wantOutputs, result.Plan.Outputs)
+	}
+	if want := wantOutputs[:1]; !slices.Equal(result.Plan.Overwrites, want) {
+		t.Errorf("Expected overwrites %v, got %v", want, result.Plan.Overwrites)
+	}
+}
+
+func TestUAssetExport_InvalidRequest_IsRegisteredAndRecorded(t *testing.T) {
+	a := app.NewApp()
+	service := NewUAssetServiceWithRunner(a, t.TempDir(), toolrunnertest.New())
+	var finished []UAssetFinished
+	defer a.Subscribe(EventFinished, func(data any) {
+		finished = append(finished, data.(UAssetFinished))
+	})()
+
+	for _, req := range []UAssetRequest{
+		{Command: "convert", FolderPath: t.TempDir()},
+		{Command: "export", FolderPath: t.TempDir(), ProfileID: "missing"},
+	} {
+		result := service.RunUAsset(context.Background(), req)
+		if result.Success || result.OperationID == "" {
+			t.Errorf("%+v: expected a failed, registered operation, got %+v", req, result)
+			continue
+		}
+		if op, err := a.GetOperation(result.OperationID); err != nil || op.State != app.OperationFailed {
+			t.Errorf("%+v: expected the operation to be listed as failed, got %+v (%v)", req, op, err)
+		}
+	}
+	if len(finished) != 2 {
+		t.Errorf("Expected both calls to emit %s, got %+v", EventFinished, finished)
+	}
+}
@@ -148,9 +148,9 @@ func TestUAssetImport_CountJSONFiles_Accurate(t *testing.T) {
 		}
 	}
 
-	app := NewApp()
+	appInstance := app.NewApp()
 	depsDir := filepath.Join(tempDir, "deps")
-	service := NewUAssetService(app, depsDir)
+	service := NewUAssetService(appInstance, depsDir)
 
 	ctx := context.Background()
 	jsonCount, err := service.CountJSONFiles(ctx, testFolder)
@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// fileStat is what a poll compares to tell whether a file changed.
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// fileSnapshot is the state of every file in a project, by slash-separated
+// path relative to it.
+type fileSnapshot map[string]fileStat
+
+// snapshot returns the state of the files under root.
+func snapshot(root string) (fileSnapshot, error) {
+	files := make(fileSnapshot)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileStat{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// diff returns the paths added, changed or removed in current, sorted.
+func (s fileSnapshot) diff(current fileSnapshot) []string {
+	var changed []string
+	for path, stat := range current {
+		if previous, ok := s[path]; !ok || previous.size != stat.size || !previous.modTime.Equal(stat.modTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
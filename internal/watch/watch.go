@@ -0,0 +1,402 @@
+// Package watch runs watch sessions for mod projects. A session polls a mod
+// project folder and, once changes settle, imports edited JSON with the
+// UAsset bridge, packs the folder and installs the packed files, so that
+// the next game launch picks up every edit without touching ARI-S.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/mods"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+	"github.com/bep/debounce"
+)
+
+// OperationOwner is the owner of the operations WatchService registers
+// with the App; each session is one operation, running until stopped.
+const OperationOwner = "watch"
+
+// EventWatch is emitted with a [WatchEvent] as sessions start, see
+// changes, run steps and stop.
+const EventWatch = "watch:event"
+
+// Kinds of [WatchEvent].
+const (
+	KindStarted  = "started"  // The session is watching
+	KindChanged  = "changed"  // Files changed; a run follows once changes settle
+	KindStep     = "step"     // A step of a run started
+	KindFailed   = "failed"   // A step failed; the run stops but the session keeps watching
+	KindFinished = "finished" // A run completed every step
+	KindStopped  = "stopped"  // The session ended
+)
+
+// Steps of a run, in order.
+const (
+	StepImport  = "import"
+	StepPack    = "pack"
+	StepInstall = "install"
+)
+
+// DefaultDebounce is how long a project must be quiet before a run.
+const DefaultDebounce = 1500 * time.Millisecond
+
+// pollInterval is how often sessions look for changes. Polling works on
+// every OS and on network shares, and a mod project is small.
+var pollInterval = 500 * time.Millisecond
+
+// WatchRequest describes a watch session.
+//
+// Each run imports the project's JSON with the UAsset bridge if any .json
+// changed, packs the project to OutputPath with Pack and, if Install is
+// set, installs the packed files into Target. The bridge converts in place,
+// so the .uasset and .uexp files it writes are part of what is packed.
+// ProfileID supplies the mappings file, engine version, naming scheme and,
+// when Target is empty, the ~mods directory, as for the individual services.
+type WatchRequest struct {
+	ProjectPath  string            `json:"project_path"`
+	OutputPath   string            `json:"output_path"` // Must be outside ProjectPath
+	ProfileID    string            `json:"profile_id"`
+	MappingsPath string            `json:"mappings_path"`
+	Pack         retoc.PackOptions `json:"pack"`
+	Install      bool              `json:"install"`
+	Target       mods.Target       `json:"target"`
+	DebounceMS   int               `json:"debounce_ms"` // 0 means DefaultDebounce
+}
+
+// WatchSession describes a running session.
+type WatchSession struct {
+	ID        string       `json:"id"` // Also its operation ID
+	Request   WatchRequest `json:"request"`
+	StartedAt time.Time    `json:"started_at"`
+	Runs      int          `json:"runs"`
+	Running   bool         `json:"running"` // A run is in progress
+	LastError string       `json:"last_error,omitempty"`
+}
+
+// WatchEvent reports the progress of a session.
+type WatchEvent struct {
+	SessionID string    `json:"session_id"`
+	Kind      string    `json:"kind"`
+	Step      string    `json:"step,omitempty"`
+	Changed   []string  `json:"changed,omitempty"` // Slash-separated, relative to the project
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// WatchService runs watch sessions with the given services. It is
+// registered as a Wails service; ServiceShutdown stops every session.
+//
+// WatchService is safe for concurrent use by multiple goroutines.
+type WatchService struct {
+	app       *app.App
+	retoc     *retoc.RetocService
+	uasset    *uasset.UAssetService
+	installer *mods.ModInstaller
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is a running watch session.
+type session struct {
+	info      WatchSession // Guarded by WatchService.mu
+	op        *app.OperationHandle
+	done      chan struct{}
+	installed bool // A run of this session has installed
+}
+
+// NewWatchService creates a WatchService that imports with u, packs with r
+// and installs with m.
+func NewWatchService(a *app.App, r *retoc.RetocService, u *uasset.UAssetService, m *mods.ModInstaller) *WatchService {
+	return &WatchService{
+		app:       a,
+		retoc:     r,
+		uasset:    u,
+		installer: m,
+		sessions:  make(map[string]*session),
+	}
+}
+
+// ServiceShutdown stops every session and waits for them to end.
+func (w *WatchService) ServiceShutdown() error {
+	w.mu.Lock()
+	sessions := make([]*session, 0, len(w.sessions))
+	for _, s := range w.sessions {
+		sessions = append(sessions, s)
+	}
+	w.mu.Unlock()
+
+	for _, s := range sessions {
+		w.app.CancelOperation(s.info.ID)
+		<-s.done
+	}
+	return nil
+}
+
+// Start starts watching req.ProjectPath and returns the new session. The
+// session runs until Stop is called with its ID or its operation is
+// cancelled.
+func (w *WatchService) Start(ctx context.Context, req WatchRequest) (WatchSession, error) {
+	if err := validate(req); err != nil {
+		return WatchSession{}, err
+	}
+	baseline, err := snapshot(req.ProjectPath)
+	if err != nil {
+		return WatchSession{}, err
+	}
+
+	// The session outlives the call that starts it
+	op := w.app.StartOperation(context.Background(), OperationOwner, "watch", req.ProjectPath)
+	s := &session{
+		info: WatchSession{ID: op.ID(), Request: req, StartedAt: time.Now()},
+		op:   op,
+		done: make(chan struct{}),
+	}
+	w.mu.Lock()
+	w.sessions[s.info.ID] = s
+	info := s.info
+	w.mu.Unlock()
+
+	w.emit(s, WatchEvent{Kind: KindStarted, Message: "Watching " + req.ProjectPath})
+	go w.watch(s, baseline)
+	return info, nil
+}
+
+// Stop stops the session with the given ID and waits for it to end,
+// cancelling the step it is running.
+func (w *WatchService) Stop(ctx context.Context, id string) error {
+	w.mu.Lock()
+	s := w.sessions[id]
+	w.mu.Unlock()
+	if s == nil {
+		return fmt.Errorf("no watch session %s", id)
+	}
+	w.app.CancelOperation(id)
+	<-s.done
+	return nil
+}
+
+// List returns the running sessions, oldest first.
+func (w *WatchService) List(ctx context.Context) []WatchSession {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sessions := make([]WatchSession, 0, len(w.sessions))
+	for _, s := range w.sessions {
+		sessions = append(sessions, s.info)
+	}
+	slices.SortFunc(sessions, func(a, b WatchSession) int { return a.StartedAt.Compare(b.StartedAt) })
+	return sessions
+}
+
+// validate checks the paths of req.
+func validate(req WatchRequest) error {
+	info, err := os.Stat(req.ProjectPath)
+	if err != nil {
+		return fmt.Errorf("project folder: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("project folder is not a directory: %s", req.ProjectPath)
+	}
+	if req.OutputPath == "" {
+		return errors.New("an output folder is required")
+	}
+	project, err := filepath.Abs(req.ProjectPath)
+	if err != nil {
+		return err
+	}
+	output, err := filepath.Abs(req.OutputPath)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(project, output); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.New("the output folder must be outside the project folder, or every pack would trigger another")
+	}
+	if req.Install && req.Target == (mods.Target{}) && req.ProfileID == "" {
+		return errors.New("installing requires a target or a game profile")
+	}
+	return nil
+}
+
+// watch polls the session's project until the session is cancelled,
+// starting a run once changes have settled for the debounce time.
+func (w *WatchService) watch(s *session, baseline fileSnapshot) {
+	defer close(s.done)
+	ctx := s.op.Context()
+	req := s.info.Request
+
+	wait := DefaultDebounce
+	if req.DebounceMS > 0 {
+		wait = time.Duration(req.DebounceMS) * time.Millisecond
+	}
+	debounced := debounce.New(wait)
+	settled := make(chan struct{}, 1)
+	settle := func() {
+		debounced(func() {
+			select {
+			case settled <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	pending := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			delete(w.sessions, s.info.ID)
+			w.mu.Unlock()
+			s.op.Finish(ctx.Err())
+			w.emit(s, WatchEvent{Kind: KindStopped})
+			return
+
+		case <-ticker.C:
+			current, err := snapshot(req.ProjectPath)
+			if err != nil {
+				continue // The folder may be mid-replace; try again next tick
+			}
+			changed := baseline.diff(current)
+			baseline = current
+			if len(changed) == 0 {
+				continue
+			}
+			for _, path := range changed {
+				pending[path] = true
+			}
+			w.emit(s, WatchEvent{Kind: KindChanged, Changed: changed})
+			settle()
+
+		case <-settled:
+			changed := make([]string, 0, len(pending))
+			for path := range pending {
+				changed = append(changed, path)
+			}
+			slices.Sort(changed)
+			clear(pending)
+			imported := w.run(ctx, s, changed)
+
+			// The import writes into the project; only other changes made
+			// during the run start another
+			current, err := snapshot(req.ProjectPath)
+			if err != nil {
+				continue
+			}
+			for _, path := range baseline.diff(current) {
+				if !imported || !isImportOutput(path) {
+					pending[path] = true
+				}
+			}
+			baseline = current
+			if len(pending) > 0 {
+				settle()
+			}
+		}
+	}
+}
+
+// run imports, packs and installs for the changed files, emitting an event
+// for each step, and reports whether it imported.
+func (w *WatchService) run(ctx context.Context, s *session, changed []string) (imported bool) {
+	req := s.info.Request
+	w.update(s, func(info *WatchSession) {
+		info.Runs++
+		info.Running = true
+	})
+	var failure string
+	defer func() {
+		w.update(s, func(info *WatchSession) {
+			info.Running = false
+			info.LastError = failure
+		})
+	}()
+	fail := func(step, errMessage string) bool {
+		if ctx.Err() == nil {
+			failure = errMessage
+			w.emit(s, WatchEvent{Kind: KindFailed, Step: step, Error: errMessage})
+		}
+		return imported
+	}
+
+	if slices.ContainsFunc(changed, func(path string) bool { return strings.EqualFold(filepath.Ext(path), ".json") }) {
+		w.emit(s, WatchEvent{Kind: KindStep, Step: StepImport, Changed: changed})
+		result := w.uasset.RunUAsset(ctx, uasset.UAssetRequest{
+			Command:      "import",
+			FolderPath:   req.ProjectPath,
+			MappingsPath: req.MappingsPath,
+			ProfileID:    req.ProfileID,
+		})
+		imported = true
+		if !result.Success {
+			return fail(StepImport, result.Error)
+		}
+	}
+
+	w.emit(s, WatchEvent{Kind: KindStep, Step: StepPack, Changed: changed})
+	pack := req.Pack
+	packed := w.retoc.RunRetoc(ctx, retoc.RetocOperation{
+		Command:    "to-zen",
+		InputPath:  req.ProjectPath,
+		OutputPath: req.OutputPath,
+		Pack:       &pack,
+		ProfileID:  req.ProfileID,
+	})
+	if !packed.Success {
+		return fail(StepPack, packed.Error)
+	}
+
+	// An unchanged pack that this session already installed is current
+	if req.Install && !(packed.Cached && s.installed) {
+		w.emit(s, WatchEvent{Kind: KindStep, Step: StepInstall})
+		target := req.Target
+		if target == (mods.Target{}) {
+			target = mods.Target{ProfileID: req.ProfileID}
+		}
+		_, err := w.installer.Install(ctx, mods.InstallRequest{
+			Target:        target,
+			Files:         packed.OutputFiles,
+			ModName:       req.Pack.ModName,
+			SourceProject: req.ProjectPath,
+		})
+		if err != nil {
+			return fail(StepInstall, err.Error())
+		}
+		s.installed = true
+	}
+
+	w.emit(s, WatchEvent{Kind: KindFinished, Changed: changed, Message: packed.Message})
+	return imported
+}
+
+// update applies change to the session's info.
+func (w *WatchService) update(s *session, change func(info *WatchSession)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	change(&s.info)
+}
+
+// emit emits e for session s.
+func (w *WatchService) emit(s *session, e WatchEvent) {
+	e.SessionID = s.info.ID // Immutable after Start
+	e.Time = time.Now()
+	w.app.Emit(EventWatch, e)
+}
+
+// isImportOutput reports whether path is a file the bridge writes when
+// importing.
+func isImportOutput(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".uasset" || ext == ".uexp"
+}
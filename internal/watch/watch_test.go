@@ -0,0 +1,240 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JaceTheGrayOne/ARI-S/internal/app"
+	"github.com/JaceTheGrayOne/ARI-S/internal/mods"
+	"github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner"
+	"github.com/JaceTheGrayOne/ARI-S/internal/toolrunner/toolrunnertest"
+	"github.com/JaceTheGrayOne/ARI-S/internal/uasset"
+)
+
+func init() {
+	pollInterval = 20 * time.Millisecond
+}
+
+// watchTest is a WatchService whose retoc and bridge are fakes, with a mod
+// project to watch and a ~mods directory to install into.
+type watchTest struct {
+	service *WatchService
+	fake    *toolrunnertest.FakeRunner
+	request WatchRequest
+	modsDir string
+
+	mu     sync.Mutex
+	events []WatchEvent
+}
+
+func newWatchTest(t *testing.T) *watchTest {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dataDir)
+	t.Setenv("AppData", dataDir)
+
+	w := &watchTest{fake: toolrunnertest.New()}
+	w.fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		utoc := cmd.Args[len(cmd.Args)-1]
+		for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+			os.WriteFile(strings.TrimSuffix(utoc, ".utoc")+ext, []byte(strings.Join(cmd.Args, " ")), 0644)
+		}
+		return nil
+	})
+	w.fake.Handle("UAssetBridge", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		return os.WriteFile(filepath.Join(cmd.Args[1], "A.uasset"), []byte("imported"), 0644)
+	})
+
+	project := filepath.Join(t.TempDir(), "MyMod")
+	os.MkdirAll(project, 0755)
+	os.WriteFile(filepath.Join(project, "A.uasset"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(project, "A.json"), []byte("{}"), 0644)
+	w.modsDir = filepath.Join(t.TempDir(), "~mods")
+
+	a := app.NewApp()
+	depsDir := t.TempDir()
+	w.service = NewWatchService(a,
+		retoc.NewRetocServiceWithRunner(a, depsDir, w.fake),
+		uasset.NewUAssetServiceWithRunner(a, depsDir, w.fake),
+		mods.NewModInstaller(a))
+	w.request = WatchRequest{
+		ProjectPath: project,
+		OutputPath:  t.TempDir(),
+		Pack:        retoc.PackOptions{ModName: "MyMod", EngineVersion: "UE5_3"},
+		Install:     true,
+		Target:      mods.Target{PaksDir: w.modsDir},
+		DebounceMS:  50,
+	}
+	t.Cleanup(a.Subscribe(EventWatch, func(data any) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.events = append(w.events, data.(WatchEvent))
+	}))
+	t.Cleanup(func() { w.service.ServiceShutdown() })
+	return w
+}
+
+// waitFor waits for an event of the given kind and returns it.
+func (w *watchTest) waitFor(t *testing.T, kind string) WatchEvent {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		w.mu.Lock()
+		for _, e := range w.events {
+			if e.Kind == kind {
+				w.mu.Unlock()
+				return e
+			}
+		}
+		w.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for a %s event", kind)
+	return WatchEvent{}
+}
+
+// steps returns the steps run so far, in order.
+func (w *watchTest) steps() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var steps []string
+	for _, e := range w.events {
+		if e.Kind == KindStep {
+			steps = append(steps, e.Step)
+		}
+	}
+	return steps
+}
+
+// touch rewrites a project file with a modification time in the future, so
+// the change is seen however coarse the file system's timestamps are.
+func touch(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+}
+
+func TestWatch_JSONEdited_ImportsPacksAndInstalls(t *testing.T) {
+	w := newWatchTest(t)
+	ctx := context.Background()
+
+	session, err := w.service.Start(ctx, w.request)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	touch(t, filepath.Join(w.request.ProjectPath, "A.json"), `{"edited":true}`)
+
+	finished := w.waitFor(t, KindFinished)
+	if !slices.Equal(finished.Changed, []string{"A.json"}) {
+		t.Errorf("Expected A.json to have changed, got %v", finished.Changed)
+	}
+	if want := []string{StepImport, StepPack, StepInstall}; !slices.Equal(w.steps(), want) {
+		t.Errorf("Expected steps %v, got %v", want, w.steps())
+	}
+	for _, name := range []string{"z_MyMod_0001_p.utoc", "z_MyMod_0001_p.ucas", "z_MyMod_0001_p.pak"} {
+		if _, err := os.Stat(filepath.Join(w.modsDir, name)); err != nil {
+			t.Errorf("Expected %s to be installed: %v", name, err)
+		}
+	}
+
+	// The import's own write to A.uasset must not start another run
+	time.Sleep(200 * time.Millisecond)
+	sessions := w.service.List(ctx)
+	if len(sessions) != 1 || sessions[0].ID != session.ID || sessions[0].Runs != 1 {
+		t.Errorf("Expected one session with one run, got %+v", sessions)
+	}
+}
+
+func TestWatch_Stop_EndsSessionAndOperation(t *testing.T) {
+	w := newWatchTest(t)
+	ctx := context.Background()
+
+	session, err := w.service.Start(ctx, w.request)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := w.service.Stop(ctx, session.ID); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	w.waitFor(t, KindStopped)
+	if sessions := w.service.List(ctx); len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got %+v", sessions)
+	}
+	if op, err := w.service.app.GetOperation(session.ID); err != nil || op.State != app.OperationCancelled {
+		t.Errorf("Expected the operation to be cancelled, got %+v", op)
+	}
+	if err := w.service.Stop(ctx, session.ID); err == nil {
+		t.Error("Expected stopping a stopped session to fail")
+	}
+}
+
+func TestWatch_PackFails_ReportsAndKeepsWatching(t *testing.T) {
+	w := newWatchTest(t)
+	fail := true
+	var mu sync.Mutex
+	w.fake.Handle("retoc", func(ctx context.Context, cmd toolrunner.Command, stdout, stderr io.Writer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New("exit status 1")
+		}
+		utoc := cmd.Args[len(cmd.Args)-1]
+		for _, ext := range []string{".utoc", ".ucas", ".pak"} {
+			os.WriteFile(strings.TrimSuffix(utoc, ".utoc")+ext, []byte("packed"), 0644)
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	if _, err := w.service.Start(ctx, w.request); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	touch(t, filepath.Join(w.request.ProjectPath, "B.uasset"), "b")
+	if failed := w.waitFor(t, KindFailed); failed.Step != StepPack || failed.Error == "" {
+		t.Errorf("Expected the pack step to fail, got %+v", failed)
+	}
+	if sessions := w.service.List(ctx); len(sessions) != 1 || sessions[0].LastError == "" {
+		t.Errorf("Expected the session to keep watching with its error, got %+v", sessions)
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	touch(t, filepath.Join(w.request.ProjectPath, "B.uasset"), "b2")
+	w.waitFor(t, KindFinished)
+	if sessions := w.service.List(ctx); len(sessions) != 1 || sessions[0].LastError != "" {
+		t.Errorf("Expected the error to clear after a good run, got %+v", sessions)
+	}
+}
+
+func TestWatch_InvalidRequest_Rejected(t *testing.T) {
+	w := newWatchTest(t)
+	tests := map[string]func(req *WatchRequest){
+		"missing project": func(req *WatchRequest) { req.ProjectPath = filepath.Join(t.TempDir(), "missing") },
+		"no output":       func(req *WatchRequest) { req.OutputPath = "" },
+		"output inside":   func(req *WatchRequest) { req.OutputPath = filepath.Join(req.ProjectPath, "out") },
+		"no target":       func(req *WatchRequest) { req.Target = mods.Target{} },
+	}
+	for name, change := range tests {
+		req := w.request
+		change(&req)
+		if _, err := w.service.Start(context.Background(), req); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if sessions := w.service.List(context.Background()); len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got %+v", sessions)
+	}
+}
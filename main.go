@@ -2,15 +2,22 @@ package main
 
 import (
     "embed"
+    "fmt"
     "log"
     "os"
     "path/filepath"
 
     "github.com/JaceTheGrayOne/ARI-S/internal/app"
+    "github.com/JaceTheGrayOne/ARI-S/internal/discovery"
+    "github.com/JaceTheGrayOne/ARI-S/internal/history"
     "github.com/JaceTheGrayOne/ARI-S/internal/injector"
+    "github.com/JaceTheGrayOne/ARI-S/internal/jobs"
+    "github.com/JaceTheGrayOne/ARI-S/internal/mods"
     "github.com/JaceTheGrayOne/ARI-S/internal/retoc"
+    "github.com/JaceTheGrayOne/ARI-S/internal/rpc"
     "github.com/JaceTheGrayOne/ARI-S/internal/uasset"
     "github.com/JaceTheGrayOne/ARI-S/internal/uwpdumper"
+    "github.com/JaceTheGrayOne/ARI-S/internal/watch"
     "github.com/wailsapp/wails/v3/pkg/application"
 )
 
@@ -37,34 +44,16 @@ var depsFS embed.FS
 var uassetBridgeManagedDLL []byte
 
 func main() {
-	// Extract native libraries first (if CGO is enabled)
-	if err := initNativeLibraries(); err != nil {
-		log.Fatalf("Fatal error during native library setup: %v", err)
+	// Run headless when the first argument is a CLI command
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		attachConsole()
+		os.Exit(runCLI(os.Args[1:], setupDependencies, os.Stdout, os.Stderr))
 	}
 
-    extractedDepsDir, err := app.EnsureDependencies(depsFS)
-    if err != nil {
-        log.Fatalf("Fatal error during dependency setup: %v", err)
-    }
-    log.Printf("Dependencies directory: %s", extractedDepsDir)
-
-    // Ensure UAssetBridge.dll exists next to UAssetBridge.exe. Some builds may
-    // only include the apphost EXE; .NET still expects the managed DLL listed
-    // in the deps.json. If it's missing, provide the embedded copy from build/.
-    {
-        dllPath := filepath.Join(extractedDepsDir, "UAssetAPI", "UAssetBridge.dll")
-        if _, statErr := os.Stat(dllPath); os.IsNotExist(statErr) {
-            if len(uassetBridgeManagedDLL) == 0 {
-                log.Printf("Warning: Embedded UAssetBridge.dll not available; cannot repair missing managed assembly")
-            } else {
-                if writeErr := os.WriteFile(dllPath, uassetBridgeManagedDLL, 0644); writeErr != nil {
-                    log.Printf("Warning: Failed to write UAssetBridge.dll: %v", writeErr)
-                } else {
-                    log.Printf("Restored missing UAssetBridge.dll to: %s", dllPath)
-                }
-            }
-        }
-    }
+	extractedDepsDir, err := setupDependencies()
+	if err != nil {
+		log.Fatalf("Fatal error during dependency setup: %v", err)
+	}
 
 	// Create a new App instance
 	appInstance := app.NewApp()
@@ -102,10 +91,24 @@ func main() {
 	uassetService := uasset.NewUAssetService(appInstance, extractedDepsDir)
 	injectorService := injector.NewInjectorService(appInstance)
 	uwpDumperService := uwpdumper.NewUWPDumperService(appInstance, extractedDepsDir)
+	loadOrderService := mods.NewLoadOrderService(appInstance)
+	modInstaller := mods.NewModInstaller(appInstance)
+	discoveryService := discovery.NewDiscoveryService(appInstance)
+	jobQueue := jobs.NewJobQueue(appInstance, retocService, uassetService)
+	historyService := history.NewHistoryService(appInstance, retocService, uassetService)
+	watchService := watch.NewWatchService(appInstance, retocService, uassetService, modInstaller)
+	rpcServer := rpc.NewServer(appInstance, retocService, uassetService, jobQueue, historyService, watchService)
 	wailsApp.RegisterService(application.NewService(retocService))
 	wailsApp.RegisterService(application.NewService(uassetService))
 	wailsApp.RegisterService(application.NewService(injectorService))
 	wailsApp.RegisterService(application.NewService(uwpDumperService))
+	wailsApp.RegisterService(application.NewService(loadOrderService))
+	wailsApp.RegisterService(application.NewService(modInstaller))
+	wailsApp.RegisterService(application.NewService(discoveryService))
+	wailsApp.RegisterService(application.NewService(jobQueue))
+	wailsApp.RegisterService(application.NewService(historyService))
+	wailsApp.RegisterService(application.NewService(watchService))
+	wailsApp.RegisterService(application.NewService(rpcServer))
 
 	// Create a new window with the necessary options.
 	wailsApp.Window.NewWithOptions(application.WebviewWindowOptions{
@@ -131,3 +134,38 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// setupDependencies extracts the native libraries (if CGO is enabled) and
+// the embedded dependencies, and returns the dependencies directory.
+func setupDependencies() (string, error) {
+	// Extract native libraries first (if CGO is enabled)
+	if err := initNativeLibraries(); err != nil {
+		return "", fmt.Errorf("native library setup: %w", err)
+	}
+
+    extractedDepsDir, err := app.EnsureDependencies(depsFS)
+    if err != nil {
+        return "", err
+    }
+    log.Printf("Dependencies directory: %s", extractedDepsDir)
+
+    // Ensure UAssetBridge.dll exists next to UAssetBridge.exe. Some builds may
+    // only include the apphost EXE; .NET still expects the managed DLL listed
+    // in the deps.json. If it's missing, provide the embedded copy from build/.
+    {
+        dllPath := filepath.Join(extractedDepsDir, "UAssetAPI", "UAssetBridge.dll")
+        if _, statErr := os.Stat(dllPath); os.IsNotExist(statErr) {
+            if len(uassetBridgeManagedDLL) == 0 {
+                log.Printf("Warning: Embedded UAssetBridge.dll not available; cannot repair missing managed assembly")
+            } else {
+                if writeErr := os.WriteFile(dllPath, uassetBridgeManagedDLL, 0644); writeErr != nil {
+                    log.Printf("Warning: Failed to write UAssetBridge.dll: %v", writeErr)
+                } else {
+                    log.Printf("Restored missing UAssetBridge.dll to: %s", dllPath)
+                }
+            }
+        }
+    }
+
+	return extractedDepsDir, nil
+}